@@ -0,0 +1,45 @@
+package main
+
+import "sync/atomic"
+
+type requestStats struct {
+	served  int64
+	dropped uint32
+	mixed   int64
+}
+
+func (s *requestStats) markServed() {
+	atomic.AddInt64(&s.served, 1)
+}
+
+func (s *requestStats) resetServed() {
+	atomic.StoreInt64(&s.served, 0)
+}
+
+func (s *requestStats) Served() int64 {
+	return s.served // plain read of an atomically written field
+}
+
+func (s *requestStats) ServedAtomic() int64 {
+	return atomic.LoadInt64(&s.served)
+}
+
+func (s *requestStats) drop() {
+	atomic.AddUint32(&s.dropped, 1)
+}
+
+func (s *requestStats) DropRate(total uint32) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(s.dropped) / float64(total)
+}
+
+func (s *requestStats) bumpMixed() {
+	atomic.AddInt64(&s.mixed, 1)
+	s.mixed++ // mixed access is reported elsewhere, not as a plain read
+}
+
+func (s *requestStats) Mixed() int64 {
+	return s.mixed
+}
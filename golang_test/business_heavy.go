@@ -126,6 +126,16 @@ func (a *App) Stop() {
 	a.wg.Wait()
 }
 
+// RecentCache reads hotCache without the lock while the background writer
+// in StartWorkers keeps appending to it.
+func (a *App) RecentCache() []string {
+	out := make([]string, 0, len(a.hotCache))
+	for _, v := range a.hotCache {
+		out = append(out, v)
+	}
+	return out
+}
+
 func (a *App) processOrder(workerID int, orderID int64, engine PricingEngine) error {
 	a.mu.RLock()
 	o := a.orders[orderID]
@@ -0,0 +1,14 @@
+package main
+
+func fillPool(pool *WorkerPool) {
+	add := pool.addTask
+	add(1)
+	go pool.addTask(2)
+	defer pool.addTask(3)
+	go func() {
+		pool.addTask(4)
+	}()
+	later := pool.addTask
+	later = func(int) {}
+	later(5) // not traceable: later is reassigned
+}
@@ -0,0 +1,39 @@
+package main
+
+import "sync"
+
+type shape interface {
+	area() int
+}
+
+type square struct{ side int }
+
+func (s square) area() int { return s.side * s.side }
+
+type rect struct{ w, h int }
+
+func (r *rect) area() int { return r.w * r.h }
+
+func total(shapes []shape) int {
+	sum := 0
+	for _, s := range shapes {
+		sum += s.area()
+	}
+	return sum
+}
+
+func report(n int) {}
+
+func main() {
+	var wg sync.Mutex
+	wg.Lock()
+	defer wg.Unlock()
+	shapes := []shape{square{2}, &rect{2, 3}}
+	log := report
+	go func() {
+		log(total(shapes))
+	}()
+	func() {
+		report(square{1}.area())
+	}()
+}
@@ -0,0 +1,24 @@
+package main
+
+type Leaf struct {
+	Value int
+}
+
+func (l *Leaf) Bump() { l.Value++ }
+
+type Mid struct {
+	Leaf Leaf
+	Ptr  *Leaf
+}
+
+type Root struct {
+	Mid Mid
+}
+
+func main() {
+	r := Root{}
+	r.Mid.Leaf.Value = 1
+	r.Mid.Ptr.Bump()
+	x, y := r.Mid.Leaf.Value, r.Mid.Ptr.Value
+	_, _ = x, y
+}
@@ -0,0 +1,15 @@
+package main
+
+const resultBuffer = 4
+
+func sendAfterClose() int {
+	results := make(chan int, resultBuffer)
+	results <- 1
+	close(results)
+	results <- 2 // panics: send on closed channel
+	total := 0
+	for v := range results {
+		total += v
+	}
+	return total
+}
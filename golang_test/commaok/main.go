@@ -0,0 +1,43 @@
+package main
+
+import "fmt"
+
+type User struct {
+	Name string
+}
+
+type Store struct {
+	users map[int]*User
+}
+
+func (s *Store) Get(id int) (*User, bool) {
+	u, ok := s.users[id]
+	return u, ok
+}
+
+func main() {
+	store := &Store{users: map[int]*User{1: {Name: "a"}}}
+	u, ok := store.Get(1)
+	if ok {
+		fmt.Println(u.Name)
+	}
+
+	m := map[string]int{"a": 1}
+	v, ok := m["a"]
+	if ok {
+		fmt.Println(v)
+	}
+
+	var x interface{} = u
+	w, isUser := x.(*User)
+	if isUser {
+		fmt.Println(w.Name)
+	}
+
+	ch := make(chan int, 1)
+	ch <- 1
+	n, open := <-ch
+	if open {
+		fmt.Println(n)
+	}
+}
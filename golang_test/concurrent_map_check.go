@@ -0,0 +1,25 @@
+package main
+
+import "sync"
+
+// registry counts keys from a background goroutine without taking the lock
+// that count uses, so the map is written and read concurrently.
+type registry struct {
+	mu   sync.Mutex
+	seen map[string]int
+}
+
+func (r *registry) record(key string) {
+	r.seen[key]++
+}
+
+func (r *registry) count(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.seen[key]
+}
+
+func runRegistry(r *registry) int {
+	go r.record("a")
+	return r.count("a")
+}
@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusActive
+	StatusClosed
+)
+
+const (
+	_  = iota
+	KB = 1 << (10 * iota)
+	MB
+)
+
+const defaultStatus = StatusActive
+
+func describeStatus(s Status) string {
+	if s == StatusPending {
+		return fmt.Sprint("pending", KB)
+	}
+	return fmt.Sprint(s, defaultStatus)
+}
@@ -0,0 +1,16 @@
+package main
+
+import "fmt"
+
+type WorkerPool struct {
+	tasks []int
+}
+
+func main() {
+	x := 42
+	wide := int64(x)
+	pool := &WorkerPool{tasks: []int{1}}
+	any := interface{}(pool)
+	p := (*WorkerPool)(pool)
+	fmt.Println(wide, any, p)
+}
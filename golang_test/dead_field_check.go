@@ -0,0 +1,23 @@
+package main
+
+type deadFieldCache struct {
+	entries map[string]int
+	hits    int
+	legacy  []string // never read or written
+	Label   string
+	raw     string `json:"raw"`
+	_       int
+}
+
+type deadFieldPoint struct{ x, y int }
+
+var deadFieldOrigin = deadFieldPoint{0, 0}
+
+func newDeadFieldCache() *deadFieldCache {
+	return &deadFieldCache{entries: make(map[string]int)}
+}
+
+func (c *deadFieldCache) get(key string) int {
+	c.hits++
+	return c.entries[key]
+}
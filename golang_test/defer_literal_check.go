@@ -0,0 +1,15 @@
+package main
+
+import "os"
+
+func closeEachInLiteral(paths []string) {
+	for _, p := range paths {
+		func() {
+			f, err := os.Open(p)
+			if err != nil {
+				return
+			}
+			defer f.Close() // runs at the end of each literal call
+		}()
+	}
+}
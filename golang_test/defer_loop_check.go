@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func readAllConfigs(paths []string) (int, error) {
+	if len(paths) == 0 {
+		return 0, errors.New("no config paths")
+	}
+	total := 0
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return total, err
+		}
+		defer f.Close() // runs only when readAllConfigs returns
+		total++
+	}
+	done := make(chan struct{})
+	defer close(done)
+	defer func() {
+		_ = total
+	}()
+	return total, nil
+}
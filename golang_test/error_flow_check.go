@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+var errMissing = errors.New("missing")
+
+func removeConfig(path string) error {
+	err := os.Remove(path)
+	err = os.Remove(path + ".bak")
+	if errors.Is(err, errMissing) {
+		return fmt.Errorf("load %s: %w", path, err)
+	}
+	if err := os.Remove(path + ".tmp"); err != nil {
+		log.Printf("cleanup: %v", err)
+	}
+	os.Remove(path + ".old")
+	_ = err
+	return err
+}
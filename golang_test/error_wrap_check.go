@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var errNotFound = errors.New("not found")
+
+type lookupError struct{ key string }
+
+func (e *lookupError) Error() string { return "lookup " + e.key }
+
+func loadConfig(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("stat %s: %v", path, err) // loses err
+	}
+	if path == "" {
+		return fmt.Errorf("load %q: %w", path, errNotFound)
+	}
+	if len(path) > 255 {
+		return fmt.Errorf("%*d: %s", 8, len(path), &lookupError{path}) // loses the lookupError
+	}
+	return fmt.Errorf("config %s not found", path)
+}
+
+func describeFailure(id int, err error) string {
+	// Formatting into a string rather than an error is fine.
+	return fmt.Sprintf("job %d: %v", id, err)
+}
+
+func explicitIndex(err error) error {
+	return fmt.Errorf("%[1]v", err)
+}
@@ -0,0 +1,28 @@
+package generics
+
+import "fmt"
+
+// Set is an unordered collection of distinct keys.
+type Set[K comparable] struct {
+	items map[K]struct{}
+}
+
+func (s *Set[K]) Add(k K) {
+	s.items[k] = struct{}{}
+}
+
+func (s Set[K]) Len() int { return len(s.items) }
+
+// Pair holds two values of possibly different types.
+type Pair[A any, B fmt.Stringer] struct {
+	First  A
+	Second B
+}
+
+func Map[T, U any](in []T, f func(T) U) []U {
+	out := make([]U, 0, len(in))
+	for _, v := range in {
+		out = append(out, f(v))
+	}
+	return out
+}
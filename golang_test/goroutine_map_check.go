@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type pollerState struct {
+	mu    sync.Mutex
+	ticks int
+}
+
+func pollUntilCancelled(ctx context.Context, s *pollerState) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Millisecond):
+			s.mu.Lock()
+			s.ticks++
+			s.mu.Unlock()
+		}
+	}
+}
+
+func spinForever(s *pollerState) {
+	for {
+		s.mu.Lock()
+		s.ticks++
+		s.mu.Unlock()
+	}
+}
+
+func startPollers(ctx context.Context, s *pollerState, hook func()) {
+	s.mu.Lock()
+	go pollUntilCancelled(ctx, s)
+	s.mu.Unlock()
+	go spinForever(s)
+	go hook()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"log"
+)
+
+func logPanic() {
+	if r := recover(); r != nil {
+		log.Println("recovered:", r)
+	}
+}
+
+func spawnUnprotected(jobs []int) {
+	go func() {
+		for _, j := range jobs {
+			if j < 0 {
+				panic(errors.New("negative job")) // crashes the program
+			}
+		}
+	}()
+}
+
+func spawnRecoveredInline(jobs []int) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Println("recovered:", r)
+			}
+		}()
+		if len(jobs) == 0 {
+			panic("no jobs")
+		}
+	}()
+}
+
+func spawnRecoveredNamed() {
+	go func() {
+		defer logPanic()
+		panic("handled by logPanic")
+	}()
+}
+
+func spawnNestedLiteral() {
+	go func() {
+		check := func(v int) {
+			if v < 0 {
+				panic("nested literal is not reported")
+			}
+		}
+		check(1)
+	}()
+}
@@ -0,0 +1,36 @@
+package main
+
+import "sync"
+
+// maxRetries bounds how often a failed job is put back on the queue.
+const maxRetries = 3
+
+type jobQueue struct {
+	mu sync.Mutex
+	// pending holds jobs that have not been handed to a worker yet.
+	pending []string
+	retries map[string]int
+}
+
+func (q *jobQueue) push(job string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, job)
+}
+
+func (q *jobQueue) drain() []string {
+	q.mu.Lock()
+	out := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+	return out
+}
+
+func (q *jobQueue) retry(job string) bool {
+	q.retries[job]++
+	if q.retries[job] > maxRetries {
+		return false
+	}
+	q.push(job)
+	return true
+}
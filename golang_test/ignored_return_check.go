@@ -0,0 +1,26 @@
+package main
+
+import "strconv"
+
+// normalizeTag returns the cleaned tag, but every caller drops it.
+func normalizeTag(tag string) string {
+	return strconv.Quote(tag)
+}
+
+func parseRetries(raw string) (int, error) {
+	return strconv.Atoi(raw)
+}
+
+func applyTags(tags []string) error {
+	for _, tag := range tags {
+		normalizeTag(tag)
+		_ = normalizeTag(tag + "!")
+	}
+	defer normalizeTag("done")
+	if _, err := parseRetries("3"); err != nil {
+		return err
+	}
+	n, err := parseRetries("4")
+	_ = n
+	return err
+}
@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+type level int
+
+func (l level) String() string { return fmt.Sprintf("L%d", int(l)) }
+
+type parseError struct{ line int }
+
+func (e *parseError) Error() string { return fmt.Sprintf("line %d", e.line) }
+
+func describeLevel() string {
+	var current level = 2
+	err := &parseError{line: 3}
+	plain := 7
+	return current.String() + err.Error() + fmt.Sprint(plain)
+}
@@ -0,0 +1,19 @@
+package main
+
+import "fmt"
+
+var a = b + 1
+var b = 2
+
+var table = map[string]int{"b": b}
+
+var lazy = func() int { return b * 2 }
+
+func init() {
+	b = 3
+	fmt.Println(a, b)
+}
+
+func main() {
+	fmt.Println(a, b, table, lazy())
+}
@@ -0,0 +1,41 @@
+package main
+
+import "fmt"
+
+type orderStore interface {
+	Load(id int64) (*Order, error)
+	Save(o *Order) error
+	Delete(id int64) error
+	fmt.Stringer
+}
+
+type archive struct {
+	store orderStore
+}
+
+func (a *archive) move(id int64) error {
+	o, err := a.store.Load(id)
+	if err != nil {
+		return err
+	}
+	save := a.store.Save
+	if err := save(o); err != nil {
+		return err
+	}
+	if s, ok := a.store.(fmt.Stringer); ok {
+		fmt.Println(s.String())
+	}
+	switch a.store.(type) {
+	case nil:
+		return nil
+	case *memoryStore:
+	}
+	return nil
+}
+
+type memoryStore struct{}
+
+func (m *memoryStore) Load(id int64) (*Order, error) { return nil, nil }
+func (m *memoryStore) Save(o *Order) error           { return nil }
+func (m *memoryStore) Delete(id int64) error         { return nil }
+func (m *memoryStore) String() string                { return "memory" }
@@ -0,0 +1,9 @@
+// +build ignore
+
+// This file uses only the legacy build constraint syntax. The ignore tag
+// keeps it out of the package; its duplicate main would otherwise conflict.
+package main
+
+func main() {}
+
+var legacyOnly = 1
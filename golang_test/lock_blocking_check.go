@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type throttledCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+	updates chan string
+}
+
+func (c *throttledCache) refresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	time.Sleep(10 * time.Millisecond) // every reader waits for the sleep
+	c.entries[key] = "fresh"
+}
+
+func (c *throttledCache) publish(key string) {
+	c.mu.Lock()
+	c.entries[key] = "published"
+	c.updates <- key // blocks until a consumer is ready
+	c.mu.Unlock()
+	c.updates <- key // fine: the lock is released
+}
+
+func (c *throttledCache) tryPublish(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case c.updates <- key:
+	default:
+	}
+}
+
+func (c *throttledCache) waitAll(wg *sync.WaitGroup) {
+	c.mu.Lock()
+	go func() {
+		time.Sleep(time.Millisecond) // another goroutine, not under the lock
+	}()
+	wg.Wait()
+	c.mu.Unlock()
+}
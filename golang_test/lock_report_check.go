@@ -0,0 +1,23 @@
+package main
+
+import "sync"
+
+type splitGuardLedger struct {
+	mu      sync.Mutex
+	auditMu sync.Mutex
+	balance int64
+	entries []int64
+}
+
+func (l *splitGuardLedger) deposit(v int64) {
+	l.mu.Lock()
+	l.balance += v
+	l.entries = append(l.entries, v)
+	l.mu.Unlock()
+}
+
+func (l *splitGuardLedger) audit() int64 {
+	l.auditMu.Lock()
+	defer l.auditMu.Unlock()
+	return l.balance // guarded by a different mutex than deposit
+}
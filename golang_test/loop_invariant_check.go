@@ -0,0 +1,38 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const invariantSep = ","
+
+func hoistable(lines []string, prefix string) int {
+	count := 0
+	for _, line := range lines {
+		re := regexp.MustCompile("^[a-z]+$")
+		upper := strings.ToUpper(prefix)
+		if re.MatchString(line) && strings.HasPrefix(line, upper) {
+			count++
+		}
+		_ = strings.Split(strings.TrimSpace(prefix), invariantSep)
+	}
+	return count
+}
+
+func notHoistable(lines []string, prefix string) {
+	for i, line := range lines {
+		prefix = strings.TrimSpace(prefix)
+		_ = strings.Repeat(line, i)
+		_ = func() *regexp.Regexp {
+			return regexp.MustCompile("x")
+		}
+	}
+	limit := 2
+	for j := 0; j < 3; j++ {
+		_ = strings.Repeat("-", limit)
+		bump(&limit)
+	}
+}
+
+func bump(n *int) { *n++ }
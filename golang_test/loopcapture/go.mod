@@ -0,0 +1,3 @@
+module loopcapture
+
+go 1.21
@@ -0,0 +1,24 @@
+package main
+
+import "fmt"
+
+// The module declares go 1.21, so every iteration shares i and id.
+func main() {
+	ids := []int{1, 2, 3}
+	for i := 0; i < len(ids); i++ {
+		go func() {
+			fmt.Println(ids[i])
+		}()
+	}
+	for _, id := range ids {
+		id := id
+		go func() {
+			fmt.Println(id)
+		}()
+	}
+	for _, id := range ids {
+		go func() {
+			fmt.Println(id)
+		}()
+	}
+}
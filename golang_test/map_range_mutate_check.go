@@ -0,0 +1,25 @@
+package main
+
+import "strings"
+
+// pruneAliases deletes other keys while ranging over the map, so whether a
+// removed alias is still visited depends on iteration order.
+func pruneAliases(names map[string]string) {
+	for name, target := range names {
+		if strings.HasPrefix(target, "alias:") {
+			delete(names, strings.TrimPrefix(target, "alias:"))
+		}
+		if name == "" {
+			delete(names, name)
+		}
+	}
+}
+
+// expand inserts new keys while ranging, so the new entries may or may not
+// be visited by the same loop.
+func expand(counts map[string]int) {
+	for k, v := range counts {
+		counts[k+"_copy"] = v
+		counts[k] = v * 2
+	}
+}
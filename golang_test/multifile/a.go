@@ -0,0 +1,10 @@
+package multifile
+
+var (
+	x      = 0
+	shared = 1
+)
+
+func first() int {
+	return shared + x
+}
@@ -0,0 +1,18 @@
+package multifile
+
+var total = sum(
+	0,
+	shared,
+)
+
+func later() int {
+	return shared + x
+}
+
+func sum(v ...int) int {
+	out := 0
+	for _, n := range v {
+		out += n
+	}
+	return out
+}
@@ -0,0 +1,6 @@
+package main
+
+func connect(host string) string {
+	addr := host + ":pipe"
+	return addr
+}
@@ -0,0 +1,41 @@
+package main
+
+type nilNode struct {
+	val  int
+	next *nilNode
+}
+
+func findNode(head *nilNode, want int) *nilNode {
+	for n := head; n != nil; n = n.next {
+		if n.val == want {
+			return n
+		}
+	}
+	return nil
+}
+
+func nilGuards(head *nilNode) int {
+	total := head.val
+	n := findNode(head, 3)
+	if n != nil && n.val > 0 {
+		total += n.val
+	} else if n == nil {
+		return total
+	} else {
+		total -= n.val
+	}
+	fresh := new(nilNode)
+	fresh.val = total
+	var empty *nilNode
+	return empty.val + fresh.val
+}
+
+func nilWalk(head *nilNode) int {
+	sum := 0
+	n := &nilNode{next: head}
+	for i := 0; i < 3; i++ {
+		sum += n.val
+		n = n.next
+	}
+	return sum
+}
@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+func scanInto(dst *int) {
+	*dst = 42
+}
+
+// pointerArgCheck passes n by pointer to callees that write through it,
+// and also takes its address without a call.
+func pointerArgCheck() int {
+	n := 0
+	scanInto(&n)
+	_, _ = fmt.Sscan("7", &n)
+	p := &n
+	*p++
+	return n
+}
@@ -0,0 +1,7 @@
+package main
+
+// FreePricing charges nothing. Its value receiver lets both FreePricing and
+// *FreePricing satisfy PricingEngine.
+type FreePricing struct{}
+
+func (FreePricing) DynamicFee(*Order) (int64, error) { return 0, nil }
@@ -0,0 +1,80 @@
+//go:build go1.23
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"sync"
+)
+
+func countTo(n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := range n {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func pairs(m map[string]int) iter.Seq2[string, int] {
+	return func(yield func(string, int) bool) {
+		for k, v := range m {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func rangeInt() {
+	var wg sync.WaitGroup
+	for i := range 10 {
+		i = i + 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Println(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func rangeFunc() {
+	var wg sync.WaitGroup
+	for n := range countTo(3) {
+		n = n * 2
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fmt.Println(n)
+		}()
+	}
+	for k, v := range pairs(map[string]int{"a": 1}) {
+		v = v + len(k)
+		fmt.Println(k, v)
+	}
+	wg.Wait()
+}
+
+func rangeChan(ch chan int) {
+	total := 0
+	for range ch {
+		total = total + 1
+	}
+	for x := range ch {
+		go func() {
+			total += x
+		}()
+	}
+	fmt.Println(total)
+}
+
+func main() {
+	rangeInt()
+	rangeFunc()
+	ch := make(chan int)
+	close(ch)
+	rangeChan(ch)
+}
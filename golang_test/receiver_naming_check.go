@@ -0,0 +1,35 @@
+package main
+
+type receiverLedger struct {
+	entries []int
+}
+
+func (l *receiverLedger) add(v int) {
+	l.entries = append(l.entries, v)
+}
+
+func (ledger *receiverLedger) total() int {
+	sum := 0
+	for _, v := range ledger.entries {
+		sum += v
+	}
+	return sum
+}
+
+func (l receiverLedger) size() int {
+	return len(l.entries)
+}
+
+func (receiverLedger) kind() string {
+	return "ledger"
+}
+
+type receiverTally int
+
+func (t receiverTally) double() receiverTally {
+	return t * 2
+}
+
+func (t *receiverTally) bump() {
+	*t++
+}
@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+
+var limit = 10
+
+func scale(n int) int {
+	factor := 2
+	total := n * factor
+	for i := 0; i < limit; i++ {
+		step := i
+		total += step * factor
+	}
+	return total
+}
+
+func report() {
+	count := 3
+	fmt.Println(count, limit)
+}
+
+type box struct {
+	width  int
+	height int
+}
+
+func (b box) area() int { return b.width * b.height }
+
+func main() {
+	fmt.Println(scale(2), box{}.area())
+	report()
+}
+
+type labeled struct {
+	box
+	label string
+}
+
+func describe(v interface{}) string {
+	switch x := v.(type) {
+	case labeled:
+		_, _ = x.box, len(x.label)
+		return x.label
+	}
+	_ = v
+	return fmt.Sprint(v)
+}
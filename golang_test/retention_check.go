@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// frameBuffer holds frames handed in by callers.
+type frameBuffer struct {
+	last  []byte
+	frame [4096]byte
+}
+
+var lastFrame []byte
+
+func (b *frameBuffer) keep(frame []byte) {
+	b.last = frame
+	lastFrame = frame
+}
+
+func (b *frameBuffer) keepCopy(frame []byte) {
+	b.last = append([]byte(nil), frame...)
+}
+
+func newCounter() *int {
+	n := 0
+	return &n
+}
+
+func dumpLater(b *frameBuffer) {
+	frame := b.frame
+	go func() {
+		fmt.Println(len(frame))
+	}()
+}
+
+func pollForever() {
+	t := time.NewTicker(time.Second)
+	for range t.C {
+		fmt.Println("tick")
+	}
+}
+
+func pollLegacy() {
+	for range time.Tick(time.Second) {
+		fmt.Println("tick")
+	}
+}
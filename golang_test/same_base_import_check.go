@@ -0,0 +1,14 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"math/rand"
+)
+
+func sameBaseImportToken() (int, error) {
+	buf := make([]byte, 8)
+	if _, err := crand.Read(buf); err != nil {
+		return 0, err
+	}
+	return rand.Intn(len(buf)) + int(buf[0]), nil
+}
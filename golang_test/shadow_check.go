@@ -0,0 +1,30 @@
+package main
+
+import "fmt"
+
+// shadowCheck covers the shadowing report: an if-init shadow whose outer
+// variable is used afterwards, a loop variable copy, a type switch guard
+// and a block-local shadow.
+func shadowCheck(items []int, in interface{}) {
+	n := len(items)
+	if n := n * 2; n > 4 {
+		fmt.Println(n)
+	}
+	fmt.Println(n)
+	for _, it := range items {
+		it := it
+		go func() { fmt.Println(it) }()
+	}
+	switch in := in.(type) {
+	case int:
+		fmt.Println(in + 1)
+	case string:
+		fmt.Println(in)
+	}
+	total := 0
+	_ = total
+	{
+		total := 1
+		_ = total
+	}
+}
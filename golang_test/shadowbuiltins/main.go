@@ -0,0 +1,55 @@
+package main
+
+import "fmt"
+
+// max shadows the builtin for the whole package.
+var max = 10
+
+func computeMin() int { return 1 }
+
+func local() {
+	items := []int{1, 2, 3}
+	n := len(items)
+	len := 5
+	fmt.Println(len, n)
+
+	min := computeMin()
+	fmt.Println(min + max)
+
+	m := map[string]int{"a": 1}
+	clear := true
+	if clear {
+		fmt.Println(m)
+	}
+}
+
+func builtins() {
+	s := []int{3, 1}
+	fmt.Println(len(s), min(1, 2))
+	m := map[string]int{"a": 1}
+	clear(m)
+}
+
+func broken() {
+	len := undefinedCall()
+	fmt.Println(len)
+}
+
+// guard declares a type switch guard that shadows an outer variable.
+func guard(x any) int {
+	v := 1
+	_ = v
+	switch v := x.(type) {
+	case int:
+		return v
+	}
+	return 0
+}
+
+func main() {
+	local()
+	builtins()
+	broken()
+	guard(1)
+	fmt.Println(max)
+}
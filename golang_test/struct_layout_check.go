@@ -0,0 +1,34 @@
+package main
+
+import "sync"
+
+type paddedRecord struct {
+	active  bool
+	id      int64
+	flag    bool
+	count   int32
+	version uint16
+}
+
+type guardedRecord struct {
+	sync.Mutex
+	ready bool
+	total int64
+}
+
+type layoutPair[K comparable, V any] struct {
+	key   K
+	value V
+	seen  bool
+}
+
+var layoutPairInts layoutPair[int8, int64]
+
+func touchLayouts(r paddedRecord, g *guardedRecord) int64 {
+	g.Lock()
+	defer g.Unlock()
+	if r.active && r.flag && g.ready {
+		return r.id + int64(r.count) + int64(r.version) + g.total + layoutPairInts.value
+	}
+	return int64(layoutPairInts.key)
+}
@@ -0,0 +1,49 @@
+package main
+
+import "sync"
+
+//goanalyzer:ignore GA312 layout kept in the order of the wire format
+type suppressedPadded struct {
+	a bool
+	b int64
+	c bool
+}
+
+// fmt.Println("//goanalyzer:ignore GA312")
+type reportedPadded struct {
+	a bool
+	b int64
+	c bool
+}
+
+//goanalyzer:ignore GA101
+type otherCodePadded struct {
+	a bool
+	b int64
+	c bool
+}
+
+//goanalyzer:ignore-func GA3*
+func suppressedWaits() {
+	var wg sync.WaitGroup
+	go func() {
+		wg.Add(1)
+		wg.Done()
+	}()
+	wg.Wait()
+}
+
+func reportedWaits() string {
+	var wg sync.WaitGroup
+	msg := "//goanalyzer:ignore GA314"
+	go func() {
+		wg.Add(1)
+		wg.Done()
+	}()
+	go func() {
+		wg.Add(1) //goanalyzer:ignore
+		wg.Done()
+	}()
+	wg.Wait()
+	return msg
+}
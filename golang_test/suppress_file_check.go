@@ -0,0 +1,10 @@
+// Layouts below mirror a C header.
+//
+//goanalyzer:ignore-file GA31?
+package main
+
+type fileSuppressedPadded struct {
+	a bool
+	b int64
+	c bool
+}
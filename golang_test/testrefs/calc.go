@@ -0,0 +1,10 @@
+package testrefs
+
+// Add sums its operands.
+func Add(a, b int) int { return a + b }
+
+// Scale multiplies v by factor.
+func Scale(v, factor int) int { return v * factor }
+
+// Untested has no test referring to it.
+func Untested() int { return 0 }
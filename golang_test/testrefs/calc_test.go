@@ -0,0 +1,34 @@
+package testrefs
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("Add(1, 2) != 3")
+	}
+}
+
+func checkScale(t *testing.T, cases [][3]int) {
+	for _, c := range cases {
+		if got := Scale(c[0], c[1]); got != c[2] {
+			t.Errorf("Scale(%d, %d) = %d", c[0], c[1], got)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	checkScale(t, [][3]int{{2, 3, 6}, {0, 5, 0}})
+}
+
+func TestScaleNegative(t *testing.T) {
+	checkScale(t, [][3]int{{-2, 3, -6}})
+	if Scale(Add(1, 1), -1) != -2 {
+		t.Fatal("Scale(2, -1) != -2")
+	}
+}
+
+func BenchmarkAdd(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Add(i, i)
+	}
+}
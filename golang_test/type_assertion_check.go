@@ -0,0 +1,19 @@
+package main
+
+// appFrom unwraps an App stored behind an interface.
+func appFrom(v any) *App {
+	if app, ok := v.(*App); ok {
+		return app
+	}
+	return v.(*App)
+}
+
+func describeValue(v any) string {
+	switch v.(type) {
+	case *App, App:
+		return "app"
+	case *WorkerPool:
+		return "pool"
+	}
+	return "other"
+}
@@ -0,0 +1,16 @@
+package main
+
+type shadowedKind struct {
+	label string
+}
+
+func newShadowedKind() shadowedKind {
+	return shadowedKind{label: "k"}
+}
+
+func useShadowedKind() int {
+	k := newShadowedKind()
+	shadowedKind := len(k.label)
+	shadowedKind++
+	return shadowedKind
+}
@@ -0,0 +1,39 @@
+package main
+
+import "fmt"
+
+type typedNilErr struct{ msg string }
+
+func (e *typedNilErr) Error() string { return e.msg }
+
+type typedNilName struct{ name string }
+
+func (n *typedNilName) String() string { return n.name }
+
+func findTypedNil(ok bool) *typedNilErr {
+	if ok {
+		return nil
+	}
+	return &typedNilErr{msg: "bad"}
+}
+
+func typedNilCheck(ok bool) {
+	var err error
+	err = findTypedNil(ok)
+	if err != nil { // true even when findTypedNil returned nil
+		fmt.Println("failed:", err)
+	}
+	var n *typedNilName
+	var s fmt.Stringer = n
+	if nil == s {
+		return
+	}
+	var fresh fmt.Stringer = new(typedNilName)
+	if fresh == nil { // holds a non-nil pointer
+		return
+	}
+	direct := fmt.Errorf("x")
+	if direct == nil { // declared with the interface type itself
+		return
+	}
+}
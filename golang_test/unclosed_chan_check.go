@@ -0,0 +1,57 @@
+package main
+
+type workQueue struct {
+	jobs chan int
+	done chan struct{}
+}
+
+func newWorkQueue() *workQueue {
+	return &workQueue{jobs: make(chan int), done: make(chan struct{})}
+}
+
+func (q *workQueue) run() {
+	for j := range q.jobs {
+		_ = j
+	}
+	close(q.done)
+}
+
+func (q *workQueue) submit(j int) {
+	q.jobs <- j
+}
+
+func unclosedLocal() {
+	results := make(chan int)
+	go func() {
+		for r := range results {
+			_ = r
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		results <- i
+	}
+}
+
+func closedLocal() {
+	results := make(chan int)
+	go func() {
+		for r := range results {
+			_ = r
+		}
+	}()
+	results <- 1
+	close(results)
+}
+
+func escapingChan() {
+	out := make(chan int)
+	go produceInto(out)
+	for v := range out {
+		_ = v
+	}
+}
+
+func produceInto(out chan<- int) {
+	out <- 1
+	close(out)
+}
@@ -0,0 +1,8 @@
+package main
+
+func unicodeIdentifiers() int {
+	café := 1
+	naïveTotal := café + 2
+	日本 := naïveTotal * café
+	return 日本 + café
+}
@@ -0,0 +1,30 @@
+package main
+
+import "fmt"
+
+// This file intentionally does not compile: the compiler rejects unused
+// locals, and the unused mode must still report them from the unsaved
+// buffer.
+
+func handler(name string, retries int, _ bool, _debug bool) (count int, err error) {
+	unusedTotal := 0 // never read
+	written := 1
+	written = 2 // only written
+	seen := map[string]bool{}
+	seen[name] = true // an index write reads the map variable
+	var shadow int
+	_ = shadow  // a blank assignment counts as a read
+	_tmp := 3   // skipped by convention
+	count = len(seen)
+	return
+}
+
+type worker struct{}
+
+func (w worker) run(job string) {} // the receiver is never reported
+
+func main() {
+	c, err := handler("a", 1, true, false)
+	fmt.Println(c, err)
+	worker{}.run("x")
+}
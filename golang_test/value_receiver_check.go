@@ -0,0 +1,34 @@
+package main
+
+type hitCounter struct {
+	hits  int
+	last  string
+	stats struct{ total int }
+	byKey map[string]int
+	ptr   *hitCounter
+}
+
+// record has a value receiver, so every write below is made to a copy.
+func (c hitCounter) record(key string) {
+	c.hits++                 // lost
+	c.last = key             // lost
+	c.stats.total += 1       // lost: nested struct is part of the copy
+	c.byKey[key]++           // kept: the map is shared with the caller
+	c.ptr.hits = c.hits      // kept: written through a pointer
+	func() { c.last = "" }() // lost, even from a closure
+}
+
+func (c *hitCounter) recordPtr(key string) {
+	c.hits++
+	c.last = key
+}
+
+// withLast returns the modified copy, which is the builder idiom.
+func (c hitCounter) withLast(key string) hitCounter {
+	c.last = key
+	return c
+}
+
+func (c hitCounter) total() int {
+	return c.hits + c.stats.total
+}
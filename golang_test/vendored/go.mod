@@ -0,0 +1,5 @@
+module vendored
+
+go 1.20
+
+require example.com/greet v0.1.0
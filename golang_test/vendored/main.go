@@ -0,0 +1,13 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/greet"
+)
+
+func main() {
+	cfg := greet.New("vendor")
+	cfg.Loud = true
+	fmt.Println(cfg.Name, cfg.Loud)
+}
@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+func addInsideGoroutine(items []int) {
+	var wg sync.WaitGroup
+	for _, item := range items {
+		go func(v int) {
+			wg.Add(1)
+			defer wg.Done()
+			_ = v
+		}(item)
+	}
+	wg.Wait()
+}
+
+type wgBatch struct {
+	wg sync.WaitGroup
+}
+
+func (b *wgBatch) start(n int) {
+	for i := 0; i < n; i++ {
+		go func() {
+			b.wg.Add(1)
+			defer b.wg.Done()
+		}()
+	}
+	b.wg.Wait()
+}
+
+func addBeforeGoroutine(n int) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var inner sync.WaitGroup
+			inner.Add(1)
+			go func() { inner.Done() }()
+			inner.Wait()
+		}()
+	}
+	wg.Wait()
+}
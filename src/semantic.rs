@@ -44,6 +44,7 @@ struct SemanticRequest {
     line: u32,
     col: u32,
     content: String,
+    timeout_ms: u64,
 }
 
 #[derive(Deserialize)]
@@ -54,6 +55,8 @@ struct SemanticPos {
 
 #[derive(Deserialize)]
 struct SemanticRange {
+    #[serde(default)]
+    file: Option<String>,
     start: SemanticPos,
     end: SemanticPos,
 }
@@ -71,6 +74,8 @@ struct SemanticResponse {
     decl: SemanticRange,
     uses: Vec<SemanticUseEntry>,
     is_pointer: bool,
+    #[serde(default)]
+    error: Option<String>,
 }
 
 #[derive(Clone, Debug)]
@@ -103,17 +108,20 @@ pub async fn resolve_semantic_variable(
         return None;
     }
     let file_path = uri.to_file_path().ok()?;
+    let file_name = path_to_string(&file_path);
     let request = SemanticRequest {
-        file: path_to_string(&file_path),
+        file: file_name.clone(),
         line: position.line,
         col: position.character,
         content: code.to_string(),
+        timeout_ms: config.timeout_ms,
     };
     let input = serde_json::to_vec(&request).ok()?;
     let mut child = Command::new(&config.helper_path)
         .stdin(Stdio::piped())
         .stdout(Stdio::piped())
         .stderr(Stdio::piped())
+        .kill_on_drop(true)
         .spawn()
         .ok()?;
     if let Some(stdin) = child.stdin.as_mut() {
@@ -133,10 +141,14 @@ pub async fn resolve_semantic_variable(
     }
     let response: Option<SemanticResponse> = serde_json::from_slice(&output.stdout).ok()?;
     let response = response?;
+    if response.error.is_some() {
+        return None;
+    }
     let declaration = map_range(response.decl);
     let uses: Vec<SemanticUse> = response
         .uses
         .into_iter()
+        .filter(|entry| in_file(&entry.range, &file_name))
         .map(|entry| SemanticUse {
             range: map_range(entry.range),
             reassign: entry.reassign,
@@ -158,6 +170,13 @@ pub async fn resolve_semantic_variable(
     Some(SemanticVariable { info, uses })
 }
 
+fn in_file(range: &SemanticRange, file_name: &str) -> bool {
+    match &range.file {
+        Some(file) => Path::new(file) == Path::new(file_name),
+        None => true,
+    }
+}
+
 fn path_to_string(path: &Path) -> String {
     path.to_string_lossy().to_string()
 }
@@ -917,6 +917,58 @@ func getValues() (int, int) {
         assert!(var_info_c.declaration.start.line <= 3);
     }
 
+    #[test]
+    fn test_comma_ok_map_access() {
+        let code = r#"
+func main() {
+    m := map[string]int{"a": 1}
+    v, ok := m["a"]
+    if ok {
+        println(v)
+    }
+}
+        "#;
+        let tree = parse_go(code).expect("parse");
+
+        let ok_info = find_variable_at_position(&tree, code, Position::new(3, 7))
+            .expect("ok should resolve");
+        assert_eq!(ok_info.name, "ok");
+        assert_eq!(ok_info.declaration.start, Position::new(3, 7));
+        assert!(ok_info
+            .uses
+            .iter()
+            .any(|u| u.start == Position::new(4, 7)));
+
+        let v_info = find_variable_at_position(&tree, code, Position::new(3, 4))
+            .expect("v should resolve");
+        assert_eq!(v_info.name, "v");
+        assert!(v_info.uses.iter().any(|u| u.start == Position::new(5, 16)));
+    }
+
+    #[test]
+    fn test_comma_ok_type_assertion() {
+        let code = r#"
+func main() {
+    var x interface{} = "s"
+    v, ok := x.(string)
+    if ok {
+        println(v)
+    }
+}
+        "#;
+        let tree = parse_go(code).expect("parse");
+
+        let ok_info = find_variable_at_position(&tree, code, Position::new(4, 7))
+            .expect("ok should resolve from its use");
+        assert_eq!(ok_info.name, "ok");
+        assert_eq!(ok_info.declaration.start, Position::new(3, 7));
+
+        let v_info = find_variable_at_position(&tree, code, Position::new(3, 4))
+            .expect("v should resolve");
+        assert_eq!(v_info.name, "v");
+        assert!(v_info.uses.iter().any(|u| u.start == Position::new(5, 16)));
+    }
+
     #[test]
     fn test_channel_operations() {
         let code = r#"
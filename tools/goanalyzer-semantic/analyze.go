@@ -0,0 +1,303 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// Finding is a single diagnostic reported by an analyzer in "analyze" mode
+// or one of the report modes. Code identifies the analyzer stably across
+// releases; Severity is "error", "warning", "info" or "hint": the
+// analyzer's default unless the config's rules override it.
+type Finding struct {
+	Analyzer string         `json:"analyzer"`
+	Code     string         `json:"code"`
+	Severity string         `json:"severity"`
+	Message  string         `json:"message"`
+	Range    Range          `json:"range"`
+	Related  []RelatedRange `json:"related,omitempty"`
+}
+
+// RelatedRange points at a secondary site that explains a finding, such as
+// the other side of a racing pair of accesses.
+type RelatedRange struct {
+	Range   Range  `json:"range"`
+	Message string `json:"message"`
+}
+
+type AnalyzeOutput struct {
+	Findings        []Finding        `json:"findings"`
+	LoadDiagnostics []LoadDiagnostic `json:"load_diagnostics,omitempty"`
+	Degraded        bool             `json:"degraded,omitempty"`
+	PluginErrors    []PluginError    `json:"plugin_errors,omitempty"`
+	// Suppressed counts the findings dropped by //goanalyzer:ignore
+	// directives; see ignoreDirective.
+	Suppressed int `json:"suppressed,omitempty"`
+	// Baselined counts the findings dropped because --baseline recorded
+	// them, and StaleBaseline lists the recorded findings that are gone.
+	Baselined     int             `json:"baselined,omitempty"`
+	StaleBaseline []BaselineEntry `json:"stale_baseline,omitempty"`
+	ConfigWarning *ConfigWarning  `json:"config_warning,omitempty"`
+	// Config is the effective configuration, filled when the request sets
+	// want_config.
+	Config *Config `json:"config,omitempty"`
+
+	// analyzedFiles and analyzedCodes record what the run covered, so a
+	// baseline entry is only stale where its finding could have been
+	// reported.
+	analyzedFiles map[string]bool
+	analyzedCodes map[string]bool
+}
+
+type analyzer struct {
+	name string
+	code string
+	// summary describes in one line what the analyzer's findings have in
+	// common, for rule listings such as SARIF's.
+	summary  string
+	severity string
+	// report names the report mode that includes the analyzer, e.g. "race"
+	// for "race_report".
+	report string
+	run    func(p *pass) []Finding
+}
+
+// pass is the per-file state shared by all analyzers of one "analyze" request.
+// The embedded passes.Pass describes the same file for the checks of the
+// passes package and the helpers they share.
+type pass struct {
+	*loadedPackage
+	*passes.Pass
+	parents map[ast.Node]ast.Node
+	config  Config
+}
+
+// newPass returns the pass over file, one of lp's files.
+func newPass(lp *loadedPackage, file *ast.File, config Config) *pass {
+	flp := *lp
+	flp.file = file
+	parents := passes.ParentMap(file)
+	return &pass{
+		loadedPackage: &flp,
+		Pass: &passes.Pass{
+			Fset:           lp.fset,
+			File:           file,
+			Pkg:            lp.pkg,
+			Info:           lp.info,
+			Parents:        parents,
+			LargeValueSize: config.LargeValueSize,
+		},
+		parents: parents,
+		config:  config,
+	}
+}
+
+// fromCheck returns the analyzer running c, converting the positions of its
+// findings to ranges.
+func fromCheck(c *passes.Check) *analyzer {
+	return &analyzer{
+		name:     c.Name,
+		code:     c.Code,
+		summary:  c.Summary,
+		severity: c.Severity,
+		report:   c.Report,
+		run: func(p *pass) []Finding {
+			var findings []Finding
+			for _, f := range c.Run(p.Pass) {
+				finding := Finding{Message: f.Message, Range: rangeForPos(p.fset, f.Pos, f.End)}
+				for _, r := range f.Related {
+					finding.Related = append(finding.Related, RelatedRange{Range: rangeForPos(p.fset, r.Pos, r.End), Message: r.Message})
+				}
+				findings = append(findings, finding)
+			}
+			return findings
+		},
+	}
+}
+
+// The analyzers implemented by the passes package, which go vet can run
+// as well; see cmd/goanalyzer-vet.
+var (
+	fieldWriteAnalyzer    = fromCheck(passes.FieldWrite)
+	mixedAtomicAnalyzer   = fromCheck(passes.MixedAtomic)
+	captureUnlockAnalyzer = fromCheck(passes.CaptureUnlock)
+	loopCaptureAnalyzer   = fromCheck(passes.LoopCapture)
+	subSliceAnalyzer      = fromCheck(passes.SubSlice)
+	subStringAnalyzer     = fromCheck(passes.SubString)
+	largeCopyAnalyzer     = fromCheck(passes.LargeCopy)
+)
+
+var analyzers = []*analyzer{
+	appendRaceAnalyzer,
+	atomicLoadAnalyzer,
+	fieldWriteAnalyzer,
+	mixedAtomicAnalyzer,
+	captureUnlockAnalyzer,
+	loopCaptureAnalyzer,
+	goMutateAnalyzer,
+	mapRaceAnalyzer,
+	subSliceAnalyzer,
+	subStringAnalyzer,
+	mapAliasAnalyzer,
+	largeCopyAnalyzer,
+	largeCaptureAnalyzer,
+	localPointerAnalyzer,
+	tickerAnalyzer,
+	paramRetainAnalyzer,
+	goPanicAnalyzer,
+	valueReceiverAnalyzer,
+	errorWrapAnalyzer,
+	lockBlockAnalyzer,
+	rangeMutateAnalyzer,
+	unusedFieldAnalyzer,
+	ignoredReturnAnalyzer,
+	deferLoopAnalyzer,
+	typedNilAnalyzer,
+	loopInvariantAnalyzer,
+	unclosedChanAnalyzer,
+	paddingAnalyzer,
+	receiverNameAnalyzer,
+	wgAddAnalyzer,
+	complexityAnalyzer,
+}
+
+func analyze(in Input) *AnalyzeOutput {
+	enabled := make(map[string]bool)
+	for _, name := range in.Analyzers {
+		enabled[name] = true
+	}
+	return runAnalyzers(in, false, func(a *analyzer) bool {
+		return len(enabled) == 0 || enabled[a.name]
+	})
+}
+
+func raceReport(in Input) *AnalyzeOutput {
+	return report(in, func(a *analyzer) bool { return a.report == "race" })
+}
+
+func retentionReport(in Input) *AnalyzeOutput {
+	return report(in, func(a *analyzer) bool { return a.report == "retention" })
+}
+
+// concurrency runs the race analyzers together with those flagging
+// goroutines that can crash the process or block while holding a lock: the
+// whole-file concurrency check a client runs when a file is opened.
+func concurrency(in Input) *AnalyzeOutput {
+	return report(in, func(a *analyzer) bool {
+		return a.report == "race" || a == goPanicAnalyzer || a == lockBlockAnalyzer
+	})
+}
+
+// report runs every analyzer accepted by include over the target file, or
+// over each file of the package when ReportScope is "package". Where two
+// analyzers flag the same range, only the one listed first in analyzers is
+// kept.
+func report(in Input, include func(*analyzer) bool) *AnalyzeOutput {
+	out := runAnalyzers(in, in.ReportScope == "package", include)
+	if out == nil {
+		return nil
+	}
+	seen := make(map[Range]bool)
+	findings := out.Findings[:0]
+	for _, f := range out.Findings {
+		if !seen[f.Range] {
+			seen[f.Range] = true
+			findings = append(findings, f)
+		}
+	}
+	out.Findings = findings
+	return out
+}
+
+// runAnalyzers runs the analyzers accepted by include over the target file,
+// or over every file of its package when wholePackage is set. Each file is
+// analyzed on its own.
+func runAnalyzers(in Input, wholePackage bool, include func(*analyzer) bool) *AnalyzeOutput {
+	lp := loadPackage(in)
+	if lp == nil {
+		return nil
+	}
+	files := []*ast.File{lp.file}
+	if wholePackage {
+		files = lp.files
+	}
+	out := &AnalyzeOutput{
+		Findings:      make([]Finding, 0),
+		Degraded:      lp.degraded,
+		analyzedFiles: make(map[string]bool),
+		analyzedCodes: make(map[string]bool),
+	}
+	if in.WantDiagnostics {
+		out.LoadDiagnostics = lp.diagnostics
+	}
+	config, warning := effectiveConfig(in)
+	out.ConfigWarning = warning
+	if in.WantConfig {
+		out.Config = &config
+	}
+	enabled := make(map[string]bool)
+	if len(in.Analyzers) == 0 {
+		for _, code := range config.EnabledCodes {
+			enabled[code] = true
+		}
+	}
+	for _, file := range files {
+		out.analyzedFiles[lp.fset.Position(file.Pos()).Filename] = true
+		p := newPass(lp, file, config)
+		for _, a := range analyzers {
+			setting := config.Rules[a.code]
+			if !include(a) || len(enabled) > 0 && !enabled[a.code] || setting == "off" {
+				continue
+			}
+			out.analyzedCodes[a.code] = true
+			severity := a.severity
+			if setting != "" {
+				severity = setting
+			}
+			for _, f := range a.run(p) {
+				f.Analyzer = a.name
+				f.Code = a.code
+				f.Severity = severity
+				out.Findings = append(out.Findings, f)
+			}
+		}
+	}
+	if !lp.syntaxOnly {
+		runPlugins(lp, in.Plugins, wholePackage, out)
+	}
+	for _, f := range out.Findings {
+		out.analyzedCodes[f.Code] = true
+	}
+	out.Suppressed = suppressFindings(lp, out)
+	sort.SliceStable(out.Findings, func(i, j int) bool {
+		return rangeLess(out.Findings[i].Range, out.Findings[j].Range)
+	})
+	return out
+}
+
+func (p *pass) rangeForNode(n ast.Node) Range {
+	return rangeForPos(p.fset, n.Pos(), n.End())
+}
+
+func rangeForPos(fset *token.FileSet, pos, end token.Pos) Range {
+	start := fset.Position(pos)
+	stop := fset.Position(end)
+	return Range{
+		File:  start.Filename,
+		Start: Pos{Line: start.Line - 1, Col: start.Column - 1},
+		End:   Pos{Line: stop.Line - 1, Col: stop.Column - 1},
+	}
+}
+
+func rangeLess(a, b Range) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	if a.Start.Line != b.Start.Line {
+		return a.Start.Line < b.Start.Line
+	}
+	return a.Start.Col < b.Start.Col
+}
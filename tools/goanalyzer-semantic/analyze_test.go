@@ -0,0 +1,915 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runAnalyzer(t *testing.T, file, name string) []Finding {
+	t.Helper()
+	out := analyze(Input{File: fixture(t, file), Mode: "analyze", Analyzers: []string{name}})
+	if out == nil {
+		t.Fatalf("analyze %s returned nil", file)
+	}
+	return out.Findings
+}
+
+func findingLines(findings []Finding) []int {
+	lines := make([]int, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, f.Range.Start.Line)
+	}
+	return lines
+}
+
+func checkFindingLines(t *testing.T, findings []Finding, want ...int) {
+	t.Helper()
+	got := findingLines(findings)
+	if len(got) != len(want) {
+		t.Fatalf("got findings on lines %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got findings on lines %v, want %v", got, want)
+		}
+	}
+}
+
+func TestAppendRaceHotCache(t *testing.T) {
+	findings := runAnalyzer(t, "business_heavy.go", "appendrace")
+	checkFindingLines(t, findings, 117)
+	if len(findings[0].Related) != 2 {
+		t.Fatalf("got related %+v, want the len and range reads in RecentCache", findings[0].Related)
+	}
+}
+
+func TestAtomicLoadPlainReads(t *testing.T) {
+	findings := runAnalyzer(t, "atomic_load_check.go", "atomicload")
+	checkFindingLines(t, findings, 19, 34)
+}
+
+func TestGoPanicWithoutRecover(t *testing.T) {
+	findings := runAnalyzer(t, "goroutine_panic_check.go", "gopanic")
+	checkFindingLines(t, findings, 14)
+}
+
+func TestValueReceiverFieldWrites(t *testing.T) {
+	findings := runAnalyzer(t, "value_receiver_check.go", "valuereceiver")
+	checkFindingLines(t, findings, 11)
+	var lines []int
+	for _, r := range findings[0].Related {
+		lines = append(lines, r.Range.Start.Line)
+	}
+	if got, want := fmt.Sprint(lines), "[12 13 14 17]"; got != want {
+		t.Fatalf("got writes on lines %s, want %s", got, want)
+	}
+}
+
+// TestValueReceiverPartlyChecked analyzes a method whose indexed write has
+// no type because the field does not exist.
+func TestValueReceiverPartlyChecked(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	src := "package p\n\ntype counter struct{ hits [2]int }\n\nfunc (c counter) bump() {\n\tc.missing[0] = 1\n\tc.hits[0]++\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := analyze(Input{File: path, Mode: "analyze", Analyzers: []string{"valuereceiver"}})
+	if out == nil || len(out.Findings) != 1 || len(out.Findings[0].Related) != 1 {
+		t.Fatalf("got %+v, want one finding for the write to hits", out)
+	}
+	if line := out.Findings[0].Related[0].Range.Start.Line; line != 6 {
+		t.Errorf("got the write on line %d, want 6", line)
+	}
+}
+
+func TestErrorWrapWithoutW(t *testing.T) {
+	findings := runAnalyzer(t, "error_wrap_check.go", "errorwrap")
+	checkFindingLines(t, findings, 16, 22)
+}
+
+func TestFormatVerbs(t *testing.T) {
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{"plain", ""},
+		{"%d%%%v", "dv"},
+		{"%-8.3f %+v %#x", "fvx"},
+		{"%*d %.*s", "*d*s"},
+		{"%w: %v", "wv"},
+	}
+	for _, tc := range cases {
+		verbs, ok := formatVerbs(tc.format)
+		if !ok || string(verbs) != tc.want {
+			t.Errorf("%q: got %q (%v), want %q", tc.format, string(verbs), ok, tc.want)
+		}
+	}
+	if _, ok := formatVerbs("%[2]v %[1]v"); ok {
+		t.Error("explicit argument indexes should not be parsed")
+	}
+}
+
+func TestLockBlockSleepAndSendUnderLock(t *testing.T) {
+	findings := runAnalyzer(t, "lock_blocking_check.go", "lockblock")
+	checkFindingLines(t, findings, 16, 23, 42)
+	for _, f := range findings {
+		if len(f.Related) != 1 || f.Related[0].Message != "mu locked here" {
+			t.Errorf("line %d: got related %+v, want the Lock call", f.Range.Start.Line, f.Related)
+		}
+	}
+}
+
+func TestLockBlockIgnoresCPUWork(t *testing.T) {
+	// heavyUnderLock sorts under the lock; the only sleep in the file runs
+	// on another goroutine.
+	findings := runAnalyzer(t, "field_signals_check.go", "lockblock")
+	checkFindingLines(t, findings)
+}
+
+// TestRaceReportGolden pins the race findings of the deliberately racy
+// fixtures. business_heavy.go's classic for-loop capture is not reported
+// because golang_test declares go 1.23, where loop variables are per
+// iteration; the loopcapture module covers that check. realistic.go's
+// locked Store methods stay clean and only the unlocked store.total++ in
+// main is reported.
+func TestRaceReportGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"field_signals_check.go", []string{
+			"GA101 warning 37:1 related=[133]",
+			"GA102 error 45:1 related=[41]",
+			"GA103 warning 90:10 related=[87]",
+			"GA103 warning 91:6 related=[87]",
+			"GA107 error 98:1 related=[90]",
+			"GA102 error 133:24 related=[41]",
+		}},
+		{"business_heavy.go", []string{
+			"GA107 error 117:4 related=[131 132]",
+			"GA105 warning 170:2 related=[140]",
+		}},
+		{"realistic.go", []string{
+			"GA105 warning 91:2 related=[55]",
+		}},
+		{"concurrent_map_check.go", []string{
+			"GA106 error 12:1 related=[18]",
+		}},
+		{"loopcapture/main.go", []string{
+			"GA104 warning 9:19 related=[7]",
+			"GA104 warning 20:15 related=[18]",
+		}},
+	}
+	for _, tt := range tests {
+		out := raceReport(Input{File: fixture(t, tt.file), Mode: "race_report"})
+		if out == nil {
+			t.Fatalf("%s: race_report returned nil", tt.file)
+		}
+		var got []string
+		for _, f := range out.Findings {
+			var related []int
+			for _, r := range f.Related {
+				related = append(related, r.Range.Start.Line)
+			}
+			got = append(got, fmt.Sprintf("%s %s %d:%d related=%v", f.Code, f.Severity, f.Range.Start.Line, f.Range.Start.Col, related))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+// TestRaceReportRules reruns field_signals_check.go with rule overrides:
+// GA102 is off and the other codes change severity.
+func TestRaceReportRules(t *testing.T) {
+	in := Input{File: fixture(t, "field_signals_check.go"), Mode: "race_report", Config: &Config{
+		Rules: map[string]string{"GA101": "error", "GA102": "off", "GA103": "hint", "GA107": "bogus"},
+	}}
+	out := raceReport(in)
+	if out == nil {
+		t.Fatal("race_report returned nil")
+	}
+	var got []string
+	for _, f := range out.Findings {
+		got = append(got, fmt.Sprintf("%s %s %d", f.Code, f.Severity, f.Range.Start.Line))
+	}
+	want := []string{"GA101 error 37", "GA103 hint 90", "GA103 hint 91", "GA107 error 98"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestRaceReportPackageScope(t *testing.T) {
+	out := raceReport(Input{File: fixture(t, "realistic.go"), Mode: "race_report", ReportScope: "package"})
+	if out == nil {
+		t.Fatal("race_report returned nil")
+	}
+	files := make(map[string]bool)
+	for _, f := range out.Findings {
+		files[f.Range.File] = true
+	}
+	for _, name := range []string{"business_heavy.go", "field_signals_check.go", "realistic.go"} {
+		if !files[fixture(t, name)] {
+			t.Errorf("package scope has no findings in %s", name)
+		}
+	}
+}
+
+func TestConcurrencyGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"business_heavy.go", []string{"GA107 appendrace error 117", "GA105 gomutate warning 170"}},
+		{"goroutine_panic_check.go", []string{"GA302 gopanic error 14"}},
+		{"lock_blocking_check.go", []string{
+			"GA301 lockblock warning 16",
+			"GA301 lockblock warning 23",
+			"GA301 lockblock warning 42",
+		}},
+		{"retention_check.go", nil},
+	}
+	for _, tt := range tests {
+		out := concurrency(Input{File: fixture(t, tt.file), Mode: "concurrency"})
+		if out == nil {
+			t.Fatalf("%s: concurrency returned nil", tt.file)
+		}
+		var got []string
+		for _, f := range out.Findings {
+			got = append(got, fmt.Sprintf("%s %s %s %d", f.Code, f.Analyzer, f.Severity, f.Range.Start.Line))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestRangeMutateOtherKeys(t *testing.T) {
+	findings := runAnalyzer(t, "map_range_mutate_check.go", "rangemutate")
+	// delete(names, name) and counts[k] = ... touch the current key and
+	// are well defined.
+	checkFindingLines(t, findings, 9, 21)
+	for i, want := range []int{7, 20} {
+		if r := findings[i].Related; len(r) != 1 || r[0].Range.Start.Line != want {
+			t.Errorf("finding %d: got related %+v, want the range over the map on line %d", i, r, want)
+		}
+	}
+}
+
+// TestRetentionReportGolden pins the retention findings. loadRetention and
+// retentionPath hold the sub-slice, substring and map aliasing positives;
+// the copies in SnapshotByUser are of small values and stay clean.
+func TestRetentionReportGolden(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"field_signals_check.go", []string{
+			"GA201 75:1", "GA202 76:1", "GA203 77:1", "GA201 78:1",
+			"GA204 98:33", "GA204 106:1", "GA204 107:21", "GA204 120:23",
+			"GA204 129:2", "GA204 130:19", "GA204 131:24",
+		}},
+		{"comments_layout_check.go", []string{"GA201 54:1", "GA202 59:1", "GA203 62:1"}},
+		{"retention_check.go", []string{
+			"GA208 16:1", "GA208 17:1", "GA206 26:8", "GA204 30:1",
+			"GA205 32:18", "GA207 37:6", "GA207 44:11",
+		}},
+	}
+	for _, tt := range tests {
+		out := retentionReport(Input{File: fixture(t, tt.file), Mode: "retention_report"})
+		if out == nil {
+			t.Fatalf("%s: retention_report returned nil", tt.file)
+		}
+		var got []string
+		for _, f := range out.Findings {
+			got = append(got, fmt.Sprintf("%s %d:%d", f.Code, f.Range.Start.Line, f.Range.Start.Col))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.file, got, tt.want)
+		}
+	}
+
+	file := fixture(t, "business_heavy.go")
+	start, end := funcLines(t, file, "SnapshotByUser")
+	out := retentionReport(Input{File: file, Mode: "retention_report"})
+	for _, f := range out.Findings {
+		if f.Range.Start.Line >= start && f.Range.Start.Line <= end {
+			t.Errorf("unexpected finding in SnapshotByUser: %+v", f)
+		}
+	}
+}
+
+// funcLines returns the zero-based first and last line of the function or
+// method declared as name in file.
+func funcLines(t *testing.T, file, name string) (int, int) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Name == name {
+			return fset.Position(fd.Pos()).Line - 1, fset.Position(fd.End()).Line - 1
+		}
+	}
+	t.Fatalf("%s does not declare %s", file, name)
+	return 0, 0
+}
+
+func TestRetentionReportDeduplicates(t *testing.T) {
+	// s.hotWindow = raw[:8] both slices and retains the raw parameter; the
+	// report keeps only the sub-slice finding.
+	checkFindingLines(t, runAnalyzer(t, "field_signals_check.go", "paramretain"), 75, 77, 78)
+	out := retentionReport(Input{File: fixture(t, "field_signals_check.go"), Mode: "retention_report"})
+	for _, f := range out.Findings {
+		if f.Code == "GA208" {
+			t.Errorf("paramretain finding on line %d was not deduplicated", f.Range.Start.Line)
+		}
+	}
+}
+
+func TestUnusedFields(t *testing.T) {
+	findings := runAnalyzer(t, "dead_field_check.go", "unusedfield")
+	checkFindingLines(t, findings, 5)
+	if findings[0].Message != "field legacy is never read or written" {
+		t.Fatalf("got message %q", findings[0].Message)
+	}
+
+	// hotWindow is written in loadRetention, so it is not reported.
+	for _, f := range runAnalyzer(t, "field_signals_check.go", "unusedfield") {
+		t.Errorf("unexpected finding %s at line %d", f.Message, f.Range.Start.Line)
+	}
+}
+
+func TestIgnoredReturnAtEveryCallSite(t *testing.T) {
+	findings := runAnalyzer(t, "ignored_return_check.go", "ignoredreturn")
+	checkFindingLines(t, findings, 5)
+	if len(findings[0].Related) != 3 {
+		t.Fatalf("got related %+v, want the bare, blank and deferred calls", findings[0].Related)
+	}
+
+	// parseRetries has one caller that keeps its int result.
+	out := callers(Input{File: fixture(t, "ignored_return_check.go"), Line: 9, Col: 6})
+	if out == nil || len(out.Callers) != 2 || out.ResultAlwaysIgnored {
+		t.Fatalf("got %+v, want two callers of parseRetries, one using the result", out)
+	}
+	if !out.Callers[0].ResultIgnored || out.Callers[1].ResultIgnored {
+		t.Fatalf("got call sites %+v, want only the first to ignore the result", out.Callers)
+	}
+	helper := callers(Input{File: fixture(t, "ignored_return_check.go"), Line: 5, Col: 6})
+	if helper == nil || !helper.ResultAlwaysIgnored {
+		t.Fatalf("got %+v, want normalizeTag's result always ignored", helper)
+	}
+}
+
+func TestDeferLoop(t *testing.T) {
+	findings := runAnalyzer(t, "defer_loop_check.go", "deferloop")
+	checkFindingLines(t, findings, 17)
+	if r := findings[0].Related; len(r) != 1 || r[0].Range.Start.Line != 12 {
+		t.Fatalf("got related %+v, want the range loop", r)
+	}
+	// A defer in a literal called per iteration runs at the end of each call.
+	checkFindingLines(t, runAnalyzer(t, "defer_literal_check.go", "deferloop"))
+}
+
+func TestTypedNilComparison(t *testing.T) {
+	findings := runAnalyzer(t, "typed_nil_check.go", "typednil")
+	checkFindingLines(t, findings, 22, 27)
+	if r := findings[0].Related; len(r) != 1 || r[0].Range.Start.Line != 21 || r[0].Message != "assigned a *typedNilErr that may be nil" {
+		t.Fatalf("got related %+v, want the findTypedNil assignment", r)
+	}
+}
+
+func TestSARIFRaceReport(t *testing.T) {
+	in := Input{File: fixture(t, "business_heavy.go"), Mode: "race_report", Format: "sarif"}
+	data, err := json.Marshal(toSARIF(raceReport(in), in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Decode generically so the test checks the wire shape rather than
+	// the structs that produced it.
+	type region struct{ StartLine, StartColumn, EndLine, EndColumn int }
+	type location struct {
+		ID               *int
+		PhysicalLocation *struct {
+			ArtifactLocation struct{ URI string }
+			Region           *region
+		}
+		Message *struct{ Text string }
+	}
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct {
+						ID                   string
+						ShortDescription     struct{ Text string }
+						DefaultConfiguration struct{ Level string }
+					}
+				}
+			}
+			Results []struct {
+				RuleID           string
+				RuleIndex        int
+				Level            string
+				Message          struct{ Text string }
+				Locations        []location
+				RelatedLocations []location
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || log.Schema == "" || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name == "" {
+		t.Fatalf("bad log header: %s", data)
+	}
+	run := log.Runs[0]
+	if len(run.Results) == 0 {
+		t.Fatal("got no results")
+	}
+	for _, rule := range run.Tool.Driver.Rules {
+		if rule.ShortDescription.Text == "" || rule.DefaultConfiguration.Level == "" {
+			t.Errorf("rule %s has no description or level", rule.ID)
+		}
+	}
+	checkLocation := func(loc location) {
+		t.Helper()
+		if loc.PhysicalLocation == nil || loc.PhysicalLocation.Region == nil || loc.PhysicalLocation.ArtifactLocation.URI == "" {
+			t.Fatalf("incomplete location %+v", loc)
+		}
+		r := loc.PhysicalLocation.Region
+		if r.StartLine < 1 || r.StartColumn < 1 || r.EndLine < r.StartLine || r.EndLine == r.StartLine && r.EndColumn < r.StartColumn {
+			t.Errorf("bad region %+v", *r)
+		}
+	}
+	related := 0
+	for _, res := range run.Results {
+		if res.RuleIndex >= len(run.Tool.Driver.Rules) || run.Tool.Driver.Rules[res.RuleIndex].ID != res.RuleID {
+			t.Errorf("result %s points at rule %d", res.RuleID, res.RuleIndex)
+		}
+		if res.Level != "error" && res.Level != "warning" && res.Level != "note" || res.Message.Text == "" || len(res.Locations) != 1 {
+			t.Errorf("bad result %+v", res)
+		}
+		checkLocation(res.Locations[0])
+		for _, loc := range res.RelatedLocations {
+			checkLocation(loc)
+			if loc.ID == nil || loc.Message == nil {
+				t.Errorf("related location without id or message: %+v", loc)
+			}
+			related++
+		}
+	}
+	if related == 0 {
+		t.Error("got no related locations, want the reads racing with the append")
+	}
+
+	// The appendrace finding on hotCache, at byte column 4 of line 117.
+	loc := run.Results[0].Locations[0].PhysicalLocation.Region
+	if run.Results[0].RuleID != "GA107" || *loc != (region{118, 5, 118, 15}) {
+		t.Errorf("got %s at %+v, want GA107 at 118:5-118:15", run.Results[0].RuleID, *loc)
+	}
+}
+
+func TestSARIFFlag(t *testing.T) {
+	dir := t.TempDir()
+	helper := filepath.Join(dir, "goanalyzer-semantic")
+	if out, err := exec.Command("go", "build", "-o", helper, ".").CombinedOutput(); err != nil {
+		t.Fatalf("building the helper: %v\n%s", err, out)
+	}
+	run := func(in Input, path string) error {
+		t.Helper()
+		req, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(helper, "-sarif", path)
+		cmd.Stdin = strings.NewReader(string(req))
+		return cmd.Run()
+	}
+
+	// A report whose target does not load still gives a valid log.
+	empty := filepath.Join(dir, "empty.sarif")
+	if err := run(Input{File: filepath.Join(dir, "missing.go"), Mode: "race_report"}, empty); err != nil {
+		t.Fatalf("race_report: %v", err)
+	}
+	data, err := os.ReadFile(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct{ Results []json.RawMessage }
+	}
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || log.Runs[0].Results == nil || len(log.Runs[0].Results) != 0 {
+		t.Fatalf("got %s, want one run with empty results", data)
+	}
+
+	// Modes without findings are rejected instead of ignoring the flag.
+	rejected := filepath.Join(dir, "outline.sarif")
+	err = run(Input{File: fixture(t, "business_heavy.go"), Mode: "outline"}, rejected)
+	if exit, ok := err.(*exec.ExitError); !ok || exit.ExitCode() != 2 {
+		t.Fatalf("outline: got %v, want exit status 2", err)
+	}
+	if _, err := os.Stat(rejected); !os.IsNotExist(err) {
+		t.Fatalf("outline wrote %s", rejected)
+	}
+}
+
+func TestLoopInvariantCalls(t *testing.T) {
+	findings := runAnalyzer(t, "loop_invariant_check.go", "loopinvariant")
+	checkFindingLines(t, findings, 12, 13, 17)
+	// The outer call is reported, not the TrimSpace nested in it.
+	if r := findings[2].Range; r.Start.Col != 6 {
+		t.Errorf("got the Split finding at column %d, want the outer call at 6", r.Start.Col)
+	}
+}
+
+func TestUnclosedChannels(t *testing.T) {
+	findings := runAnalyzer(t, "unclosed_chan_check.go", "unclosedchan")
+	// The jobs field and the results channel of unclosedLocal; done is
+	// closed, and escapingChan's channel is closed by produceInto.
+	checkFindingLines(t, findings, 3, 23)
+	if r := findings[0].Related; len(r) != 2 || r[0].Range.Start.Line != 12 || r[1].Range.Start.Line != 19 {
+		t.Fatalf("got related %+v, want the range in run and the send in submit", r)
+	}
+}
+
+func TestPadding(t *testing.T) {
+	if build.Default.GOARCH != "amd64" && build.Default.GOARCH != "arm64" {
+		t.Skip("sizes are checked for 64-bit architectures")
+	}
+	findings := runAnalyzer(t, "struct_layout_check.go", "padding")
+	// paddedRecord only: guardedRecord has no smaller order and
+	// layoutPair is generic.
+	checkFindingLines(t, findings, 4)
+	want := "struct paddedRecord is 32 bytes; ordering its fields as id, count, version, active, flag makes it 16 bytes, saving 16"
+	if findings[0].Message != want {
+		t.Errorf("got %q, want %q", findings[0].Message, want)
+	}
+	if r := findings[0].Range; r.End.Line != 10 {
+		t.Errorf("got range %+v, want the whole declaration", r)
+	}
+}
+
+func TestReceiverNames(t *testing.T) {
+	findings := runAnalyzer(t, "receiver_naming_check.go", "receivername")
+	// Only total's ledger: l is used twice, kind's receiver is unnamed and
+	// receiverTally's methods agree.
+	checkFindingLines(t, findings, 10)
+	if f := findings[0]; f.Range.Start.Col != 6 || len(f.Related) != 1 || f.Related[0].Range.Start.Line != 6 {
+		t.Errorf("got %+v, want ledger related to add's l", f)
+	}
+}
+
+func TestWaitGroupAddInGoroutine(t *testing.T) {
+	findings := runAnalyzer(t, "wg_add_check.go", "wgadd")
+	// addBeforeGoroutine calls Add first, and inner belongs to the
+	// goroutine that adds to it.
+	checkFindingLines(t, findings, 8, 23)
+	if r := findings[0].Related; len(r) != 1 || r[0].Range.Start.Line != 7 {
+		t.Errorf("got related %+v, want the go statement", r)
+	}
+}
+
+func TestSuppressionComments(t *testing.T) {
+	in := Input{File: fixture(t, "suppress_check.go"), Mode: "analyze", Analyzers: []string{"padding", "wgadd"}}
+	out := analyze(in)
+	if out == nil {
+		t.Fatal("analyze returned nil")
+	}
+	// reportedPadded has the directive only inside commented-out code,
+	// otherCodePadded's names another code, and the first goroutine of
+	// reportedWaits has it only in a string. suppressedPadded, the Add of
+	// suppressedWaits and the one with a trailing directive are dropped.
+	checkFindingLines(t, out.Findings, 12, 19, 39)
+	if out.Suppressed != 3 {
+		t.Errorf("got %d suppressed, want 3", out.Suppressed)
+	}
+
+	in.File = fixture(t, "suppress_file_check.go")
+	if out := analyze(in); out == nil || len(out.Findings) != 0 || out.Suppressed != 1 {
+		t.Errorf("got %+v, want the file's padding finding suppressed", out)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	write := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module b\n\ngo 1.20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	const structs = "type first struct {\n\ta bool\n\tb int64\n\tc bool\n}\n\ntype second struct {\n\ta bool\n\tb int64\n\tc bool\n}\n"
+	write("package p\n\n" + structs)
+	in := Input{File: path, Mode: "analyze", Analyzers: []string{"padding"}}
+	baseline := filepath.Join(dir, ".goanalyzer-baseline.json")
+	out := analyze(in)
+	if len(out.Findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(out.Findings))
+	}
+	if err := writeBaseline(baseline, out, in); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines inserted above move the findings but keep their fingerprints.
+	write("package p\n\n// Added later.\nvar unrelated = 1\n\n" + structs)
+	out = analyze(in)
+	if err := applyBaseline(baseline, out, in); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Findings) != 0 || out.Baselined != 2 || out.StaleBaseline != nil {
+		t.Fatalf("got %+v, want both findings baselined", out)
+	}
+
+	// A new finding is reported, and the renamed struct's entry is stale.
+	write("package p\n\n" + strings.Replace(structs, "type second", "type renamed", 1))
+	out = analyze(in)
+	if err := applyBaseline(baseline, out, in); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Findings) != 1 || !strings.Contains(out.Findings[0].Message, "renamed") || out.Baselined != 1 {
+		t.Fatalf("got %+v, want only the renamed struct reported", out)
+	}
+	if s := out.StaleBaseline; len(s) != 1 || s[0].File != "p.go" || !strings.Contains(s[0].Message, "second") {
+		t.Errorf("got stale entries %+v, want second's", s)
+	}
+
+	// Entries for analyzers that did not run are not stale.
+	in.Analyzers = []string{"wgadd"}
+	out = analyze(in)
+	if err := applyBaseline(baseline, out, in); err != nil || out.StaleBaseline != nil {
+		t.Errorf("got %+v (err %v), want no stale entries", out.StaleBaseline, err)
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	// complexBusinessFlow scores 10, the default threshold, which is not
+	// above it.
+	checkFindingLines(t, runAnalyzer(t, "business_heavy.go", "complexity"))
+
+	in := Input{File: fixture(t, "business_heavy.go"), Mode: "analyze", Analyzers: []string{"complexity"}, Config: &Config{ComplexityThreshold: 8}}
+	out := analyze(in)
+	if out == nil {
+		t.Fatal("analyze returned nil")
+	}
+	checkFindingLines(t, out.Findings, 89, 215)
+	want := "App.StartWorkers has cyclomatic complexity 9, above the threshold of 8"
+	if f := out.Findings[0]; f.Message != want || f.Range.End.Line <= f.Range.Start.Line {
+		t.Errorf("got %q over %+v, want %q over the declaration", f.Message, f.Range, want)
+	}
+}
+
+// TestPluginHelperProcess is not a test: it is the misbehaving plugin run
+// by TestPlugins, selected by GA_PLUGIN_HELPER.
+func TestPluginHelperProcess(t *testing.T) {
+	switch os.Getenv("GA_PLUGIN_HELPER") {
+	case "hang":
+		time.Sleep(time.Minute)
+	case "garbage":
+		fmt.Println("not json")
+	default:
+		return
+	}
+	os.Exit(0)
+}
+
+func TestPlugins(t *testing.T) {
+	plugin := filepath.Join(t.TempDir(), "writeonly")
+	if out, err := exec.Command("go", "build", "-o", plugin, "./plugins/writeonly").CombinedOutput(); err != nil {
+		t.Fatalf("building the example plugin: %v\n%s", err, out)
+	}
+	file := fixture(t, "field_signals_check.go")
+	in := Input{File: file, Mode: "analyze", Analyzers: []string{"unusedfield"}, Plugins: []PluginSpec{
+		{Cmd: plugin, Findings: true},
+		// Not asked for findings, so not run.
+		{Cmd: plugin, Name: "quiet"},
+	}}
+	out := analyze(in)
+	if out == nil {
+		t.Fatal("got nil")
+	}
+	var got []string
+	for _, f := range out.Findings {
+		if strings.HasPrefix(f.Code, "writeonly/") {
+			if f.Range.File != file {
+				t.Errorf("got a finding in %s, want only the target file", f.Range.File)
+			}
+			got = append(got, fmt.Sprintf("%s %d", f.Code, f.Range.Start.Line))
+		}
+	}
+	// statusCode, hotWindow and shortLabel are written but never read.
+	if want := "[writeonly/WO001 24 writeonly/WO001 26 writeonly/WO001 27]"; fmt.Sprint(got) != want {
+		t.Errorf("got plugin findings %v, want %s", got, want)
+	}
+	if len(out.PluginErrors) != 0 {
+		t.Errorf("got plugin errors %+v", out.PluginErrors)
+	}
+
+	// A hanging plugin is killed at its timeout and one answering garbage
+	// is reported; neither affects the built-in findings.
+	t.Setenv("GA_PLUGIN_HELPER", "hang")
+	helper := []string{"-test.run=^TestPluginHelperProcess$"}
+	start := time.Now()
+	in.Plugins = []PluginSpec{{Cmd: os.Args[0], Args: helper, Name: "hang", Findings: true, TimeoutMs: 200}}
+	hung := analyze(in)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("analysis took %v with a hanging plugin", elapsed)
+	}
+	if hung == nil || len(hung.PluginErrors) != 1 || hung.PluginErrors[0].Plugin != "hang" {
+		t.Fatalf("got %+v, want the hanging plugin reported", hung)
+	}
+	t.Setenv("GA_PLUGIN_HELPER", "garbage")
+	in.Plugins[0].Name = "garbage"
+	bad := analyze(in)
+	if bad == nil || len(bad.PluginErrors) != 1 || len(bad.Findings) != len(out.Findings)-len(got) {
+		t.Fatalf("got %+v, want the garbage plugin reported and the built-in findings kept", bad)
+	}
+}
+
+func TestProjectConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module cfg\n\ngo 1.20\n")
+	write("p.go", `package p
+
+import "sync"
+
+var mu sync.Mutex
+
+func slowQuery() int { return 0 }
+
+func locked(buf [64]byte) [64]byte {
+	mu.Lock()
+	defer mu.Unlock()
+	_ = slowQuery()
+	copied := buf
+	return copied
+}
+`)
+	write("goanalyzer.json", `{"large_value_size": 64, "blocking_funcs": ["p.slowQuery"]}`)
+	file := filepath.Join(dir, "p.go")
+	codes := func(out *AnalyzeOutput) string {
+		t.Helper()
+		if out == nil {
+			t.Fatal("got nil")
+		}
+		var got []string
+		for _, f := range out.Findings {
+			got = append(got, fmt.Sprintf("%s %d", f.Code, f.Range.Start.Line))
+		}
+		return fmt.Sprint(got)
+	}
+
+	// The config adds slowQuery to the blocking calls and lowers the
+	// large copy threshold to the array's size.
+	in := Input{File: file, Mode: "analyze", Analyzers: []string{"lockblock", "largecopy"}, WantConfig: true}
+	out := analyze(in)
+	if got, want := codes(out), "[GA301 11 GA204 12]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if out.Config == nil || out.Config.LargeValueSize != 64 || out.ConfigWarning != nil {
+		t.Errorf("got config %+v, warning %+v", out.Config, out.ConfigWarning)
+	}
+
+	// Request settings win over the file's.
+	in.Config = &Config{LargeValueSize: 1024}
+	if got, want := codes(analyze(in)), "[GA301 11]"; got != want {
+		t.Errorf("with a request override: got %s, want %s", got, want)
+	}
+
+	// Enabled codes apply when the request names no analyzers. The file
+	// changes size, so the cached config is reparsed whatever the mtime.
+	write("goanalyzer.json", `{"large_value_size": 64, "enabled_codes": ["GA204"]}`)
+	if got, want := codes(analyze(Input{File: file, Mode: "analyze"})), "[GA204 12]"; got != want {
+		t.Errorf("with enabled codes: got %s, want %s", got, want)
+	}
+
+	// An unknown rule setting makes the whole file unusable.
+	write("goanalyzer.json", `{"rules": {"GA204": "loud"}}`)
+	if w := analyze(Input{File: file, Mode: "analyze"}).ConfigWarning; w == nil || !strings.Contains(w.Message, "GA204") {
+		t.Errorf("got warning %+v, want the GA204 rule reported", w)
+	}
+
+	// A malformed file is reported and ignored.
+	write("goanalyzer.json", `{"large_value_size": "big"}`)
+	out = analyze(Input{File: file, Mode: "analyze", Analyzers: []string{"largecopy"}})
+	if got := codes(out); got != "[]" {
+		t.Errorf("with a malformed config: got %s, want the default threshold", got)
+	}
+	if w := out.ConfigWarning; w == nil || filepath.Base(w.File) != "goanalyzer.json" || w.Message == "" {
+		t.Errorf("got warning %+v, want the malformed goanalyzer.json reported", w)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	write := func(src string) {
+		t.Helper()
+		// Save the way editors do: write a temporary file and rename it
+		// over the target.
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module w\n\ngo 1.20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	write("package p\n\ntype loose struct {\n\ta bool\n\tb int64\n\tc bool\n}\n")
+
+	r, w := io.Pipe()
+	stop := make(chan os.Signal, 1)
+	done := make(chan int, 1)
+	in := Input{File: path, Mode: "analyze", Analyzers: []string{"padding"}}
+	go func() {
+		done <- watch(in, analyze, true, 10*time.Millisecond, w, stop)
+		w.Close()
+	}()
+	lines := bufio.NewScanner(r)
+	next := func() WatchEvent {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("watch stopped writing: %v", lines.Err())
+		}
+		var ev WatchEvent
+		if err := json.Unmarshal(lines.Bytes(), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, ev.Time); err != nil {
+			t.Errorf("bad time %q", ev.Time)
+		}
+		return ev
+	}
+
+	// The first run reports in full, later ones the difference.
+	if ev := next(); ev.Report == nil || len(ev.Report.Findings) != 1 {
+		t.Fatalf("got %+v, want a full report with the padded struct", ev)
+	}
+	write("package p\n\ntype loose struct {\n\tb int64\n\ta bool\n\tc bool\n}\n\ntype tight struct {\n\tx int64\n}\n")
+	if ev := next(); ev.Report != nil || len(ev.Added) != 0 || len(ev.Removed) != 1 || ev.Removed[0].Code != "GA312" {
+		t.Fatalf("got %+v, want the padding finding removed", ev)
+	}
+	write("package p\n\ntype loose struct {\n\tb bool\n\ta int64\n\tc bool\n}\n")
+	if ev := next(); len(ev.Added) != 1 || len(ev.Removed) != 0 {
+		t.Fatalf("got %+v, want the padding finding back", ev)
+	}
+
+	stop <- os.Interrupt
+	if code := <-done; code != 0 {
+		t.Errorf("exit code %d, want 0", code)
+	}
+	if lines.Scan() {
+		t.Errorf("got %s after stopping, want no pending report", lines.Text())
+	}
+}
+
+func TestDiffFindings(t *testing.T) {
+	f := func(code string, line int) Finding {
+		return Finding{Code: code, Range: Range{Start: Pos{Line: line}}}
+	}
+	added, removed := diffFindings(
+		[]Finding{f("GA101", 1), f("GA101", 1), f("GA202", 5)},
+		[]Finding{f("GA101", 1), f("GA303", 9)},
+	)
+	if fmt.Sprint(added) != fmt.Sprint([]Finding{f("GA303", 9)}) {
+		t.Errorf("added %v", added)
+	}
+	if fmt.Sprint(removed) != fmt.Sprint([]Finding{f("GA101", 1), f("GA202", 5)}) {
+		t.Errorf("removed %v", removed)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// appendRaceAnalyzer flags slice fields that are reassigned with
+// `x.f = append(x.f, ...)` on one goroutine and read or ranged over on
+// another without a common lock. The append replaces the slice header, so
+// the reader can observe a torn header even when the elements themselves
+// are never shared.
+var appendRaceAnalyzer = &analyzer{
+	name:     "appendrace",
+	code:     "GA107",
+	summary:  "Slice field appended to on one goroutine and read on another",
+	severity: "error",
+	report:   "race",
+	run:      runAppendRace,
+}
+
+type sliceFieldAccess struct {
+	sel   *ast.SelectorExpr
+	field *types.Var
+	ctx   ast.Node
+	locks map[types.Object]bool
+	// ranged is set for reads that are the operand of a range clause.
+	ranged bool
+}
+
+func runAppendRace(p *pass) []Finding {
+	launched := passes.LaunchedFuncs(p.file, p.info)
+	access := func(sel *ast.SelectorExpr, field *types.Var) *sliceFieldAccess {
+		return &sliceFieldAccess{
+			sel:   sel,
+			field: field,
+			ctx:   passes.GoroutineContext(sel, p.parents, p.info, launched),
+			locks: passes.HeldLocks(sel, p.parents, p.info),
+		}
+	}
+
+	var appends []*sliceFieldAccess
+	consumed := make(map[*ast.SelectorExpr]bool)
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		as, ok := n.(*ast.AssignStmt)
+		if !ok || len(as.Lhs) != len(as.Rhs) {
+			return true
+		}
+		for i, lhs := range as.Lhs {
+			sel, field := p.sliceField(lhs)
+			if field == nil {
+				continue
+			}
+			arg := p.appendBase(as.Rhs[i])
+			argSel, argField := p.sliceField(arg)
+			if argField != field || types.ExprString(argSel.X) != types.ExprString(sel.X) {
+				continue
+			}
+			consumed[sel] = true
+			consumed[argSel] = true
+			appends = append(appends, access(sel, field))
+		}
+		return true
+	})
+	if len(appends) == 0 {
+		return nil
+	}
+
+	reads := make(map[*types.Var][]*sliceFieldAccess)
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		sel, field := p.sliceField(expr)
+		if field == nil || consumed[sel] || p.IsAssignTarget(sel) {
+			return true
+		}
+		a := access(sel, field)
+		if rs, ok := p.parents[sel].(*ast.RangeStmt); ok && rs.X == sel {
+			a.ranged = true
+		}
+		reads[field] = append(reads[field], a)
+		return true
+	})
+
+	var findings []Finding
+	for _, app := range appends {
+		var related []RelatedRange
+		for _, r := range reads[app.field] {
+			if r.ctx == app.ctx || passes.SharesLock(r.locks, app.locks) {
+				continue
+			}
+			msg := "unsynchronized read of " + app.field.Name()
+			if r.ranged {
+				msg = "unsynchronized range over " + app.field.Name()
+			}
+			related = append(related, RelatedRange{Range: p.rangeForNode(r.sel), Message: msg})
+		}
+		if len(related) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message: "slice field " + app.field.Name() + " is append-reassigned here while another goroutine reads it without synchronization",
+			Range:   p.rangeForNode(app.sel),
+			Related: related,
+		})
+	}
+	return findings
+}
+
+// sliceField returns expr as a selector of a slice-typed struct field.
+func (p *pass) sliceField(expr ast.Expr) (*ast.SelectorExpr, *types.Var) {
+	sel, field := p.StructField(expr)
+	if field == nil {
+		return nil, nil
+	}
+	if _, ok := field.Type().Underlying().(*types.Slice); !ok {
+		return nil, nil
+	}
+	return sel, field
+}
+
+// appendBase returns the first argument of a call to the append builtin.
+func (p *pass) appendBase(expr ast.Expr) ast.Expr {
+	call, ok := passes.Unparen(expr).(*ast.CallExpr)
+	if !ok || len(call.Args) == 0 {
+		return nil
+	}
+	id, ok := passes.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	if b, ok := p.info.Uses[id].(*types.Builtin); !ok || b.Name() != "append" {
+		return nil
+	}
+	return call.Args[0]
+}
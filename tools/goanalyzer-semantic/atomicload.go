@@ -0,0 +1,121 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// atomicLoadAnalyzer flags plain reads of struct fields whose every write in
+// the file goes through sync/atomic. Fields that are also written plainly
+// are left to the mixed-atomic check.
+var atomicLoadAnalyzer = &analyzer{
+	name:     "atomicload",
+	code:     "GA108",
+	summary:  "Plain read of a field written only through sync/atomic",
+	severity: "warning",
+	report:   "race",
+	run:      runAtomicLoad,
+}
+
+type atomicFieldState struct {
+	// suffix is the type part of the atomic function names used on the
+	// field, e.g. "Int64" for atomic.AddInt64.
+	suffix       string
+	atomicWrites []*ast.SelectorExpr
+	plainWrite   bool
+	plainReads   []*ast.SelectorExpr
+}
+
+func runAtomicLoad(p *pass) []Finding {
+	fields := make(map[*types.Var]*atomicFieldState)
+	state := func(field *types.Var) *atomicFieldState {
+		st := fields[field]
+		if st == nil {
+			st = &atomicFieldState{}
+			fields[field] = st
+		}
+		return st
+	}
+
+	atomicArgs := make(map[*ast.SelectorExpr]bool)
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		op, suffix := passes.AtomicOp(call, p.info)
+		if op == "" {
+			return true
+		}
+		addr, ok := passes.Unparen(call.Args[0]).(*ast.UnaryExpr)
+		if !ok || addr.Op != token.AND {
+			return true
+		}
+		sel, field := p.StructField(addr.X)
+		if field == nil {
+			return true
+		}
+		atomicArgs[sel] = true
+		if op != "Load" {
+			st := state(field)
+			st.suffix = suffix
+			st.atomicWrites = append(st.atomicWrites, sel)
+		}
+		return true
+	})
+
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		sel, field := p.StructField(expr)
+		if field == nil || atomicArgs[sel] {
+			return true
+		}
+		st := state(field)
+		switch parent := p.parents[sel].(type) {
+		case *ast.AssignStmt:
+			if p.IsAssignTarget(sel) {
+				st.plainWrite = true
+				return true
+			}
+		case *ast.IncDecStmt:
+			st.plainWrite = true
+			return true
+		case *ast.UnaryExpr:
+			// &s.f handed to anything but sync/atomic may be written through.
+			if parent.Op == token.AND {
+				st.plainWrite = true
+				return true
+			}
+		}
+		st.plainReads = append(st.plainReads, sel)
+		return true
+	})
+
+	var findings []Finding
+	for field, st := range fields {
+		if len(st.atomicWrites) == 0 || st.plainWrite {
+			continue
+		}
+		related := make([]RelatedRange, 0, len(st.atomicWrites))
+		for _, w := range st.atomicWrites {
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(w),
+				Message: "atomic write of " + field.Name(),
+			})
+		}
+		for _, r := range st.plainReads {
+			findings = append(findings, Finding{
+				Message: "field " + field.Name() + " is only written atomically; read it with atomic.Load" + st.suffix,
+				Range:   p.rangeForNode(r),
+				Related: related,
+			})
+		}
+	}
+	return findings
+}
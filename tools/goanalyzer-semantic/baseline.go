@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BaselineEntry is an accepted finding recorded by --baseline write. File
+// is slash-separated and relative to the baseline file. Fingerprint hashes
+// the code with the trimmed text of the line the finding starts on rather
+// than its line number, so the entry still matches after edits elsewhere
+// in the file.
+type BaselineEntry struct {
+	Code        string `json:"code"`
+	File        string `json:"file"`
+	Fingerprint string `json:"fingerprint"`
+	Message     string `json:"message,omitempty"`
+}
+
+// baselineVersion is bumped whenever fingerprints change meaning.
+const baselineVersion = 1
+
+type baselineFile struct {
+	Version int             `json:"version"`
+	Entries []BaselineEntry `json:"entries"`
+}
+
+// baselineEntries returns an entry for each finding of out, relative to
+// the directory of the baseline file at path.
+func baselineEntries(path string, out *AnalyzeOutput, in Input) []BaselineEntry {
+	dir := filepath.Dir(path)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	target := in.File
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	src := &columnMapper{target: filepath.Clean(target), content: in.Content, lines: make(map[string][]string)}
+	entries := make([]BaselineEntry, 0, len(out.Findings))
+	for _, f := range out.Findings {
+		file := f.Range.File
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			file = rel
+		}
+		line := strings.TrimSpace(src.line(f.Range.File, f.Range.Start.Line))
+		sum := sha256.Sum256([]byte(f.Code + "\x00" + line))
+		entries = append(entries, BaselineEntry{
+			Code:        f.Code,
+			File:        filepath.ToSlash(file),
+			Fingerprint: hex.EncodeToString(sum[:8]),
+			Message:     f.Message,
+		})
+	}
+	return entries
+}
+
+// writeBaseline records every finding of out in a baseline file at path.
+func writeBaseline(path string, out *AnalyzeOutput, in Input) error {
+	data, err := json.MarshalIndent(baselineFile{Version: baselineVersion, Entries: baselineEntries(path, out, in)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// applyBaseline drops the findings of out recorded in the baseline file at
+// path, counting them in out.Baselined. Entries left unmatched for a file
+// and code that were analyzed are listed in out.StaleBaseline, since the
+// finding they accepted is gone and they can be removed.
+func applyBaseline(path string, out *AnalyzeOutput, in Input) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var base baselineFile
+	if err := json.Unmarshal(data, &base); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if base.Version != baselineVersion {
+		return fmt.Errorf("%s: baseline version %d, want %d", path, base.Version, baselineVersion)
+	}
+	key := func(e BaselineEntry) string { return e.Code + " " + e.File + " " + e.Fingerprint }
+	remaining := make(map[string]int)
+	for _, e := range base.Entries {
+		remaining[key(e)]++
+	}
+	kept := out.Findings[:0]
+	for i, e := range baselineEntries(path, out, in) {
+		if remaining[key(e)] > 0 {
+			remaining[key(e)]--
+			out.Baselined++
+			continue
+		}
+		kept = append(kept, out.Findings[i])
+	}
+	out.Findings = kept
+
+	dir := filepath.Dir(path)
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for _, e := range base.Entries {
+		if remaining[key(e)] == 0 {
+			continue
+		}
+		remaining[key(e)]--
+		file := filepath.Join(dir, filepath.FromSlash(e.File))
+		if out.analyzedFiles[file] && out.analyzedCodes[e.Code] {
+			out.StaleBaseline = append(out.StaleBaseline, e)
+		}
+	}
+	return nil
+}
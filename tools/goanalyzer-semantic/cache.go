@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// packageCache keeps loaded packages across the requests handled by one
+// process, keyed by package directory. Each entry records a fingerprint of
+// every overlay in the directory and of every Go file on disk there; a
+// lookup whose fingerprint differs evicts the entry, so an edited overlay or
+// member file is always type-checked again. Changes to imported packages
+// are not tracked.
+var packageCache = &loadCache{entries: make(map[string]cacheEntry)}
+
+type loadCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	fingerprint string
+	lp          *loadedPackage
+}
+
+// load returns the cached package of targetFile's directory when its
+// fingerprint still matches and it can serve targetFile, and otherwise
+// loads it with fresh and replaces the entry. overlays holds the unsaved
+// text of files in that directory, keyed by path.
+func (c *loadCache) load(targetFile string, overlays map[string]string, fresh func() *loadedPackage) *loadedPackage {
+	dir := filepath.Dir(targetFile)
+	fingerprint := packageFingerprint(dir, overlays)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[dir]; ok {
+		if e.fingerprint == fingerprint {
+			if lp := e.lp.forTarget(targetFile); lp != nil {
+				return lp
+			}
+		} else {
+			delete(c.entries, dir)
+		}
+	}
+	lp := fresh()
+	if lp != nil && fingerprint != "" {
+		c.entries[dir] = cacheEntry{fingerprint: fingerprint, lp: lp}
+	}
+	return lp
+}
+
+// forTarget returns lp with targetFile as its target, or nil when lp does
+// not hold targetFile, e.g. because it belongs to the _test package of the
+// directory, or was loaded for a file whose build constraints decided
+// which files were kept.
+func (lp *loadedPackage) forTarget(targetFile string) *loadedPackage {
+	if lp.fset.Position(lp.file.Pos()).Filename == targetFile {
+		return lp
+	}
+	if lp.targetSpecific {
+		return nil
+	}
+	for _, f := range lp.files {
+		if lp.fset.Position(f.Pos()).Filename == targetFile {
+			shared := *lp
+			shared.file = f
+			return &shared
+		}
+	}
+	return nil
+}
+
+// reset drops every entry, e.g. after the importer changed.
+func (c *loadCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+}
+
+// packageFingerprint hashes the overlays of the files in dir, in path
+// order, together with the name, size and modification time of each Go file
+// on disk there. It returns "" when the directory cannot be read, which
+// disables caching.
+func packageFingerprint(dir string, overlays map[string]string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	paths := make([]string, 0, len(overlays))
+	for path := range overlays {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Fprintf(h, "overlay %s %d\n%s\n", path, len(overlays[path]), overlays[path])
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return ""
+		}
+		fmt.Fprintf(h, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// CallersOutput is the response of "callers" mode.
+type CallersOutput struct {
+	Name    string     `json:"name"`
+	Decl    Range      `json:"decl"`
+	Callers []CallSite `json:"callers"`
+	// ResultAlwaysIgnored is set when the function returns something besides
+	// errors and every call site discards it.
+	ResultAlwaysIgnored bool `json:"result_always_ignored,omitempty"`
+}
+
+// CallSite is one call of the function within the package.
+type CallSite struct {
+	// Range covers the whole call expression.
+	Range Range `json:"range"`
+	// Caller is the enclosing function, "T.m" for methods, or "" for
+	// package-level initializers.
+	Caller string `json:"caller"`
+	// Go and Defer mark calls made by a go or defer statement, directly or
+	// from the function literal it runs.
+	Go    bool `json:"go,omitempty"`
+	Defer bool `json:"defer,omitempty"`
+	// ViaValue marks calls through a variable holding the function or a
+	// method value.
+	ViaValue bool `json:"via_value,omitempty"`
+	// ViaInterface marks calls of an interface method that dispatch to the
+	// function.
+	ViaInterface bool `json:"via_interface,omitempty"`
+	// ResultIgnored marks calls that discard every non-error result of a
+	// function that has one.
+	ResultIgnored bool `json:"result_ignored,omitempty"`
+}
+
+func (o *CallersOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Callers {
+		f(&o.Callers[i].Range)
+	}
+}
+
+func callers(in Input) *CallersOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	fn := funcAtPosition(lp, in.Line, in.Col)
+	if fn == nil {
+		return nil
+	}
+	decl, ok := rangeForObject(lp.fset, fn)
+	if ident := findDeclIdent(lp.info, fn); ident != nil {
+		decl, ok = rangeForIdent(lp.fset, ident), true
+	}
+	if !ok {
+		return nil
+	}
+	parents := buildPackageParentMap(lp.files)
+	values := funcValues(lp.files, lp.info, parents, fn)
+	out := &CallersOutput{Name: fn.Name(), Decl: decl, Callers: make([]CallSite, 0)}
+	hasResult := hasNonErrorResult(fn)
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			site := CallSite{}
+			switch callee := passes.CalledFunc(call, lp.info); {
+			case callee == fn:
+				site.ViaInterface = isInterfaceMethod(fn)
+			case callee != nil && dispatchesTo(callee, fn):
+				site.ViaInterface = true
+			default:
+				id, ok := passes.Unparen(call.Fun).(*ast.Ident)
+				if !ok || !values[lp.info.Uses[id]] {
+					return true
+				}
+				site.ViaValue = true
+			}
+			site.Range = rangeForPos(lp.fset, call.Pos(), call.End())
+			site.Caller = callerName(call, parents)
+			site.Go, site.Defer = launchedBy(call, parents)
+			site.ResultIgnored = hasResult && resultIgnored(call, lp.info, parents)
+			out.Callers = append(out.Callers, site)
+			return true
+		})
+	}
+	sort.Slice(out.Callers, func(i, j int) bool {
+		return rangeLess(out.Callers[i].Range, out.Callers[j].Range)
+	})
+	out.ResultAlwaysIgnored = hasResult && len(out.Callers) > 0
+	for _, site := range out.Callers {
+		out.ResultAlwaysIgnored = out.ResultAlwaysIgnored && site.ResultIgnored
+	}
+	return out
+}
+
+// funcAtPosition returns the function or method named at line/col, or the
+// one whose declaration encloses the position.
+func funcAtPosition(lp *loadedPackage, line, col int) *types.Func {
+	if ident, selMap := findIdentAtPosition(lp.fset, lp.file, line, col); ident != nil {
+		if fn, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Func); ok {
+			return fn
+		}
+	}
+	pos, ok := filePos(lp.fset, lp.file, line, col)
+	if !ok {
+		return nil
+	}
+	for _, decl := range lp.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if ok && fd.Pos() <= pos && pos < fd.End() {
+			fn, _ := lp.info.Defs[fd.Name].(*types.Func)
+			return fn
+		}
+	}
+	return nil
+}
+
+// funcValues returns the variables that statically hold fn: declared from
+// fn or a method value of it and never reassigned.
+func funcValues(files []*ast.File, info *types.Info, parents map[ast.Node]ast.Node, fn *types.Func) map[types.Object]bool {
+	values := make(map[types.Object]bool)
+	for v, bound := range boundFuncs(files, info, parents) {
+		if bound == fn {
+			values[v] = true
+		}
+	}
+	return values
+}
+
+// boundFuncs maps the variables that statically hold a function, declared
+// from a function name, method value or method expression and never
+// reassigned, to that function.
+func boundFuncs(files []*ast.File, info *types.Info, parents map[ast.Node]ast.Node) map[types.Object]*types.Func {
+	values := make(map[types.Object]*types.Func)
+	bind := func(names []*ast.Ident, rhs []ast.Expr) {
+		if len(names) != len(rhs) {
+			return
+		}
+		for i, name := range names {
+			if v := info.Defs[name]; v != nil {
+				if fn := funcOf(rhs[i], info); fn != nil {
+					values[v] = fn
+				}
+			}
+		}
+	}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if node.Tok != token.DEFINE {
+					return true
+				}
+				names := make([]*ast.Ident, 0, len(node.Lhs))
+				for _, lhs := range node.Lhs {
+					id, _ := lhs.(*ast.Ident)
+					names = append(names, id)
+				}
+				bind(names, node.Rhs)
+			case *ast.ValueSpec:
+				bind(node.Names, node.Values)
+			}
+			return true
+		})
+	}
+	for id, obj := range info.Uses {
+		if values[obj] != nil && isReassign(id, info, parents) {
+			delete(values, obj)
+		}
+	}
+	return values
+}
+
+// funcOf returns the function expr denotes without calling it: a function
+// name, a method value x.m or a method expression T.m.
+func funcOf(expr ast.Expr, info *types.Info) *types.Func {
+	switch e := passes.Unparen(expr).(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[e].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		if sel := info.Selections[e]; sel != nil {
+			fn, _ := sel.Obj().(*types.Func)
+			return fn
+		}
+		fn, _ := info.Uses[e.Sel].(*types.Func)
+		return fn
+	}
+	return nil
+}
+
+func isInterfaceMethod(fn *types.Func) bool {
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() != nil && types.IsInterface(sig.Recv().Type())
+}
+
+// dispatchesTo reports whether calling the interface method callee can run
+// the concrete method fn: fn's receiver type implements callee's interface.
+func dispatchesTo(callee, fn *types.Func) bool {
+	if callee.Name() != fn.Name() || !isInterfaceMethod(callee) || isInterfaceMethod(fn) {
+		return false
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return false
+	}
+	iface, ok := callee.Type().(*types.Signature).Recv().Type().Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+	recv := sig.Recv().Type()
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	return types.Implements(recv, iface) || types.Implements(types.NewPointer(recv), iface)
+}
+
+// callerName names the function declaration enclosing node.
+func callerName(node ast.Node, parents map[ast.Node]ast.Node) string {
+	for n := parents[node]; n != nil; n = parents[n] {
+		fd, ok := n.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if fd.Recv == nil || len(fd.Recv.List) == 0 {
+			return fd.Name.Name
+		}
+		return receiverTypeName(fd.Recv.List[0].Type) + "." + fd.Name.Name
+	}
+	return ""
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(e.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(e.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(e.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// launchedBy reports whether call is made by a go or defer statement,
+// either as the statement's own call or from inside the function literal
+// the statement runs.
+func launchedBy(call *ast.CallExpr, parents map[ast.Node]ast.Node) (goStmt, deferStmt bool) {
+	var node ast.Node = call
+	for {
+		switch p := parents[node].(type) {
+		case *ast.GoStmt:
+			return p.Call == node, false
+		case *ast.DeferStmt:
+			return false, p.Call == node
+		}
+		lit := enclosingFuncLit(node, parents)
+		if lit == nil {
+			return false, false
+		}
+		outer, ok := parents[lit].(*ast.CallExpr)
+		if !ok || outer.Fun != lit {
+			return false, false
+		}
+		node = outer
+	}
+}
+
+func enclosingFuncLit(node ast.Node, parents map[ast.Node]ast.Node) *ast.FuncLit {
+	for n := parents[node]; n != nil; n = parents[n] {
+		switch f := n.(type) {
+		case *ast.FuncLit:
+			return f
+		case *ast.FuncDecl:
+			return nil
+		}
+	}
+	return nil
+}
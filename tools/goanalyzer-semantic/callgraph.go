@@ -0,0 +1,145 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// CallGraphOutput is the response of "callgraph" mode: the static call
+// graph among the functions and methods declared in the target's package,
+// as adjacency lists.
+type CallGraphOutput struct {
+	Nodes []CallGraphNode `json:"nodes"`
+}
+
+// CallGraphNode is a function declaration, named "T.m" for methods, with
+// the calls it makes to functions of the package. Calls made by function
+// literals are attributed to the declaration defining them.
+type CallGraphNode struct {
+	Name  string          `json:"name"`
+	Range Range           `json:"range"`
+	Calls []CallGraphEdge `json:"calls"`
+}
+
+// CallGraphEdge is one call site. Go and Defer mark calls made by a go or
+// defer statement, directly or from the function literal it runs, and
+// ViaValue calls through a variable holding the function. Approx marks the
+// edges of an interface method call, one to each method of the package
+// that may implement it.
+type CallGraphEdge struct {
+	Callee   string `json:"callee"`
+	Range    Range  `json:"range"`
+	Go       bool   `json:"go,omitempty"`
+	Defer    bool   `json:"defer,omitempty"`
+	ViaValue bool   `json:"via_value,omitempty"`
+	Approx   bool   `json:"approx,omitempty"`
+}
+
+func (o *CallGraphOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Nodes {
+		f(&o.Nodes[i].Range)
+		for j := range o.Nodes[i].Calls {
+			f(&o.Nodes[i].Calls[j].Range)
+		}
+	}
+}
+
+func callGraph(in Input) *CallGraphOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	files := make([]*ast.File, len(lp.files))
+	copy(files, lp.files)
+	sort.Slice(files, func(i, j int) bool {
+		return lp.fset.Position(files[i].Pos()).Filename < lp.fset.Position(files[j].Pos()).Filename
+	})
+	parents := buildPackageParentMap(files)
+	values := boundFuncs(files, lp.info, parents)
+
+	out := &CallGraphOutput{Nodes: make([]CallGraphNode, 0)}
+	nodes := make(map[*ast.FuncDecl]int)
+	var methods []*types.Func
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			fn, ok := lp.info.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			if fd.Recv != nil {
+				methods = append(methods, fn)
+			}
+			nodes[fd] = len(out.Nodes)
+			out.Nodes = append(out.Nodes, CallGraphNode{
+				Name:  funcDisplayName(fn),
+				Range: rangeForIdent(lp.fset, fd.Name),
+				Calls: make([]CallGraphEdge, 0),
+			})
+		}
+	}
+
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			fd := enclosingFuncDecl(call, parents)
+			if fd == nil {
+				return true
+			}
+			i, ok := nodes[fd]
+			if !ok {
+				return true
+			}
+			edge := CallGraphEdge{Range: rangeForPos(lp.fset, call.Pos(), call.End())}
+			edge.Go, edge.Defer = launchedBy(call, parents)
+			add := func(callee *types.Func) {
+				if callee.Pkg() == lp.pkg {
+					e := edge
+					e.Callee = funcDisplayName(callee)
+					out.Nodes[i].Calls = append(out.Nodes[i].Calls, e)
+				}
+			}
+			callee := passes.CalledFunc(call, lp.info)
+			if callee == nil {
+				if id, ok := passes.Unparen(call.Fun).(*ast.Ident); ok && values[lp.info.Uses[id]] != nil {
+					edge.ViaValue = true
+					add(values[lp.info.Uses[id]].Origin())
+				}
+				return true
+			}
+			callee = callee.Origin()
+			if !isInterfaceMethod(callee) {
+				add(callee)
+				return true
+			}
+			edge.Approx = true
+			for _, m := range methods {
+				if dispatchesTo(callee, m) {
+					add(m)
+				}
+			}
+			return true
+		})
+	}
+	return out
+}
+
+// funcDisplayName names fn as callerName does: "T.m" for methods.
+func funcDisplayName(fn *types.Func) string {
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		return receiverName(recv.Type()) + "." + fn.Name()
+	}
+	return fn.Name()
+}
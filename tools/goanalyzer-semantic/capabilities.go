@@ -0,0 +1,63 @@
+package main
+
+// CapabilitiesOutput is the response of "capabilities" mode and of the
+// --capabilities flag. It is built from the same tables main and the
+// analyzers use, so a client can hide what this binary cannot do.
+type CapabilitiesOutput struct {
+	ProtocolVersion int `json:"protocol_version"`
+	// Modes lists the accepted values of Input.Mode besides "", the
+	// default resolve query.
+	Modes []string `json:"modes"`
+	// Findings catalogs every code an analyzer can report.
+	Findings []FindingCode `json:"findings"`
+	// PositionEncodings lists the accepted values of Input.ColumnMode.
+	PositionEncodings []string `json:"position_encodings"`
+	// MaxRequestBytes is the largest request accepted, or 0 when requests
+	// are not limited.
+	MaxRequestBytes int64 `json:"max_request_bytes"`
+	// Features names the optional parts of the protocol this binary
+	// supports: "lsp" for --lsp, "package_cache" for loaded packages kept
+	// across the requests of one process, "config" for goanalyzer.json,
+	// "plugins", "sarif", "stream", "offset" positions and --watch.
+	Features []string `json:"features"`
+}
+
+// FindingCode describes the findings of one analyzer.
+type FindingCode struct {
+	Code     string `json:"code"`
+	Analyzer string `json:"analyzer"`
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	// Report names the report mode including the code, e.g. "race" for
+	// "race_report"; codes without one are only run by "analyze".
+	Report string `json:"report,omitempty"`
+}
+
+func (o *CapabilitiesOutput) mapRanges(f func(*Range)) {}
+
+var features = []string{"lsp", "package_cache", "config", "plugins", "sarif", "stream", "offset", "watch"}
+
+func capabilities() *CapabilitiesOutput {
+	out := &CapabilitiesOutput{
+		ProtocolVersion:   protocolVersion,
+		Modes:             make([]string, 0, len(protocolModes)),
+		Findings:          make([]FindingCode, 0, len(analyzers)),
+		PositionEncodings: schemaEnums["Input.column_mode"],
+		Features:          features,
+	}
+	for _, m := range protocolModes {
+		if m.name != "" {
+			out.Modes = append(out.Modes, m.name)
+		}
+	}
+	for _, a := range analyzers {
+		out.Findings = append(out.Findings, FindingCode{
+			Code:     a.code,
+			Analyzer: a.name,
+			Title:    a.summary,
+			Severity: a.severity,
+			Report:   a.report,
+		})
+	}
+	return out
+}
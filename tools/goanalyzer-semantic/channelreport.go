@@ -0,0 +1,230 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// ChannelReportOutput is the response of "channel_report" mode: every
+// operation on the channel variable or field at Line/Col in the package.
+type ChannelReportOutput struct {
+	Name     string `json:"name"`
+	Decl     Range  `json:"decl"`
+	ElemType string `json:"elem_type"`
+	// Buffer is the capacity given to make, 0 for an unbuffered channel,
+	// or -1 when it is not a constant or differs between make calls.
+	Buffer int64       `json:"buffer"`
+	Ops    []ChannelOp `json:"ops"`
+	Closed bool        `json:"closed"`
+	// SendAfterClose lists sends that follow a close of the channel in the
+	// same function and panic when that path is taken.
+	SendAfterClose []Range `json:"send_after_close,omitempty"`
+}
+
+// ChannelOp is one use of the channel. Kind is "send", "receive", "close"
+// or "range".
+type ChannelOp struct {
+	Kind     string `json:"kind"`
+	Range    Range  `json:"range"`
+	Function string `json:"function"`
+	// Goroutine is set when the operation runs on a goroutine started by a
+	// go statement in the package.
+	Goroutine bool `json:"goroutine,omitempty"`
+	// Select marks a select case; NonBlocking one of a select with a
+	// default clause, which never waits for the channel.
+	Select      bool `json:"select,omitempty"`
+	NonBlocking bool `json:"non_blocking,omitempty"`
+}
+
+func (o *ChannelReportOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Ops {
+		f(&o.Ops[i].Range)
+	}
+	for i := range o.SendAfterClose {
+		f(&o.SendAfterClose[i])
+	}
+}
+
+func channelReport(in Input) *ChannelReportOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	ch, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Var)
+	if !ok {
+		return nil
+	}
+	chanType, ok := ch.Type().Underlying().(*types.Chan)
+	if !ok {
+		return nil
+	}
+	out := &ChannelReportOutput{
+		Name:     ch.Name(),
+		ElemType: types.TypeString(chanType.Elem(), types.RelativeTo(lp.pkg)),
+		Buffer:   -1,
+		Ops:      make([]ChannelOp, 0),
+	}
+	out.Decl, _ = lp.objectRange(ch)
+
+	closes := make(map[*ast.BlockStmt][]token.Pos)
+	type sendSite struct {
+		node *ast.SendStmt
+		body *ast.BlockStmt
+	}
+	var sends []sendSite
+	made := false
+	for _, f := range lp.files {
+		parents := passes.ParentMap(f)
+		launched := passes.LaunchedFuncs(f, lp.info)
+		add := func(kind string, node ast.Node) {
+			op := ChannelOp{
+				Kind:      kind,
+				Range:     rangeForPos(lp.fset, node.Pos(), node.End()),
+				Function:  callerName(node, parents),
+				Goroutine: passes.GoroutineContext(node, parents, lp.info, launched) != nil,
+			}
+			op.Select, op.NonBlocking = selectCase(node, parents)
+			out.Ops = append(out.Ops, op)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.SendStmt:
+				if passes.ExprObject(node.Chan, lp.info) == ch {
+					add("send", node)
+					sends = append(sends, sendSite{node, enclosingFuncBody(node, parents)})
+				}
+			case *ast.UnaryExpr:
+				if node.Op == token.ARROW && passes.ExprObject(node.X, lp.info) == ch {
+					add("receive", node)
+				}
+			case *ast.RangeStmt:
+				if passes.ExprObject(node.X, lp.info) == ch {
+					add("range", node)
+				}
+			case *ast.CallExpr:
+				if isBuiltin(node, "close", lp.info) && len(node.Args) == 1 && passes.ExprObject(node.Args[0], lp.info) == ch {
+					add("close", node)
+					out.Closed = true
+					body := enclosingFuncBody(node, parents)
+					closes[body] = append(closes[body], node.Pos())
+				}
+				if size, ok := makeChanSize(node, lp.info); ok && assignedTo(node, ch, lp.info, parents) {
+					if !made {
+						out.Buffer = size
+					} else if out.Buffer != size {
+						out.Buffer = -1
+					}
+					made = true
+				}
+			}
+			return true
+		})
+	}
+	for _, s := range sends {
+		for _, pos := range closes[s.body] {
+			if s.body != nil && pos < s.node.Pos() {
+				out.SendAfterClose = append(out.SendAfterClose, rangeForPos(lp.fset, s.node.Pos(), s.node.End()))
+				break
+			}
+		}
+	}
+	sort.SliceStable(out.Ops, func(i, j int) bool {
+		return rangeLess(out.Ops[i].Range, out.Ops[j].Range)
+	})
+	return out
+}
+
+// selectCase reports whether the channel operation node is the
+// communication of a select case, and whether that select has a default
+// clause.
+func selectCase(node ast.Node, parents map[ast.Node]ast.Node) (bool, bool) {
+	var cur ast.Node = node
+	for {
+		switch p := parents[cur].(type) {
+		case *ast.ExprStmt, *ast.AssignStmt, *ast.ParenExpr:
+			cur = p
+			continue
+		case *ast.CommClause:
+			if p.Comm != cur {
+				return false, false
+			}
+			body, _ := parents[p].(*ast.BlockStmt)
+			for _, stmt := range body.List {
+				if cc, ok := stmt.(*ast.CommClause); ok && cc.Comm == nil {
+					return true, true
+				}
+			}
+			return true, false
+		}
+		return false, false
+	}
+}
+
+// makeChanSize returns the buffer size of a make(chan T, n) call: n when it
+// is constant, 0 without it and -1 otherwise.
+func makeChanSize(call *ast.CallExpr, info *types.Info) (int64, bool) {
+	if !isBuiltin(call, "make", info) || len(call.Args) == 0 {
+		return 0, false
+	}
+	if _, ok := info.TypeOf(call.Args[0]).Underlying().(*types.Chan); !ok {
+		return 0, false
+	}
+	if len(call.Args) < 2 {
+		return 0, true
+	}
+	if tv, ok := info.Types[call.Args[1]]; ok && tv.Value != nil {
+		if size, exact := constant.Int64Val(constant.ToInt(tv.Value)); exact {
+			return size, true
+		}
+	}
+	return -1, true
+}
+
+// assignedTo reports whether expr is the value stored into obj by an
+// assignment, a var declaration or a keyed composite literal element.
+func assignedTo(expr ast.Expr, obj types.Object, info *types.Info, parents map[ast.Node]ast.Node) bool {
+	switch p := parents[expr].(type) {
+	case *ast.AssignStmt:
+		for i, rhs := range p.Rhs {
+			if rhs == expr && i < len(p.Lhs) {
+				if id, ok := p.Lhs[i].(*ast.Ident); ok && info.Defs[id] == obj {
+					return true
+				}
+				return passes.ExprObject(p.Lhs[i], info) == obj
+			}
+		}
+	case *ast.ValueSpec:
+		for i, v := range p.Values {
+			if v == expr && i < len(p.Names) {
+				return info.Defs[p.Names[i]] == obj
+			}
+		}
+	case *ast.KeyValueExpr:
+		if id, ok := p.Key.(*ast.Ident); ok && p.Value == expr {
+			return info.Uses[id] == obj
+		}
+	}
+	return false
+}
+
+func isBuiltin(call *ast.CallExpr, name string, info *types.Info) bool {
+	id, ok := passes.Unparen(call.Fun).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	b, ok := info.Uses[id].(*types.Builtin)
+	return ok && b.Name() == name
+}
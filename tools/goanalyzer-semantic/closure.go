@@ -0,0 +1,138 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// ClosureReportOutput is the response of "closure_report" mode: the outer
+// variables captured by the innermost function literal at Line/Col.
+type ClosureReportOutput struct {
+	Range Range `json:"range"`
+	// Launch is how the literal is used: "go", "defer", "call" when it is
+	// invoked in place, "passed" when it is a call argument, or "stored".
+	Launch   string    `json:"launch"`
+	Captures []Capture `json:"captures"`
+}
+
+// Capture is an outer variable referenced by a function literal. Access is
+// "read", "write" or "read_write"; taking the variable's address counts as
+// a write. Size is -1 when unknown.
+type Capture struct {
+	Name   string `json:"name"`
+	Decl   Range  `json:"decl"`
+	Type   string `json:"type"`
+	Access string `json:"access"`
+	Size   int64  `json:"size"`
+}
+
+func (o *ClosureReportOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Range)
+	for i := range o.Captures {
+		f(&o.Captures[i].Decl)
+	}
+}
+
+// closureReport lists captures in source order of their first reference.
+// Package-level variables are shared by every function and are not
+// captures.
+func closureReport(in Input) *ClosureReportOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	pos, ok := filePos(lp.fset, lp.file, in.Line, in.Col)
+	if !ok {
+		return nil
+	}
+	var lit *ast.FuncLit
+	ast.Inspect(lp.file, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || pos >= n.End() {
+			return false
+		}
+		if fl, ok := n.(*ast.FuncLit); ok {
+			lit = fl
+		}
+		return true
+	})
+	if lit == nil {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	out := &ClosureReportOutput{
+		Range:    rangeForPos(lp.fset, lit.Pos(), lit.End()),
+		Launch:   closureLaunch(lit, parents),
+		Captures: make([]Capture, 0),
+	}
+
+	index := make(map[*types.Var]int)
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v, ok := lp.info.Uses[id].(*types.Var)
+		if !ok || v.IsField() || v.Parent() == lp.pkg.Scope() || (v.Pos() >= lit.Pos() && v.Pos() < lit.End()) {
+			return true
+		}
+		i, seen := index[v]
+		if !seen {
+			decl, _ := lp.objectRange(v)
+			i = len(out.Captures)
+			index[v] = i
+			out.Captures = append(out.Captures, Capture{
+				Name: v.Name(),
+				Decl: decl,
+				Type: types.TypeString(v.Type(), types.RelativeTo(lp.pkg)),
+				Size: sizeBytes(v.Type()),
+			})
+		}
+		access := "read"
+		switch parent := parents[id].(type) {
+		case *ast.IncDecStmt:
+			access = "read_write"
+		case *ast.AssignStmt:
+			if isReassign(id, lp.info, parents) {
+				access = "write"
+				if parent.Tok != token.ASSIGN {
+					access = "read_write"
+				}
+			}
+		case *ast.UnaryExpr:
+			if parent.Op == token.AND {
+				access = "write"
+			}
+		}
+		switch c := &out.Captures[i]; {
+		case c.Access == "":
+			c.Access = access
+		case c.Access != access:
+			c.Access = "read_write"
+		}
+		return true
+	})
+	return out
+}
+
+func closureLaunch(lit *ast.FuncLit, parents map[ast.Node]ast.Node) string {
+	call, ok := parents[lit].(*ast.CallExpr)
+	if !ok {
+		return "stored"
+	}
+	if call.Fun != lit {
+		return "passed"
+	}
+	switch parents[call].(type) {
+	case *ast.GoStmt:
+		return "go"
+	case *ast.DeferStmt:
+		return "defer"
+	}
+	return "call"
+}
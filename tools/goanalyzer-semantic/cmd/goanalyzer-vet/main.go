@@ -0,0 +1,22 @@
+// Command goanalyzer-vet runs the race and retention checks of
+// goanalyzer-semantic outside the editor, on packages named like go vet's
+// arguments. Each diagnostic ends with the finding code the JSON protocol
+// reports, e.g. "(GA101)", which is also its category in -json output:
+//
+//	goanalyzer-vet ./...
+//	goanalyzer-vet -largecopy.size=4096 ./...
+//
+// It can also be run by go vet itself:
+//
+//	go vet -vettool=$(which goanalyzer-vet) ./...
+package main
+
+import (
+	"goanalyzer-semantic/passes"
+
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(passes.Analyzers...)
+}
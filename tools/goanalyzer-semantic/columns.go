@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultTabSize = 4
+
+// columnMapper converts between go/token byte columns and the visual columns
+// reported by editors that expand tabs to tab stops. Lines are read lazily
+// per file; the target file uses the request's overlay content when present,
+// and the other files of its package their entries in Overlays.
+type columnMapper struct {
+	tabSize  int
+	target   string
+	content  string
+	overlays map[string]string
+	lines    map[string][]string
+}
+
+// newColumnMapper returns nil in the default "byte" column mode, where no
+// conversion is needed.
+func newColumnMapper(in Input) *columnMapper {
+	if in.ColumnMode != "visual" {
+		return nil
+	}
+	tabSize := in.TabSize
+	if tabSize <= 0 {
+		tabSize = defaultTabSize
+	}
+	target := in.File
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	target = filepath.Clean(target)
+	return &columnMapper{
+		tabSize:  tabSize,
+		target:   target,
+		content:  in.Content,
+		overlays: packageOverlays(in, target),
+		lines:    make(map[string][]string),
+	}
+}
+
+func (m *columnMapper) line(file string, line int) string {
+	file = filepath.Clean(file)
+	lines, ok := m.lines[file]
+	if !ok {
+		var src string
+		if text, ok := m.overlays[file]; ok {
+			src = text
+		} else if file == m.target && m.content != "" {
+			src = m.content
+		} else if data, err := os.ReadFile(file); err == nil {
+			src = string(data)
+		}
+		lines = strings.Split(src, "\n")
+		m.lines[file] = lines
+	}
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return lines[line]
+}
+
+// toByte converts a zero-based visual column on line to a byte column. A
+// column that falls inside an expanded tab maps to the tab itself.
+func (m *columnMapper) toByte(file string, line, col int) int {
+	text := m.line(file, line)
+	visual := 0
+	for i, r := range text {
+		next := visual + 1
+		if r == '\t' {
+			next = visual + m.tabSize - visual%m.tabSize
+		}
+		if col < next {
+			return i
+		}
+		visual = next
+	}
+	return len(text) + col - visual
+}
+
+// toVisual converts a zero-based byte column on line to a visual column.
+func (m *columnMapper) toVisual(file string, line, col int) int {
+	text := m.line(file, line)
+	visual := 0
+	for i := 0; i < col; {
+		if i >= len(text) {
+			return visual + col - i
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\t' {
+			visual += m.tabSize - visual%m.tabSize
+		} else {
+			visual++
+		}
+		i += size
+	}
+	return visual
+}
+
+func (m *columnMapper) mapRange(r *Range) {
+	file := r.File
+	if file == "" {
+		file = m.target
+	}
+	r.Start.Col = m.toVisual(file, r.Start.Line, r.Start.Col)
+	r.End.Col = m.toVisual(file, r.End.Line, r.End.Col)
+}
+
+// rangeMapper is implemented by every response type so that column
+// conversion can be applied uniformly after a query has run.
+type rangeMapper interface {
+	mapRanges(f func(*Range))
+}
+
+func (o *Output) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Uses {
+		f(&o.Uses[i].Range)
+	}
+	if o.ScopeRange != nil {
+		f(o.ScopeRange)
+	}
+	mapDiagnosticRanges(o.LoadDiagnostics, f)
+}
+
+func (o *AnalyzeOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Findings {
+		f(&o.Findings[i].Range)
+		for j := range o.Findings[i].Related {
+			f(&o.Findings[i].Related[j].Range)
+		}
+	}
+	mapDiagnosticRanges(o.LoadDiagnostics, f)
+}
+
+func mapDiagnosticRanges(diags []LoadDiagnostic, f func(*Range)) {
+	for i := range diags {
+		if diags[i].Range != nil {
+			f(diags[i].Range)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// defaultComplexityThreshold is the highest cyclomatic complexity a
+// function may have before the complexity analyzer reports it.
+const defaultComplexityThreshold = 10
+
+// complexityAnalyzer flags function declarations whose cyclomatic
+// complexity, as computed by cyclomaticComplexity and shown by "metrics"
+// mode, exceeds the configured threshold. Function literals count towards
+// the declaration they appear in.
+var complexityAnalyzer = &analyzer{
+	name:     "complexity",
+	code:     "GA315",
+	summary:  "Function exceeds the cyclomatic complexity threshold",
+	severity: "info",
+	run:      runComplexity,
+}
+
+func runComplexity(p *pass) []Finding {
+	threshold := p.config.ComplexityThreshold
+	if threshold <= 0 {
+		threshold = defaultComplexityThreshold
+	}
+	var findings []Finding
+	for _, decl := range p.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		if score := cyclomaticComplexity(fd.Body); score > threshold {
+			findings = append(findings, Finding{
+				Message: fmt.Sprintf("%s has cyclomatic complexity %d, above the threshold of %d", callerName(fd.Name, p.parents), score, threshold),
+				Range:   p.rangeForNode(fd),
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// inferGuard returns the mutex that is held at every access of field across
+// files, or nil when the field is never accessed or no single lock covers
+// all accesses. Among several candidates the one declared first wins.
+func inferGuard(files []*ast.File, info *types.Info, parents map[ast.Node]ast.Node, field *types.Var) types.Object {
+	var common map[types.Object]bool
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || (common != nil && len(common) == 0) {
+				return true
+			}
+			if s := info.Selections[sel]; s == nil || s.Obj() != field {
+				return true
+			}
+			held := passes.HeldLocks(sel, parents, info)
+			if common == nil {
+				common = held
+				return true
+			}
+			for lock := range common {
+				if !held[lock] {
+					delete(common, lock)
+				}
+			}
+			return true
+		})
+	}
+	var guard types.Object
+	for lock := range common {
+		if guard == nil || lock.Pos() < guard.Pos() {
+			guard = lock
+		}
+	}
+	return guard
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Config holds the analyzer settings a project keeps in a goanalyzer.json
+// (or .goanalyzer/config.json) at its module root. A request can carry a
+// Config of its own, whose set fields win over the file's.
+type Config struct {
+	// LargeValueSize replaces passes.DefaultLargeValueSize for the
+	// retention analyzers.
+	LargeValueSize int64 `json:"large_value_size,omitempty"`
+	// BlockingFuncs adds functions and methods to lockblock's
+	// blockingFuncs, keyed the same way, e.g. "example.com/db.Conn.Query".
+	BlockingFuncs []string `json:"blocking_funcs,omitempty"`
+	// EnabledCodes restricts the analyzers to those with the given finding
+	// codes. It is ignored when the request names its analyzers.
+	EnabledCodes []string `json:"enabled_codes,omitempty"`
+	// ComplexityThreshold replaces defaultComplexityThreshold for the
+	// complexity analyzer.
+	ComplexityThreshold int `json:"complexity_threshold,omitempty"`
+	// Rules overrides the severity of findings by code, e.g.
+	// {"GA101": "error"}, or turns a rule "off" so its analyzer is not run.
+	// Request rules are merged over the file's code by code.
+	Rules map[string]string `json:"rules,omitempty"`
+}
+
+// ruleSettings are the values accepted in Config.Rules.
+var ruleSettings = map[string]bool{"off": true, "error": true, "warning": true, "info": true, "hint": true}
+
+// ConfigWarning reports a config file that was found but could not be
+// used; the analysis then runs with the request's settings alone.
+type ConfigWarning struct {
+	File    string `json:"file"`
+	Message string `json:"message"`
+}
+
+// configFileNames are looked up in order in the module root.
+var configFileNames = []string{"goanalyzer.json", filepath.Join(".goanalyzer", "config.json")}
+
+type configEntry struct {
+	modTime time.Time
+	size    int64
+	config  *Config
+	err     error
+}
+
+// configCache keeps parsed config files for the life of the process, which
+// matters in --lsp mode. An entry is reparsed when the file's modification
+// time or size changes.
+var configCache = struct {
+	sync.Mutex
+	entries map[string]configEntry
+}{entries: make(map[string]configEntry)}
+
+// projectConfig returns the config file of the module containing target,
+// or a nil Config when there is none.
+func projectConfig(target string) (*Config, *ConfigWarning) {
+	root := findModuleRoot(filepath.Dir(target))
+	if root == "" {
+		return nil, nil
+	}
+	for _, name := range configFileNames {
+		path := filepath.Join(root, name)
+		fi, err := os.Stat(path)
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		configCache.Lock()
+		entry, ok := configCache.entries[path]
+		if !ok || !entry.modTime.Equal(fi.ModTime()) || entry.size != fi.Size() {
+			entry = configEntry{modTime: fi.ModTime(), size: fi.Size()}
+			entry.config, entry.err = parseConfig(path)
+			configCache.entries[path] = entry
+		}
+		configCache.Unlock()
+		if entry.err != nil {
+			return nil, &ConfigWarning{File: path, Message: entry.err.Error()}
+		}
+		return entry.config, nil
+	}
+	return nil, nil
+}
+
+func parseConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.LargeValueSize < 0 {
+		return nil, errors.New("large_value_size must not be negative")
+	}
+	if c.ComplexityThreshold < 0 {
+		return nil, errors.New("complexity_threshold must not be negative")
+	}
+	for code, setting := range c.Rules {
+		if !ruleSettings[setting] {
+			return nil, fmt.Errorf("rule %s: unknown setting %q", code, setting)
+		}
+	}
+	return &c, nil
+}
+
+// effectiveConfig merges the project's config file with the request's.
+func effectiveConfig(in Input) (Config, *ConfigWarning) {
+	var c Config
+	target := in.File
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	file, warning := projectConfig(target)
+	if file != nil {
+		c = *file
+	}
+	if r := in.Config; r != nil {
+		if r.LargeValueSize != 0 {
+			c.LargeValueSize = r.LargeValueSize
+		}
+		if r.BlockingFuncs != nil {
+			c.BlockingFuncs = r.BlockingFuncs
+		}
+		if r.EnabledCodes != nil {
+			c.EnabledCodes = r.EnabledCodes
+		}
+		if r.ComplexityThreshold != 0 {
+			c.ComplexityThreshold = r.ComplexityThreshold
+		}
+		if len(r.Rules) > 0 {
+			rules := make(map[string]string, len(c.Rules)+len(r.Rules))
+			for code, setting := range c.Rules {
+				rules[code] = setting
+			}
+			for code, setting := range r.Rules {
+				if ruleSettings[setting] {
+					rules[code] = setting
+				}
+			}
+			c.Rules = rules
+		}
+	}
+	return c, warning
+}
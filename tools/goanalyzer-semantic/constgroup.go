@@ -0,0 +1,141 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// ConstGroupOutput is the response of "const_group" mode: the const
+// declaration holding the constant at Line/Col. An ungrouped constant is a
+// group of one.
+type ConstGroupOutput struct {
+	Range Range `json:"range"`
+	// Type is the type shared by every named member, such as Status in
+	// `const ( A Status = iota; B )`, or empty when the members differ.
+	Type string `json:"type,omitempty"`
+	// Iota is the last value expression of the group using iota, as
+	// written, which the members after it without a value repeat.
+	Iota    string        `json:"iota,omitempty"`
+	Members []ConstMember `json:"members"`
+}
+
+// ConstMember is one constant of the group. Value is its exact value in Go
+// syntax. Used is set when the constant is referenced anywhere in the
+// package; blank members are never used.
+type ConstMember struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+	Decl  Range  `json:"decl"`
+	Used  bool   `json:"used"`
+}
+
+func (o *ConstGroupOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Range)
+	for i := range o.Members {
+		f(&o.Members[i].Decl)
+	}
+}
+
+func constGroup(in Input) *ConstGroupOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	c, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Const)
+	if !ok || c.Pkg() != lp.pkg {
+		return nil
+	}
+	gd := constDecl(lp.files, c)
+	if gd == nil {
+		return nil
+	}
+
+	used := make(map[types.Object]bool)
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if obj, ok := lp.info.Uses[id].(*types.Const); ok {
+					used[obj] = true
+				}
+			}
+			return true
+		})
+	}
+
+	out := &ConstGroupOutput{Range: rangeForPos(lp.fset, gd.Pos(), gd.End()), Members: make([]ConstMember, 0)}
+	qualifier := types.RelativeTo(lp.pkg)
+	typeNames := make(map[string]bool)
+	for _, spec := range gd.Specs {
+		vs := spec.(*ast.ValueSpec)
+		for _, v := range vs.Values {
+			if usesIota(v, lp.info) {
+				out.Iota = types.ExprString(v)
+			}
+		}
+		for _, name := range vs.Names {
+			obj, ok := lp.info.Defs[name].(*types.Const)
+			if !ok {
+				continue
+			}
+			m := ConstMember{
+				Name:  obj.Name(),
+				Value: obj.Val().ExactString(),
+				Type:  types.TypeString(obj.Type(), qualifier),
+				Decl:  rangeForIdent(lp.fset, name),
+				Used:  used[obj],
+			}
+			if name.Name != "_" {
+				typeNames[m.Type] = true
+			}
+			out.Members = append(out.Members, m)
+		}
+	}
+	if len(typeNames) == 1 {
+		for t := range typeNames {
+			out.Type = t
+		}
+	}
+	return out
+}
+
+// constDecl returns the const declaration, at package level or in a
+// function body, that declares c.
+func constDecl(files []*ast.File, c *types.Const) *ast.GenDecl {
+	var found *ast.GenDecl
+	for _, f := range files {
+		if c.Pos() < f.Pos() || c.Pos() >= f.End() {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if found != nil || n == nil || c.Pos() < n.Pos() || c.Pos() >= n.End() {
+				return false
+			}
+			if gd, ok := n.(*ast.GenDecl); ok && gd.Tok == token.CONST {
+				found = gd
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// usesIota reports whether expr refers to the predeclared iota.
+func usesIota(expr ast.Expr, info *types.Info) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" && info.Uses[id] == types.Universe.Lookup("iota") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
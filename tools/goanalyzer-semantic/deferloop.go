@@ -0,0 +1,35 @@
+package main
+
+import "go/ast"
+
+// deferLoopAnalyzer flags defer statements inside a for or range loop of
+// their function. Deferred calls only run when the function returns, so a
+// defer per iteration keeps every resource of the loop open until then.
+// Defers inside a function literal called in the loop body run per call and
+// are not reported.
+var deferLoopAnalyzer = &analyzer{
+	name:     "deferloop",
+	code:     "GA308",
+	summary:  "Defer inside a loop",
+	severity: "warning",
+	run:      runDeferLoop,
+}
+
+func runDeferLoop(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		ds, ok := n.(*ast.DeferStmt)
+		if !ok {
+			return true
+		}
+		if loop := enclosingLoop(ds, p.parents); loop != nil {
+			findings = append(findings, Finding{
+				Message: "defer inside a loop runs only when the function returns",
+				Range:   p.rangeForNode(ds),
+				Related: []RelatedRange{{Range: p.rangeForNode(loop), Message: "enclosing loop"}},
+			})
+		}
+		return true
+	})
+	return findings
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// DeferReportOutput is the response of "defer_report" mode.
+type DeferReportOutput struct {
+	Defers []DeferEntry `json:"defers"`
+}
+
+// DeferEntry describes one defer statement.
+type DeferEntry struct {
+	Range Range `json:"range"`
+	// Function is the declaration containing the defer, "T.m" for methods.
+	Function string `json:"function"`
+	// Target is the deferred function as written, or "func literal".
+	Target string `json:"target"`
+	// Eager lists the expressions evaluated when the defer statement runs:
+	// the receiver or operand of a deferred selector and the arguments.
+	Eager []string `json:"eager"`
+	// Lazy lists the outer variables, and fields reached through them, that
+	// a deferred function literal reads only when it finally runs.
+	Lazy []string `json:"lazy"`
+	// InLoop is set when the defer is inside a for or range statement of
+	// its function, so every iteration stacks one more call until return.
+	InLoop bool `json:"in_loop,omitempty"`
+	// Cleanup is "unlock" for Unlock/RUnlock, "close" for Close methods and
+	// the close builtin, "stop" for Stop methods, or empty.
+	Cleanup string `json:"cleanup,omitempty"`
+	// EarlyReturns are return statements of the function that precede the
+	// defer, on whose paths the deferred call is never registered.
+	EarlyReturns []Range `json:"early_returns,omitempty"`
+}
+
+func (o *DeferReportOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Defers {
+		d := &o.Defers[i]
+		f(&d.Range)
+		for j := range d.EarlyReturns {
+			f(&d.EarlyReturns[j])
+		}
+	}
+}
+
+// deferReport lists the defer statements of the target file, or with
+// DeferScope "function" of the function enclosing Line/Col.
+func deferReport(in Input) *DeferReportOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	var scope ast.Node = lp.file
+	if in.DeferScope == "function" {
+		fd := funcDeclAt(lp, in.Line, in.Col)
+		if fd == nil {
+			return nil
+		}
+		scope = fd
+	}
+
+	out := &DeferReportOutput{Defers: make([]DeferEntry, 0)}
+	ast.Inspect(scope, func(n ast.Node) bool {
+		ds, ok := n.(*ast.DeferStmt)
+		if !ok || ds.Call == nil {
+			return true
+		}
+		d := DeferEntry{
+			Range:    rangeForPos(lp.fset, ds.Pos(), ds.End()),
+			Function: callerName(ds, parents),
+			Eager:    make([]string, 0),
+			Lazy:     make([]string, 0),
+			InLoop:   enclosingLoop(ds, parents) != nil,
+		}
+		switch fun := passes.Unparen(ds.Call.Fun).(type) {
+		case *ast.FuncLit:
+			d.Target = "func literal"
+			for _, c := range literalCaptures(lp, fun) {
+				d.Lazy = append(d.Lazy, c.Name)
+			}
+		case *ast.SelectorExpr:
+			d.Target = types.ExprString(fun)
+			if lp.info.Selections[fun] != nil {
+				d.Eager = append(d.Eager, types.ExprString(fun.X))
+			}
+			switch fun.Sel.Name {
+			case "Unlock", "RUnlock":
+				d.Cleanup = "unlock"
+			case "Close":
+				d.Cleanup = "close"
+			case "Stop":
+				d.Cleanup = "stop"
+			}
+		default:
+			d.Target = types.ExprString(fun)
+			if b, ok := lp.info.Uses[passes.RootIdent(fun)].(*types.Builtin); ok && b.Name() == "close" {
+				d.Cleanup = "close"
+			}
+		}
+		for _, arg := range ds.Call.Args {
+			d.Eager = append(d.Eager, types.ExprString(arg))
+		}
+		if body := enclosingFuncBody(ds, parents); body != nil {
+			inspectFuncBody(body, func(n ast.Node) {
+				if ret, ok := n.(*ast.ReturnStmt); ok && ret.Pos() < ds.Pos() {
+					d.EarlyReturns = append(d.EarlyReturns, rangeForPos(lp.fset, ret.Pos(), ret.End()))
+				}
+			})
+		}
+		out.Defers = append(out.Defers, d)
+		return true
+	})
+	return out
+}
+
+// enclosingFuncBody returns the body of the function declaration or literal
+// that node belongs to.
+func enclosingFuncBody(node ast.Node, parents map[ast.Node]ast.Node) *ast.BlockStmt {
+	for n := parents[node]; n != nil; n = parents[n] {
+		switch fn := n.(type) {
+		case *ast.FuncLit:
+			return fn.Body
+		case *ast.FuncDecl:
+			return fn.Body
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// DefinitionOutput is the response of "definition" mode: where the symbol
+// at the position is declared, without collecting its uses.
+type DefinitionOutput struct {
+	Name string `json:"name"`
+	Decl Range  `json:"decl"`
+	// DeclKind is one of "var", "param", "result", "field", "const",
+	// "type", "func", "method" or "package".
+	DeclKind string `json:"decl_kind"`
+	Type     string `json:"type"`
+}
+
+func (o *DefinitionOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+}
+
+// definition resolves only the declaration of the symbol at the position.
+// It skips use collection and the package-wide parent map; the target
+// file's parents are enough to recognize type switch guards.
+func definition(in Input) *DefinitionOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	t := resolveTarget(lp, in.Line, in.Col, parents, true)
+	if t == nil {
+		return nil
+	}
+	out := &DefinitionOutput{
+		Name:     t.name(),
+		Decl:     t.declRange(lp.fset),
+		DeclKind: t.kind(lp.info),
+	}
+	if typ := t.typ(lp.info); typ != nil {
+		out.Type = types.TypeString(typ, types.RelativeTo(lp.pkg))
+	}
+	return out
+}
+
+func (t *symbolTarget) name() string {
+	if t.declIdent != nil {
+		return t.declIdent.Name
+	}
+	return t.obj.Name()
+}
+
+func (t *symbolTarget) declRange(fset *token.FileSet) Range {
+	if t.declIdent == nil {
+		return t.externalDecl
+	}
+	return rangeForIdent(fset, t.declIdent)
+}
+
+// kind returns the decl_kind of the target; a type switch guard is a var.
+func (t *symbolTarget) kind(info *types.Info) string {
+	if t.typeSwitch != nil {
+		return "var"
+	}
+	return declKind(t.obj, info)
+}
+
+// typ returns the type of the target. A type switch guard has a different
+// type in every clause, so the type of the switched expression is used.
+func (t *symbolTarget) typ(info *types.Info) types.Type {
+	if _, ok := t.obj.(*types.PkgName); ok {
+		return nil
+	}
+	if t.typeSwitch == nil {
+		return t.obj.Type()
+	}
+	if subject := typeSwitchSubject(t.typeSwitch); subject != nil {
+		return info.TypeOf(subject)
+	}
+	return nil
+}
+
+func declKind(obj types.Object, info *types.Info) string {
+	switch o := obj.(type) {
+	case *types.Var:
+		if o.IsField() {
+			return "field"
+		}
+		if kind := paramKind(o, info); kind != "" {
+			return kind
+		}
+		return "var"
+	case *types.Const:
+		return "const"
+	case *types.PkgName:
+		return "package"
+	case *types.TypeName:
+		return "type"
+	case *types.Func:
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return "method"
+		}
+		return "func"
+	}
+	return "var"
+}
+
+// paramKind returns "param" for a receiver or parameter and "result" for a
+// named result, and "" for other variables. The checker puts all of them
+// in the scope it records for the function type, together with the locals
+// declared at the top of the body, so they are told apart by position.
+func paramKind(v *types.Var, info *types.Info) string {
+	for node, scope := range info.Scopes {
+		ft, ok := node.(*ast.FuncType)
+		if !ok || scope != v.Parent() {
+			continue
+		}
+		switch {
+		case v.Pos() < ft.Params.End():
+			return "param"
+		case ft.Results != nil && v.Pos() < ft.Results.End():
+			return "result"
+		}
+		return ""
+	}
+	return ""
+}
+
+// typeSwitchSubject returns x in `switch v := x.(type)`.
+func typeSwitchSubject(ts *ast.TypeSwitchStmt) ast.Expr {
+	as, ok := ts.Assign.(*ast.AssignStmt)
+	if !ok || len(as.Rhs) != 1 {
+		return nil
+	}
+	if ta, ok := as.Rhs[0].(*ast.TypeAssertExpr); ok {
+		return ta.X
+	}
+	return nil
+}
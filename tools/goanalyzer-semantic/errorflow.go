@@ -0,0 +1,287 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// ErrorFlowOutput is the response of "error_flow" mode: what happens to the
+// error variable at Line/Col.
+type ErrorFlowOutput struct {
+	Name     string `json:"name"`
+	Decl     Range  `json:"decl"`
+	Function string `json:"function"`
+	// Uses classifies every read and write of the variable; see ErrorUse.
+	Uses []ErrorUse `json:"uses"`
+	// Discarded are calls in the declaring function, including its function
+	// literals, whose error result is dropped by a bare call or a blank
+	// assignment.
+	Discarded []Range `json:"discarded"`
+}
+
+// ErrorUse is one use of the error variable. Kind is "checked" for nil
+// comparisons and errors.Is/As, "returned" (including bare returns of a
+// named result), "wrapped" for a %w operand of fmt.Errorf, "logged" for
+// log and fmt print calls, "blank" for `_ = err`, "assigned" for a write
+// that is read later, "overwritten" for a write followed, in source order,
+// by another write before any read, and "other" for anything else.
+type ErrorUse struct {
+	Kind  string `json:"kind"`
+	Range Range  `json:"range"`
+}
+
+func (o *ErrorFlowOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Uses {
+		f(&o.Uses[i].Range)
+	}
+	for i := range o.Discarded {
+		f(&o.Discarded[i])
+	}
+}
+
+// errorFlow follows the error variable at Line/Col by object identity, so
+// an err redeclared in an if statement's init is a variable of its own.
+func errorFlow(in Input) *ErrorFlowOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	v, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Var)
+	if !ok || v.IsField() || !isErrorType(v.Type()) {
+		return nil
+	}
+	declIdent := findDeclIdent(lp.info, v)
+	if declIdent == nil {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	fd := enclosingFuncDecl(declIdent, parents)
+	if fd == nil || fd.Body == nil {
+		return nil
+	}
+	out := &ErrorFlowOutput{
+		Name:      v.Name(),
+		Decl:      rangeForIdent(lp.fset, declIdent),
+		Function:  callerName(declIdent, parents),
+		Uses:      make([]ErrorUse, 0),
+		Discarded: make([]Range, 0),
+	}
+
+	// A write is ordered after the statement that makes it, so err in
+	// `err = wrap(err)` is read before it is written, and only counts as
+	// overwritten by a later write in the same statement list.
+	type event struct {
+		pos   token.Pos
+		write bool
+		block ast.Node
+		use   int
+	}
+	var events []event
+	if stmt := assignStmtOf(declIdent, parents); stmt != nil && paramKind(v, lp.info) == "" {
+		events = append(events, event{pos: stmt.End(), write: true, block: parents[stmt], use: -1})
+	}
+	result := paramKind(v, lp.info) == "result"
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.Ident:
+			if lp.info.Uses[node] != v {
+				return true
+			}
+			e := event{pos: node.Pos(), use: len(out.Uses)}
+			kind := "assigned"
+			if !isReassign(node, lp.info, parents) {
+				kind = errorUseKind(node, lp.info, parents)
+			} else if stmt := assignStmtOf(node, parents); stmt != nil {
+				e.pos, e.write, e.block = stmt.End(), true, parents[stmt]
+			}
+			events = append(events, e)
+			out.Uses = append(out.Uses, ErrorUse{Kind: kind, Range: rangeForIdent(lp.fset, node)})
+		case *ast.ReturnStmt:
+			if result && len(node.Results) == 0 && enclosingFuncDecl(node, parents) == fd && enclosingFuncLit(node, parents) == nil {
+				events = append(events, event{pos: node.Pos(), use: len(out.Uses)})
+				out.Uses = append(out.Uses, ErrorUse{Kind: "returned", Range: rangeForPos(lp.fset, node.Pos(), node.End())})
+			}
+		case *ast.CallExpr:
+			if errorResultDiscarded(node, lp.info, parents) {
+				out.Discarded = append(out.Discarded, rangeForPos(lp.fset, node.Pos(), node.End()))
+			}
+		}
+		return true
+	})
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].pos < events[j].pos })
+	for i, e := range events {
+		if !e.write {
+			continue
+		}
+		if i+1 < len(events) && events[i+1].write && events[i+1].block == e.block {
+			if e.use < 0 {
+				out.Uses = append(out.Uses, ErrorUse{Kind: "overwritten", Range: out.Decl})
+			} else {
+				out.Uses[e.use].Kind = "overwritten"
+			}
+		}
+	}
+	sort.SliceStable(out.Uses, func(i, j int) bool {
+		return rangeLess(out.Uses[i].Range, out.Uses[j].Range)
+	})
+	return out
+}
+
+// errorUseKind classifies a read of an error variable; see ErrorUse.
+func errorUseKind(id *ast.Ident, info *types.Info, parents map[ast.Node]ast.Node) string {
+	var expr ast.Node = id
+	for {
+		p, ok := parents[expr].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = p
+	}
+	switch p := parents[expr].(type) {
+	case *ast.BinaryExpr:
+		if p.Op == token.EQL || p.Op == token.NEQ {
+			return "checked"
+		}
+	case *ast.ReturnStmt:
+		return "returned"
+	case *ast.AssignStmt:
+		for i, rhs := range p.Rhs {
+			if rhs == expr && len(p.Lhs) == len(p.Rhs) && isBlank(p.Lhs[i]) {
+				return "blank"
+			}
+		}
+	case *ast.CallExpr:
+		fn := passes.CalledFunc(p, info)
+		if fn == nil || fn.Pkg() == nil {
+			break
+		}
+		switch path := fn.Pkg().Path(); {
+		case path == "errors" && (fn.Name() == "Is" || fn.Name() == "As"):
+			return "checked"
+		case path == "fmt" && fn.Name() == "Errorf":
+			if errorfWraps(p, expr, info) {
+				return "wrapped"
+			}
+		case path == "log" || path == "log/slog":
+			return "logged"
+		case path == "fmt" && isPrintFunc(fn.Name()):
+			return "logged"
+		}
+	}
+	return "other"
+}
+
+// errorfWraps reports whether arg is formatted with %w by the fmt.Errorf
+// call.
+func errorfWraps(call *ast.CallExpr, arg ast.Node, info *types.Info) bool {
+	if len(call.Args) == 0 {
+		return false
+	}
+	format := info.Types[call.Args[0]].Value
+	if format == nil || format.Kind() != constant.String {
+		return false
+	}
+	verbs, ok := formatVerbs(constant.StringVal(format))
+	if !ok {
+		return false
+	}
+	for i, verb := range verbs {
+		if i+1 < len(call.Args) && call.Args[i+1] == arg {
+			return verb == 'w'
+		}
+	}
+	return false
+}
+
+// errorResultDiscarded reports whether call returns an error that is
+// dropped: the call is an expression statement, or the error's position is
+// assigned to _. The fmt print functions, whose errors are conventionally
+// ignored, are left out.
+func errorResultDiscarded(call *ast.CallExpr, info *types.Info, parents map[ast.Node]ast.Node) bool {
+	if fn := passes.CalledFunc(call, info); fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == "fmt" && isPrintFunc(fn.Name()) {
+		return false
+	}
+	sig, ok := info.TypeOf(call.Fun).(*types.Signature)
+	if !ok {
+		if tv, ok := info.Types[call.Fun]; !ok || tv.IsType() {
+			return false
+		}
+		sig, ok = info.TypeOf(call.Fun).Underlying().(*types.Signature)
+		if !ok {
+			return false
+		}
+	}
+	results := sig.Results()
+	errIndex := -1
+	for i := 0; i < results.Len(); i++ {
+		if isErrorType(results.At(i).Type()) {
+			errIndex = i
+		}
+	}
+	if errIndex < 0 {
+		return false
+	}
+	switch p := parents[call].(type) {
+	case *ast.ExprStmt:
+		return true
+	case *ast.AssignStmt:
+		if len(p.Rhs) == 1 && len(p.Lhs) == results.Len() {
+			return isBlank(p.Lhs[errIndex])
+		}
+		for i, rhs := range p.Rhs {
+			if rhs == call && len(p.Lhs) == len(p.Rhs) {
+				return isBlank(p.Lhs[i])
+			}
+		}
+	}
+	return false
+}
+
+func isPrintFunc(name string) bool {
+	return strings.HasPrefix(name, "Print") || strings.HasPrefix(name, "Fprint")
+}
+
+// assignStmtOf returns the statement that assigns a value to id: an
+// assignment, or a var declaration with initial values. It returns nil
+// otherwise, including for range clauses, which assign once per iteration.
+func assignStmtOf(id *ast.Ident, parents map[ast.Node]ast.Node) ast.Stmt {
+	switch p := parents[id].(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range p.Lhs {
+			if lhs == id {
+				return p
+			}
+		}
+	case *ast.ValueSpec:
+		if len(p.Values) > 0 {
+			if stmt, ok := parents[parents[p]].(*ast.DeclStmt); ok {
+				return stmt
+			}
+		}
+	}
+	return nil
+}
+
+func enclosingFuncDecl(node ast.Node, parents map[ast.Node]ast.Node) *ast.FuncDecl {
+	for n := parents[node]; n != nil; n = parents[n] {
+		if fd, ok := n.(*ast.FuncDecl); ok {
+			return fd
+		}
+	}
+	return nil
+}
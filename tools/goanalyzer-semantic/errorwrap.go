@@ -0,0 +1,105 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// errorWrapAnalyzer flags fmt.Errorf calls that format an error value with
+// a verb other than %w. The result carries only the text, so errors.Is and
+// errors.As can no longer see the original error. Format strings that use
+// explicit argument indexes are skipped.
+var errorWrapAnalyzer = &analyzer{
+	name:     "errorwrap",
+	code:     "GA304",
+	summary:  "fmt.Errorf formats an error without %w",
+	severity: "info",
+	run:      runErrorWrap,
+}
+
+func runErrorWrap(p *pass) []Finding {
+	errType := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+			return true
+		}
+		fn := passes.CalledFunc(call, p.info)
+		if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "fmt" || fn.Name() != "Errorf" {
+			return true
+		}
+		format := p.info.Types[call.Args[0]].Value
+		if format == nil || format.Kind() != constant.String {
+			return true
+		}
+		verbs, ok := formatVerbs(constant.StringVal(format))
+		if !ok {
+			return true
+		}
+		var related []RelatedRange
+		for i, verb := range verbs {
+			if verb == 'w' || verb == '*' || i+1 >= len(call.Args) {
+				continue
+			}
+			arg := call.Args[i+1]
+			typ := p.info.TypeOf(arg)
+			if typ == nil || !types.Implements(typ, errType) {
+				continue
+			}
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(arg),
+				Message: "error formatted with %" + string(verb) + " instead of %w",
+			})
+		}
+		if len(related) == 0 {
+			return true
+		}
+		findings = append(findings, Finding{
+			Message: "fmt.Errorf formats an error without %w, which drops it from the error chain",
+			Range:   p.rangeForNode(call),
+			Related: related,
+		})
+		return true
+	})
+	return findings
+}
+
+// formatVerbs returns the verb consuming each operand of a printf format, in
+// operand order; a '*' width or precision consumes an operand of its own
+// and is reported as '*'. It reports false for formats with explicit
+// argument indexes like %[1]v.
+func formatVerbs(format string) ([]rune, bool) {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		for i < len(format) && (format[i] == '.' || format[i] == '*' || format[i] == '[' || ('0' <= format[i] && format[i] <= '9')) {
+			switch format[i] {
+			case '[':
+				return nil, false
+			case '*':
+				verbs = append(verbs, '*')
+			}
+			i++
+		}
+		if i >= len(format) {
+			break
+		}
+		if format[i] == '%' {
+			continue
+		}
+		verb := []rune(format[i:])[0]
+		verbs = append(verbs, verb)
+	}
+	return verbs, true
+}
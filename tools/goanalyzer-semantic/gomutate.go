@@ -0,0 +1,45 @@
+package main
+
+import "goanalyzer-semantic/passes"
+
+// goMutateAnalyzer flags `go func() {...}()` literals that write a struct
+// field through a variable captured from the enclosing function, such as a
+// shared pointer, without holding a lock inside the goroutine. Locks held
+// by the enclosing function at the go statement do not protect the
+// goroutine, which runs after they may have been released.
+var goMutateAnalyzer = &analyzer{
+	name:     "gomutate",
+	code:     "GA105",
+	summary:  "Goroutine writes a field through a captured variable without a lock",
+	severity: "warning",
+	report:   "race",
+	run:      runGoMutate,
+}
+
+func runGoMutate(p *pass) []Finding {
+	var findings []Finding
+	for field, accesses := range p.FieldAccesses() {
+		if passes.HasAtomicAccess(accesses) {
+			continue
+		}
+		for _, w := range accesses {
+			if !w.Write || len(w.Locks) > 0 {
+				continue
+			}
+			v, captured := p.CapturedRoot(w)
+			if !captured {
+				continue
+			}
+			var related []RelatedRange
+			if decl, ok := p.objectRange(v); ok {
+				related = append(related, RelatedRange{Range: decl, Message: v.Name() + " is declared outside the goroutine"})
+			}
+			findings = append(findings, Finding{
+				Message: "goroutine writes field " + field.Name() + " through captured variable " + v.Name() + " without holding a lock",
+				Range:   p.rangeForNode(w.Sel),
+				Related: related,
+			})
+		}
+	}
+	return findings
+}
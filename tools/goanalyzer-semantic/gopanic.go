@@ -0,0 +1,129 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// goPanicAnalyzer flags `go func() {...}()` literals whose body calls panic
+// without a deferred recover. An unrecovered panic on any goroutine takes
+// down the whole process. Only literal panic calls in the goroutine body
+// itself are considered; nested function literals are skipped.
+var goPanicAnalyzer = &analyzer{
+	name:     "gopanic",
+	code:     "GA302",
+	summary:  "Goroutine panics without a deferred recover",
+	severity: "error",
+	run:      runGoPanic,
+}
+
+func runGoPanic(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := passes.Unparen(gs.Call.Fun).(*ast.FuncLit)
+		if !ok || lit.Body == nil {
+			return true
+		}
+		var panics []*ast.CallExpr
+		recovered := false
+		inspectFuncBody(lit.Body, func(n ast.Node) {
+			switch node := n.(type) {
+			case *ast.DeferStmt:
+				if p.deferRecovers(node.Call) {
+					recovered = true
+				}
+			case *ast.CallExpr:
+				if p.isBuiltinCall(node, "panic") {
+					panics = append(panics, node)
+				}
+			}
+		})
+		if recovered || len(panics) == 0 {
+			return true
+		}
+		related := make([]RelatedRange, 0, len(panics))
+		for _, call := range panics {
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(call),
+				Message: "panic without recover",
+			})
+		}
+		findings = append(findings, Finding{
+			Message: "goroutine can panic without a deferred recover, which crashes the program",
+			Range:   rangeForPos(p.fset, gs.Go, gs.Go+token.Pos(len("go"))),
+			Related: related,
+		})
+		return true
+	})
+	return findings
+}
+
+// inspectFuncBody calls f for every node in body without descending into
+// nested function literals, whose statements do not run as part of body.
+func inspectFuncBody(body *ast.BlockStmt, f func(ast.Node)) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		f(n)
+		return true
+	})
+}
+
+// deferRecovers reports whether the deferred call may recover a panic: a
+// function literal or same-package function that calls recover, or a
+// function whose body is not available and is given the benefit of the
+// doubt.
+func (p *pass) deferRecovers(call *ast.CallExpr) bool {
+	var body *ast.BlockStmt
+	switch fun := passes.Unparen(call.Fun).(type) {
+	case *ast.FuncLit:
+		body = fun.Body
+	default:
+		fn := passes.CalledFunc(call, p.info)
+		if fn == nil {
+			return false
+		}
+		decl := p.funcDecl(fn)
+		if decl == nil {
+			return true
+		}
+		body = decl.Body
+	}
+	if body == nil {
+		return false
+	}
+	found := false
+	inspectFuncBody(body, func(n ast.Node) {
+		if c, ok := n.(*ast.CallExpr); ok && p.isBuiltinCall(c, "recover") {
+			found = true
+		}
+	})
+	return found
+}
+
+func (p *pass) isBuiltinCall(call *ast.CallExpr, name string) bool {
+	return isBuiltin(call, name, p.info)
+}
+
+// funcDecl returns the declaration of fn among the package's files.
+func (p *pass) funcDecl(fn *types.Func) *ast.FuncDecl {
+	for _, f := range p.files {
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && p.info.Defs[fd.Name] == fn {
+				return fd
+			}
+		}
+	}
+	return nil
+}
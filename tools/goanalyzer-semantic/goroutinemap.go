@@ -0,0 +1,266 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// GoroutineMapOutput is the response of "goroutine_map" mode: every go
+// statement of the target file in source order.
+type GoroutineMapOutput struct {
+	Goroutines []GoroutineLaunch `json:"goroutines"`
+}
+
+// GoroutineLaunch describes one go statement. Kind is "literal" for a
+// function literal, "func" or "method" for a named function, or "value"
+// for a call through a function value.
+type GoroutineLaunch struct {
+	Range Range  `json:"range"`
+	Kind  string `json:"kind"`
+	// Target names the launched function, "T.m" for methods.
+	Target string `json:"target,omitempty"`
+	// Function is the declaration containing the go statement.
+	Function string `json:"function"`
+	// Loop is the innermost for or range statement of Function around the
+	// go statement.
+	Loop   *Range           `json:"loop,omitempty"`
+	Inputs []GoroutineInput `json:"inputs"`
+	// LocksHeld names the mutexes held when the goroutine starts.
+	LocksHeld []string `json:"locks_held,omitempty"`
+	// Termination is how the goroutine's body ends: "context" when an
+	// endless loop waits on ctx.Done(), "stop_channel" when it returns from
+	// a select case receiving from a channel, "bounded" when it has no
+	// endless loop, "none" when an endless loop has neither exit, and
+	// "unknown" when the body is not in the package.
+	Termination string `json:"termination"`
+}
+
+// GoroutineInput is a variable or field the goroutine reads from its
+// launcher. Via is "capture" for a function literal's outer variables and
+// the fields reached through them, "argument" for call arguments and
+// "receiver" for the receiver of a launched method.
+type GoroutineInput struct {
+	Name  string `json:"name"`
+	Via   string `json:"via"`
+	Field bool   `json:"field,omitempty"`
+}
+
+func (o *GoroutineMapOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Goroutines {
+		f(&o.Goroutines[i].Range)
+		if o.Goroutines[i].Loop != nil {
+			f(o.Goroutines[i].Loop)
+		}
+	}
+}
+
+func goroutineMap(in Input) *GoroutineMapOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	bodies := make(map[*types.Func]*ast.BlockStmt)
+	for _, f := range lp.files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+				if fn, ok := lp.info.Defs[fd.Name].(*types.Func); ok {
+					bodies[fn] = fd.Body
+				}
+			}
+		}
+	}
+
+	out := &GoroutineMapOutput{Goroutines: make([]GoroutineLaunch, 0)}
+	ast.Inspect(lp.file, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok || gs.Call == nil {
+			return true
+		}
+		g := GoroutineLaunch{
+			Range:    rangeForPos(lp.fset, gs.Pos(), gs.End()),
+			Function: callerName(gs, parents),
+			Inputs:   make([]GoroutineInput, 0),
+		}
+		if loop := enclosingLoop(gs, parents); loop != nil {
+			r := rangeForPos(lp.fset, loop.Pos(), loop.End())
+			g.Loop = &r
+		}
+		for lock := range passes.HeldLocks(gs, parents, lp.info) {
+			g.LocksHeld = append(g.LocksHeld, lock.Name())
+		}
+		sort.Strings(g.LocksHeld)
+
+		var body *ast.BlockStmt
+		if lit, ok := passes.Unparen(gs.Call.Fun).(*ast.FuncLit); ok {
+			g.Kind = "literal"
+			body = lit.Body
+			g.Inputs = append(g.Inputs, literalCaptures(lp, lit)...)
+		} else if fn := passes.CalledFunc(gs.Call, lp.info); fn != nil {
+			g.Kind = "func"
+			g.Target = fn.Name()
+			if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+				g.Kind = "method"
+				g.Target = receiverName(recv.Type()) + "." + fn.Name()
+				if sel, ok := passes.Unparen(gs.Call.Fun).(*ast.SelectorExpr); ok {
+					g.Inputs = append(g.Inputs, goroutineInput(lp, sel.X, "receiver"))
+				}
+			}
+			body = bodies[fn]
+		} else {
+			g.Kind = "value"
+		}
+		for _, arg := range gs.Call.Args {
+			g.Inputs = append(g.Inputs, goroutineInput(lp, arg, "argument"))
+		}
+		g.Termination = termination(lp.info, body)
+		out.Goroutines = append(out.Goroutines, g)
+		return true
+	})
+	return out
+}
+
+// enclosingLoop returns the innermost for or range statement around node
+// within its function.
+func enclosingLoop(node ast.Node, parents map[ast.Node]ast.Node) ast.Node {
+	for n := parents[node]; n != nil; n = parents[n] {
+		switch n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return n
+		case *ast.FuncLit, *ast.FuncDecl:
+			return nil
+		}
+	}
+	return nil
+}
+
+func receiverName(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+	return types.TypeString(typ, nil)
+}
+
+// literalCaptures lists the outer local variables lit references, followed
+// by the fields it reaches through them, each once in source order.
+func literalCaptures(lp *loadedPackage, lit *ast.FuncLit) []GoroutineInput {
+	var vars, fields []GoroutineInput
+	seen := make(map[string]bool)
+	outer := func(id *ast.Ident) bool {
+		v, ok := lp.info.Uses[id].(*types.Var)
+		return ok && !v.IsField() && v.Parent() != lp.pkg.Scope() && (v.Pos() < lit.Pos() || v.Pos() >= lit.End())
+	}
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if root, ok := passes.Unparen(node.X).(*ast.Ident); ok && outer(root) {
+				if s := lp.info.Selections[node]; s != nil && s.Kind() == types.FieldVal {
+					name := root.Name + "." + node.Sel.Name
+					if !seen[name] {
+						seen[name] = true
+						fields = append(fields, GoroutineInput{Name: name, Via: "capture", Field: true})
+					}
+				}
+			}
+		case *ast.Ident:
+			if outer(node) && !seen[node.Name] {
+				seen[node.Name] = true
+				vars = append(vars, GoroutineInput{Name: node.Name, Via: "capture"})
+			}
+		}
+		return true
+	})
+	return append(vars, fields...)
+}
+
+func goroutineInput(lp *loadedPackage, expr ast.Expr, via string) GoroutineInput {
+	in := GoroutineInput{Name: types.ExprString(expr), Via: via}
+	if sel, ok := passes.Unparen(expr).(*ast.SelectorExpr); ok {
+		if s := lp.info.Selections[sel]; s != nil && s.Kind() == types.FieldVal {
+			in.Field = true
+		}
+	}
+	return in
+}
+
+// termination classifies how the goroutine body ends; see
+// GoroutineLaunch.Termination.
+func termination(info *types.Info, body *ast.BlockStmt) string {
+	if body == nil {
+		return "unknown"
+	}
+	endless, ctxDone, stopCase := false, false, false
+	inspectFuncBody(body, func(n ast.Node) {
+		switch node := n.(type) {
+		case *ast.ForStmt:
+			if node.Cond == nil {
+				endless = true
+			}
+		case *ast.CommClause:
+			recv := commReceive(node.Comm)
+			if recv == nil || !returnsDirectly(node.Body) {
+				return
+			}
+			if call, ok := passes.Unparen(recv.X).(*ast.CallExpr); ok && isContextDone(call, info) {
+				ctxDone = true
+			} else {
+				stopCase = true
+			}
+		}
+	})
+	switch {
+	case !endless:
+		return "bounded"
+	case ctxDone:
+		return "context"
+	case stopCase:
+		return "stop_channel"
+	}
+	return "none"
+}
+
+// commReceive returns the receive operation of a select case, if any.
+func commReceive(comm ast.Stmt) *ast.UnaryExpr {
+	var expr ast.Expr
+	switch s := comm.(type) {
+	case *ast.ExprStmt:
+		expr = s.X
+	case *ast.AssignStmt:
+		if len(s.Rhs) == 1 {
+			expr = s.Rhs[0]
+		}
+	}
+	if u, ok := passes.Unparen(expr).(*ast.UnaryExpr); ok && u.Op == token.ARROW {
+		return u
+	}
+	return nil
+}
+
+func returnsDirectly(stmts []ast.Stmt) bool {
+	for _, s := range stmts {
+		if _, ok := s.(*ast.ReturnStmt); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isContextDone reports whether call is ctx.Done() on a context.Context.
+func isContextDone(call *ast.CallExpr, info *types.Info) bool {
+	sel, ok := passes.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Done" {
+		return false
+	}
+	named, ok := info.TypeOf(sel.X).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
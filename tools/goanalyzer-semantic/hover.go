@@ -0,0 +1,175 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// HoverOutput is the response of "hover" mode: everything an editor shows
+// for the symbol under the mouse, without its uses.
+type HoverOutput struct {
+	Name     string `json:"name"`
+	Decl     Range  `json:"decl"`
+	DeclKind string `json:"decl_kind"`
+	// Type is qualified with full package paths.
+	Type string `json:"type"`
+	// Size is the size in bytes of a value of Type on the host architecture;
+	// it is omitted for functions, untyped constants and generic types.
+	Size *int64 `json:"size,omitempty"`
+	Doc  string `json:"doc,omitempty"`
+	// Value is the exact value of a constant.
+	Value string `json:"value,omitempty"`
+	// Owner is the named struct type a field belongs to.
+	Owner string `json:"owner,omitempty"`
+	// GuardedBy names the mutex held at every access of a field in the
+	// package, when there is one.
+	GuardedBy string `json:"guarded_by,omitempty"`
+	// ImplementsError and ImplementsStringer report whether Type satisfies
+	// error and fmt.Stringer, and Implements lists every interface of
+	// Input.Interfaces it satisfies. For a type name, methods with pointer
+	// receivers count too.
+	ImplementsError    bool     `json:"implements_error,omitempty"`
+	ImplementsStringer bool     `json:"implements_stringer,omitempty"`
+	Implements         []string `json:"implements,omitempty"`
+}
+
+// defaultInterfaces are the interfaces hover checks when the request names
+// none.
+var defaultInterfaces = []string{"error", "fmt.Stringer"}
+
+func (o *HoverOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+}
+
+func hover(in Input) *HoverOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := buildPackageParentMap(lp.files)
+	t := resolveTarget(lp, in.Line, in.Col, parents, true)
+	if t == nil {
+		return nil
+	}
+	out := &HoverOutput{
+		Name:     t.name(),
+		Decl:     t.declRange(lp.fset),
+		DeclKind: t.kind(lp.info),
+	}
+	typ := t.typ(lp.info)
+	if typ != nil {
+		out.Type = types.TypeString(typ, nil)
+		if out.DeclKind != "func" && out.DeclKind != "method" {
+			out.Size = passes.SizeOf(typ)
+			_, isType := t.obj.(*types.TypeName)
+			out.ImplementsError = implements(typ, lookupInterface(lp, "error"), isType)
+			out.ImplementsStringer = implements(typ, lookupInterface(lp, "fmt.Stringer"), isType)
+			names := in.Interfaces
+			if len(names) == 0 {
+				names = defaultInterfaces
+			}
+			for _, name := range names {
+				if implements(typ, lookupInterface(lp, name), isType) {
+					out.Implements = append(out.Implements, name)
+				}
+			}
+		}
+	}
+	if t.declIdent != nil && t.typeSwitch == nil {
+		out.Doc = docForIdent(t.declIdent, parents)
+	}
+	switch obj := t.obj.(type) {
+	case *types.Const:
+		out.Value = obj.Val().ExactString()
+	case *types.Var:
+		if !obj.IsField() {
+			break
+		}
+		if t.declIdent != nil {
+			out.Owner = fieldOwner(t.declIdent, parents)
+		}
+		if lock := inferGuard(lp.files, lp.info, parents, obj); lock != nil {
+			out.GuardedBy = lock.Name()
+		}
+	}
+	return out
+}
+
+// implements reports whether typ, or with orPointer *typ, satisfies iface.
+// Interface types themselves are not reported.
+func implements(typ types.Type, iface *types.Interface, orPointer bool) bool {
+	if iface == nil || types.IsInterface(typ) {
+		return false
+	}
+	if types.Implements(typ, iface) {
+		return true
+	}
+	_, isPtr := typ.Underlying().(*types.Pointer)
+	return orPointer && !isPtr && types.Implements(types.NewPointer(typ), iface)
+}
+
+// lookupInterface finds the interface type named "error" or "path.Name",
+// among the package's imports first and through the importer otherwise.
+func lookupInterface(lp *loadedPackage, name string) *types.Interface {
+	var obj types.Object
+	if name == "error" {
+		obj = types.Universe.Lookup(name)
+	} else if dot := strings.LastIndex(name, "."); dot > 0 {
+		path := name[:dot]
+		var pkg *types.Package
+		for _, imp := range lp.pkg.Imports() {
+			if imp.Path() == path {
+				pkg = imp
+			}
+		}
+		if pkg == nil {
+			pkg, _ = newVendorImporter(lp.fset).ImportFrom(path, filepath.Dir(lp.fset.Position(lp.file.Pos()).Filename), 0)
+		}
+		if pkg != nil {
+			obj = pkg.Scope().Lookup(name[dot+1:])
+		}
+	}
+	if tn, ok := obj.(*types.TypeName); ok {
+		iface, _ := tn.Type().Underlying().(*types.Interface)
+		return iface
+	}
+	return nil
+}
+
+// sizeBytes is passes.SizeOf with -1 for types of unknown size, including
+// invalid types left by type errors.
+func sizeBytes(typ types.Type) int64 {
+	if typ == nil {
+		return -1
+	}
+	if b, ok := typ.Underlying().(*types.Basic); ok && b.Kind() == types.Invalid {
+		return -1
+	}
+	if size := passes.SizeOf(typ); size != nil {
+		return *size
+	}
+	return -1
+}
+
+// fieldOwner returns the name of the type declaring the struct field ident.
+func fieldOwner(ident *ast.Ident, parents map[ast.Node]ast.Node) string {
+	var n ast.Node = ident
+	for n != nil {
+		switch node := n.(type) {
+		case *ast.TypeSpec:
+			return node.Name.Name
+		case *ast.FuncDecl, *ast.FuncLit:
+			// A struct literal type inside a function has no name.
+			return ""
+		}
+		n = parents[n]
+	}
+	return ""
+}
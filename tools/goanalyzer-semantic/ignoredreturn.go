@@ -0,0 +1,146 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// ignoredReturnAnalyzer flags functions declared in the file whose non-error
+// results are discarded at every call site in the package, by a bare call,
+// a go or defer statement or assignments to _. Such results may be dead.
+// Functions that are never called, used as values, or exported from a
+// package other than main are skipped, since their results may be used
+// elsewhere.
+var ignoredReturnAnalyzer = &analyzer{
+	name:     "ignoredreturn",
+	code:     "GA307",
+	summary:  "Function result discarded at every call site",
+	severity: "info",
+	run:      runIgnoredReturn,
+}
+
+func runIgnoredReturn(p *pass) []Finding {
+	parents := buildPackageParentMap(p.files)
+	var findings []Finding
+	for _, decl := range p.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		fn, ok := p.info.Defs[fd.Name].(*types.Func)
+		if !ok || (fn.Exported() && p.pkg.Name() != "main") || !hasNonErrorResult(fn) {
+			continue
+		}
+		var sites []*ast.CallExpr
+		asValue := false
+		for id, obj := range p.info.Uses {
+			if obj != fn {
+				continue
+			}
+			var fun ast.Node = id
+			if sel, ok := parents[id].(*ast.SelectorExpr); ok && sel.Sel == id {
+				fun = sel
+			}
+			for {
+				paren, ok := parents[fun].(*ast.ParenExpr)
+				if !ok {
+					break
+				}
+				fun = paren
+			}
+			call, ok := parents[fun].(*ast.CallExpr)
+			if !ok || call.Fun != fun {
+				asValue = true
+				break
+			}
+			sites = append(sites, call)
+		}
+		if asValue || len(sites) == 0 {
+			continue
+		}
+		related := make([]RelatedRange, 0, len(sites))
+		for _, call := range sites {
+			if !resultIgnored(call, p.info, parents) {
+				related = nil
+				break
+			}
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(call),
+				Message: "result discarded",
+			})
+		}
+		if related == nil {
+			continue
+		}
+		sort.Slice(related, func(i, j int) bool {
+			return rangeLess(related[i].Range, related[j].Range)
+		})
+		findings = append(findings, Finding{
+			Message: "every caller of " + fd.Name.Name + " ignores its result",
+			Range:   p.rangeForNode(fd.Name),
+			Related: related,
+		})
+	}
+	return findings
+}
+
+// hasNonErrorResult reports whether fn returns anything besides errors.
+func hasNonErrorResult(fn *types.Func) bool {
+	results := fn.Type().(*types.Signature).Results()
+	for i := 0; i < results.Len(); i++ {
+		if !isErrorType(results.At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
+
+// resultIgnored reports whether every non-error result of call is
+// discarded: the call is a statement of its own, run by go or defer, or
+// assigned to _ in each non-error position.
+func resultIgnored(call *ast.CallExpr, info *types.Info, parents map[ast.Node]ast.Node) bool {
+	var node ast.Node = call
+	for {
+		paren, ok := parents[node].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		node = paren
+	}
+	switch parent := parents[node].(type) {
+	case *ast.ExprStmt, *ast.GoStmt, *ast.DeferStmt:
+		return true
+	case *ast.AssignStmt:
+		if len(parent.Rhs) != 1 {
+			for i, rhs := range parent.Rhs {
+				if rhs == node {
+					return isBlank(parent.Lhs[i])
+				}
+			}
+			return false
+		}
+		sig, ok := info.TypeOf(call.Fun).Underlying().(*types.Signature)
+		if !ok || sig.Results().Len() != len(parent.Lhs) {
+			return false
+		}
+		for i, lhs := range parent.Lhs {
+			if !isBlank(lhs) && !isErrorType(sig.Results().At(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isErrorType(typ types.Type) bool {
+	return types.Identical(typ, types.Universe.Lookup("error").Type())
+}
+
+func isBlank(expr ast.Expr) bool {
+	id, ok := passes.Unparen(expr).(*ast.Ident)
+	return ok && id.Name == "_"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/types"
+	"sort"
+)
+
+// ImplementationsOutput is the response of "implementations" mode.
+type ImplementationsOutput struct {
+	Name string `json:"name"`
+	Decl Range  `json:"decl"`
+	// Direction is "implementations" when the query names an interface or
+	// interface method, and "interfaces" when it names a concrete type or
+	// method and the results are the interfaces it satisfies.
+	Direction string           `json:"direction"`
+	Results   []Implementation `json:"results"`
+}
+
+// Implementation is one named type on the other side of the relation.
+type Implementation struct {
+	Type string `json:"type"`
+	// Decl is the type's declaration, or for a method query the method
+	// that corresponds to the queried one.
+	Decl Range `json:"decl"`
+	// PointerOnly is set when only the pointer type satisfies the
+	// interface, because some methods have pointer receivers.
+	PointerOnly bool `json:"pointer_only,omitempty"`
+}
+
+func (o *ImplementationsOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Results {
+		f(&o.Results[i].Decl)
+	}
+}
+
+// implementations relates interfaces and the named types of the package
+// that satisfy them, in either direction. Only the target package is
+// searched.
+func implementations(in Input) *ImplementationsOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	var named *types.Named
+	var method *types.Func
+	switch obj := identObject(lp.info, lp.pkg, ident, selMap[ident]).(type) {
+	case *types.TypeName:
+		named, _ = obj.Type().(*types.Named)
+	case *types.Func:
+		method = obj
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			named = receiverNamed(sig.Recv().Type())
+		}
+	}
+	if named == nil {
+		return nil
+	}
+	obj := types.Object(named.Obj())
+	if method != nil {
+		obj = method
+	}
+	decl, ok := lp.objectRange(obj)
+	if !ok {
+		return nil
+	}
+	out := &ImplementationsOutput{Name: obj.Name(), Decl: decl, Results: make([]Implementation, 0)}
+	iface, isIface := named.Underlying().(*types.Interface)
+	if isIface {
+		out.Direction = "implementations"
+	} else {
+		out.Direction = "interfaces"
+	}
+
+	scope := lp.pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tn.IsAlias() || tn == named.Obj() {
+			continue
+		}
+		other, ok := tn.Type().(*types.Named)
+		if !ok || other.TypeParams().Len() > 0 {
+			continue
+		}
+		otherIface, otherIsIface := other.Underlying().(*types.Interface)
+		if otherIsIface == isIface {
+			continue
+		}
+		var impl Implementation
+		if isIface {
+			impl, ok = satisfies(other, iface)
+		} else {
+			if otherIface.Empty() {
+				continue
+			}
+			impl, ok = satisfies(named, otherIface)
+		}
+		if !ok {
+			continue
+		}
+		impl.Type = tn.Name()
+		target := types.Object(tn)
+		if method != nil {
+			recv := types.Type(other)
+			if !otherIsIface {
+				recv = types.NewPointer(other)
+			}
+			// For the reverse query, an interface without the method is
+			// satisfied without involving it.
+			m, _, _ := types.LookupFieldOrMethod(recv, true, lp.pkg, method.Name())
+			if _, ok := m.(*types.Func); !ok {
+				continue
+			}
+			target = m
+		}
+		if impl.Decl, ok = lp.objectRange(target); !ok {
+			continue
+		}
+		out.Results = append(out.Results, impl)
+	}
+	sort.Slice(out.Results, func(i, j int) bool {
+		return rangeLess(out.Results[i].Decl, out.Results[j].Decl)
+	})
+	return out
+}
+
+// satisfies reports whether t or only *t implements iface.
+func satisfies(t types.Type, iface *types.Interface) (Implementation, bool) {
+	if types.Implements(t, iface) {
+		return Implementation{}, true
+	}
+	if types.Implements(types.NewPointer(t), iface) {
+		return Implementation{PointerOnly: true}, true
+	}
+	return Implementation{}, false
+}
+
+func receiverNamed(t types.Type) *types.Named {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, _ := t.(*types.Named)
+	return named
+}
+
+// objectRange returns the declaration range of obj, preferring the
+// identifier recorded by the type checker.
+func (lp *loadedPackage) objectRange(obj types.Object) (Range, bool) {
+	if ident := findDeclIdent(lp.info, obj); ident != nil {
+		return rangeForIdent(lp.fset, ident), true
+	}
+	return rangeForObject(lp.fset, obj)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// vendorImporter resolves imports from the module's vendor/ tree when the
+// module is vendored and delegates everything else to the default importer.
+// Vendored packages are type-checked from source into the shared FileSet so
+// their objects carry real positions.
+type vendorImporter struct {
+	fset     *token.FileSet
+	fallback types.Importer
+	packages map[string]*types.Package
+	// loaded and failed count import attempts so callers can tell a broken
+	// toolchain (every import fails) from an ordinary missing dependency.
+	loaded int
+	failed int
+}
+
+// defaultImporter creates the importer used for non-vendored packages.
+var defaultImporter = importer.Default
+
+func newVendorImporter(fset *token.FileSet) *vendorImporter {
+	return &vendorImporter{
+		fset:     fset,
+		fallback: defaultImporter(),
+		packages: make(map[string]*types.Package),
+	}
+}
+
+func (vi *vendorImporter) Import(path string) (*types.Package, error) {
+	return vi.ImportFrom(path, "", 0)
+}
+
+func (vi *vendorImporter) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	pkg, err := vi.importFrom(path, dir, mode)
+	if err != nil {
+		vi.failed++
+	} else {
+		vi.loaded++
+	}
+	return pkg, err
+}
+
+func (vi *vendorImporter) importFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if pkgDir := findVendoredPackage(dir, path); pkgDir != "" {
+		return vi.importVendored(path, pkgDir)
+	}
+	if from, ok := vi.fallback.(types.ImporterFrom); ok {
+		return from.ImportFrom(path, dir, mode)
+	}
+	return vi.fallback.Import(path)
+}
+
+func (vi *vendorImporter) importVendored(path, pkgDir string) (*types.Package, error) {
+	if pkg, ok := vi.packages[pkgDir]; ok {
+		return pkg, nil
+	}
+	// Build constraints are applied as in parsePackageFiles, so files for
+	// other platforms and //go:build ignore files stay out of the package.
+	pkgs, err := parser.ParseDir(vi.fset, pkgDir, func(fi os.FileInfo) bool {
+		if strings.HasSuffix(fi.Name(), "_test.go") {
+			return false
+		}
+		match, err := build.Default.MatchFile(pkgDir, fi.Name())
+		return err == nil && match
+	}, parser.ParseComments)
+	if err != nil && len(pkgs) == 0 {
+		return nil, err
+	}
+	var files []*ast.File
+	for name, pkg := range pkgs {
+		if name == "main" {
+			continue
+		}
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+		break
+	}
+	config := &types.Config{
+		Importer: vi,
+		Error:    func(error) {},
+	}
+	pkg, err := config.Check(path, vi.fset, files, nil)
+	if pkg == nil {
+		return nil, err
+	}
+	vi.packages[pkgDir] = pkg
+	return pkg, nil
+}
+
+// findVendoredPackage returns the directory of path inside the vendor tree of
+// the module containing dir, or "" when the module is not in vendor mode or
+// does not vendor path.
+func findVendoredPackage(dir, path string) string {
+	if dir == "" || path == "" || path == "C" {
+		return ""
+	}
+	root := findModuleRoot(dir)
+	if root == "" || !vendorMode(root) {
+		return ""
+	}
+	pkgDir := filepath.Join(root, "vendor", filepath.FromSlash(path))
+	if fi, err := os.Stat(pkgDir); err == nil && fi.IsDir() {
+		return pkgDir
+	}
+	return ""
+}
+
+// vendorMode reports whether the go command would build the module at root
+// from its vendor tree: -mod in GOFLAGS decides when set, and otherwise
+// vendor/modules.txt must exist and go.mod must declare go 1.14 or later.
+// A go.mod without a go directive counts as go 1.16, as for the go command.
+func vendorMode(root string) bool {
+	switch goFlagsMod() {
+	case "vendor":
+		return true
+	case "mod", "readonly":
+		return false
+	}
+	if !fileExists(filepath.Join(root, "vendor", "modules.txt")) {
+		return false
+	}
+	major, minor, ok := goModVersion(filepath.Join(root, "go.mod"))
+	return !ok || major > 1 || major == 1 && minor >= 14
+}
+
+// goFlagsMod returns the value of the last -mod flag in GOFLAGS, or "".
+func goFlagsMod() string {
+	mod := ""
+	for _, flag := range strings.Fields(os.Getenv("GOFLAGS")) {
+		flag = strings.TrimPrefix(strings.TrimPrefix(flag, "-"), "-")
+		if v, ok := strings.CutPrefix(flag, "mod="); ok {
+			mod = v
+		}
+	}
+	return mod
+}
+
+// goModVersion returns the major and minor version of the go directive in
+// the go.mod file at path. ok is false when there is none or it is
+// malformed.
+func goModVersion(path string) (major, minor int, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "go" {
+			continue
+		}
+		parts := strings.SplitN(fields[1], ".", 3)
+		if len(parts) < 2 {
+			return 0, 0, false
+		}
+		major, err1 := strconv.Atoi(parts[0])
+		minor, err2 := strconv.Atoi(parts[1])
+		return major, minor, err1 == nil && err2 == nil
+	}
+	return 0, 0, false
+}
+
+func findModuleRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if fileExists(filepath.Join(dir, "go.mod")) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func fileExists(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && !fi.IsDir()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// InterfaceUsageOutput is the response of "interface_usage" mode: how the
+// package uses the interface-typed variable or field at Line/Col.
+type InterfaceUsageOutput struct {
+	Name      string `json:"name"`
+	Decl      Range  `json:"decl"`
+	Interface string `json:"interface"`
+	// Methods is the interface's full method set, including embedded
+	// methods, sorted by name, each with the calls made on the value.
+	Methods []InterfaceMethodUse `json:"methods"`
+	// Uncalled names the methods never called on the value, candidates for
+	// a narrower interface.
+	Uncalled   []string        `json:"uncalled"`
+	Assertions []TypeAssertion `json:"assertions"`
+}
+
+// InterfaceMethodUse lists the calls of one method on the value; a method
+// value such as f := v.M counts as a call.
+type InterfaceMethodUse struct {
+	Name  string  `json:"name"`
+	Calls []Range `json:"calls"`
+}
+
+// TypeAssertion is a v.(T) expression, or with Switch set one case type of
+// a type switch on the value. Type is "nil" for a nil case.
+type TypeAssertion struct {
+	Range  Range  `json:"range"`
+	Type   string `json:"type"`
+	Switch bool   `json:"switch,omitempty"`
+}
+
+func (o *InterfaceUsageOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Methods {
+		for j := range o.Methods[i].Calls {
+			f(&o.Methods[i].Calls[j])
+		}
+	}
+	for i := range o.Assertions {
+		f(&o.Assertions[i].Range)
+	}
+}
+
+func interfaceUsage(in Input) *InterfaceUsageOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	v, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Var)
+	if !ok {
+		return nil
+	}
+	iface, ok := v.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil
+	}
+	qualifier := types.RelativeTo(lp.pkg)
+	out := &InterfaceUsageOutput{
+		Name:       v.Name(),
+		Interface:  types.TypeString(v.Type(), qualifier),
+		Methods:    make([]InterfaceMethodUse, 0, iface.NumMethods()),
+		Uncalled:   make([]string, 0),
+		Assertions: make([]TypeAssertion, 0),
+	}
+	out.Decl, _ = lp.objectRange(v)
+	index := make(map[string]int)
+	for i := 0; i < iface.NumMethods(); i++ {
+		index[iface.Method(i).Name()] = i
+		out.Methods = append(out.Methods, InterfaceMethodUse{Name: iface.Method(i).Name(), Calls: make([]Range, 0)})
+	}
+
+	assertion := func(expr ast.Expr, isSwitch bool) {
+		name := "nil"
+		if tv := lp.info.Types[expr]; !tv.IsNil() {
+			name = types.TypeString(lp.info.TypeOf(expr), qualifier)
+		}
+		out.Assertions = append(out.Assertions, TypeAssertion{Range: rangeForPos(lp.fset, expr.Pos(), expr.End()), Type: name, Switch: isSwitch})
+	}
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.SelectorExpr:
+				s := lp.info.Selections[node]
+				if s == nil || s.Kind() != types.MethodVal || passes.ExprObject(node.X, lp.info) != v {
+					return true
+				}
+				if i, ok := index[node.Sel.Name]; ok {
+					out.Methods[i].Calls = append(out.Methods[i].Calls, rangeForPos(lp.fset, node.Pos(), node.End()))
+				}
+			case *ast.TypeAssertExpr:
+				if node.Type != nil && passes.ExprObject(node.X, lp.info) == v {
+					assertion(node.Type, false)
+				}
+			case *ast.TypeSwitchStmt:
+				if passes.ExprObject(typeSwitchOperand(node), lp.info) != v {
+					return true
+				}
+				for _, stmt := range node.Body.List {
+					for _, expr := range stmt.(*ast.CaseClause).List {
+						assertion(expr, true)
+					}
+				}
+			}
+			return true
+		})
+	}
+	for _, m := range out.Methods {
+		if len(m.Calls) == 0 {
+			out.Uncalled = append(out.Uncalled, m.Name)
+		}
+	}
+	sort.SliceStable(out.Assertions, func(i, j int) bool {
+		return rangeLess(out.Assertions[i].Range, out.Assertions[j].Range)
+	})
+	return out
+}
+
+// typeSwitchOperand returns x in `switch x.(type)` and `switch y :=
+// x.(type)`.
+func typeSwitchOperand(ts *ast.TypeSwitchStmt) ast.Expr {
+	switch s := ts.Assign.(type) {
+	case *ast.ExprStmt:
+		if ta, ok := s.X.(*ast.TypeAssertExpr); ok {
+			return ta.X
+		}
+	case *ast.AssignStmt:
+		return typeSwitchSubject(ts)
+	}
+	return nil
+}
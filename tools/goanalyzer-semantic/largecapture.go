@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+
+	"goanalyzer-semantic/passes"
+)
+
+// largeCaptureAnalyzer flags `go func() {...}()` literals that capture a
+// local variable of at least passes.DefaultLargeValueSize bytes, or the
+// configured size.
+// The variable moves to the heap and stays alive for as long as the
+// goroutine runs; passing the needed parts as arguments keeps it on the
+// stack.
+var largeCaptureAnalyzer = &analyzer{
+	name:     "largecapture",
+	code:     "GA205",
+	summary:  "Goroutine captures a large local variable",
+	severity: "info",
+	report:   "retention",
+	run:      runLargeCapture,
+}
+
+func runLargeCapture(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := passes.Unparen(gs.Call.Fun).(*ast.FuncLit)
+		if !ok || lit.Body == nil {
+			return true
+		}
+		reported := make(map[*types.Var]bool)
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			v, ok := p.info.Uses[id].(*types.Var)
+			if !ok || reported[v] || v.Parent() == p.pkg.Scope() || (v.Pos() >= lit.Pos() && v.Pos() < lit.End()) {
+				return true
+			}
+			if !p.IsLarge(v.Type()) {
+				return true
+			}
+			reported[v] = true
+			finding := Finding{
+				Message: "goroutine captures " + v.Name() + " (" + strconv.FormatInt(*passes.SizeOf(v.Type()), 10) + " bytes), keeping it alive while the goroutine runs",
+				Range:   p.rangeForNode(id),
+			}
+			if decl, ok := p.objectRange(v); ok {
+				finding.Related = []RelatedRange{{Range: decl, Message: v.Name() + " is declared here"}}
+			}
+			findings = append(findings, finding)
+			return true
+		})
+		return true
+	})
+	return findings
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// lifetimeGap is the number of statements from which the distance between a
+// declaration and its first use, or after a variable's last use, is worth
+// a hint.
+const lifetimeGap = 5
+
+// LifetimeOutput is the response of "lifetime" mode.
+type LifetimeOutput struct {
+	// Function is "f" for functions and "T.m" for methods.
+	Function  string        `json:"function"`
+	Variables []VarLifetime `json:"variables"`
+}
+
+// VarLifetime describes how long a local variable of the function is live.
+type VarLifetime struct {
+	Name     string `json:"name"`
+	Decl     Range  `json:"decl"`
+	FirstUse *Range `json:"first_use,omitempty"`
+	LastUse  *Range `json:"last_use,omitempty"`
+	// LiveUntil is the empty range where the variable stops being live:
+	// the end of its last use, of the outermost loop containing a use, or
+	// of the function when Extended is set.
+	LiveUntil Range `json:"live_until"`
+	// Extended is "captured", "address" or "returned" when the variable
+	// may outlive its last use, or "loop" when a use inside a loop keeps it
+	// live until the loop ends.
+	Extended string `json:"extended,omitempty"`
+	// Gap is "declared_far" when at least lifetimeGap statements separate
+	// the declaration from the first use, "dead_tail" when as many follow
+	// the end of the lifetime in the variable's scope, or both
+	// comma-separated.
+	Gap string `json:"gap,omitempty"`
+}
+
+func (o *LifetimeOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Variables {
+		v := &o.Variables[i]
+		f(&v.Decl)
+		if v.FirstUse != nil {
+			f(v.FirstUse)
+		}
+		if v.LastUse != nil {
+			f(v.LastUse)
+		}
+		f(&v.LiveUntil)
+	}
+}
+
+// lifetime reports the local variables of the function enclosing Line/Col.
+// Parameters, results and receivers live for the whole call and are not
+// listed.
+func lifetime(in Input) *LifetimeOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	fd := funcDeclAt(lp, in.Line, in.Col)
+	if fd == nil || fd.Body == nil {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+
+	var stmts []ast.Stmt
+	uses := make(map[*types.Var][]*ast.Ident)
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+		case ast.Stmt:
+			stmts = append(stmts, node)
+		case *ast.Ident:
+			if v, ok := lp.info.Uses[node].(*types.Var); ok {
+				uses[v] = append(uses[v], node)
+			}
+		}
+		return true
+	})
+	between := func(from, to token.Pos) int {
+		count := 0
+		for _, s := range stmts {
+			if s.Pos() > from && s.Pos() < to {
+				count++
+			}
+		}
+		return count
+	}
+
+	out := &LifetimeOutput{Function: callerName(fd.Name, parents), Variables: make([]VarLifetime, 0)}
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			return true
+		}
+		v, ok := lp.info.Defs[id].(*types.Var)
+		if !ok || v.IsField() || v.Parent() == nil {
+			return true
+		}
+		vl := VarLifetime{Name: id.Name, Decl: rangeForIdent(lp.fset, id)}
+		end := id.End()
+		list := uses[v]
+		if len(list) > 0 {
+			first, last := list[0], list[len(list)-1]
+			firstRange, lastRange := rangeForIdent(lp.fset, first), rangeForIdent(lp.fset, last)
+			vl.FirstUse, vl.LastUse = &firstRange, &lastRange
+			end = last.End()
+			if between(id.End(), first.Pos()) >= lifetimeGap {
+				vl.Gap = "declared_far"
+			}
+		}
+		for _, use := range list {
+			if reason := escapeReason(use, id, parents); reason != "" {
+				vl.Extended = reason
+				end = fd.Body.Rbrace
+				break
+			}
+			if loop := outermostLoop(use, id, parents); loop != nil && loop.End() > end {
+				vl.Extended = "loop"
+				end = loop.End()
+			}
+		}
+		pos := lp.fset.Position(end)
+		vl.LiveUntil = Range{File: pos.Filename, Start: Pos{Line: pos.Line - 1, Col: pos.Column - 1}}
+		vl.LiveUntil.End = vl.LiveUntil.Start
+		if scope := v.Parent(); len(list) > 0 && between(end, scope.End()) >= lifetimeGap {
+			if vl.Gap != "" {
+				vl.Gap += ","
+			}
+			vl.Gap += "dead_tail"
+		}
+		out.Variables = append(out.Variables, vl)
+		return true
+	})
+	sort.SliceStable(out.Variables, func(i, j int) bool {
+		return rangeLess(out.Variables[i].Decl, out.Variables[j].Decl)
+	})
+	return out
+}
+
+// escapeReason reports why use may keep the variable declared by decl alive
+// past the use: "captured" inside a function literal declared after it,
+// "address" when its address is taken, or "returned".
+func escapeReason(use, decl *ast.Ident, parents map[ast.Node]ast.Node) string {
+	var expr ast.Node = use
+	for {
+		p, ok := parents[expr].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = p
+	}
+	switch p := parents[expr].(type) {
+	case *ast.UnaryExpr:
+		if p.Op == token.AND {
+			return "address"
+		}
+	case *ast.ReturnStmt:
+		return "returned"
+	}
+	for n := parents[use]; n != nil; n = parents[n] {
+		switch fn := n.(type) {
+		case *ast.FuncLit:
+			if decl.Pos() < fn.Pos() || decl.Pos() >= fn.End() {
+				return "captured"
+			}
+		case *ast.FuncDecl:
+			return ""
+		}
+	}
+	return ""
+}
+
+// outermostLoop returns the outermost for or range statement that contains
+// use but not the declaration decl.
+func outermostLoop(use, decl *ast.Ident, parents map[ast.Node]ast.Node) ast.Node {
+	var loop ast.Node
+	for n := parents[use]; n != nil; n = parents[n] {
+		if n.Pos() <= decl.Pos() && decl.Pos() < n.End() {
+			break
+		}
+		switch n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			loop = n
+		}
+	}
+	return loop
+}
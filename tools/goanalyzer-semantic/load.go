@@ -0,0 +1,343 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadDiagnostic explains why a file of the package was left out of (or is
+// only partially covered by) the analysis. Reason is one of "parse_error",
+// "build_constraints" or "cgo"; Range marks the first error when known.
+type LoadDiagnostic struct {
+	File    string `json:"file"`
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+	Range   *Range `json:"range,omitempty"`
+}
+
+type loadedPackage struct {
+	fset        *token.FileSet
+	file        *ast.File
+	files       []*ast.File
+	pkg         *types.Package
+	info        *types.Info
+	diagnostics []LoadDiagnostic
+	// degraded is set when conflicting declarations remained after
+	// dropping platform-specific files, so results may be incomplete.
+	degraded bool
+	// syntaxOnly is set when no import could be loaded; queries then fall
+	// back to the parser's object resolution instead of type information.
+	syntaxOnly bool
+	// targetSpecific is set when the target has build constraints, so
+	// which other files were kept depends on it and the package cannot
+	// serve requests for those files.
+	targetSpecific bool
+}
+
+func loadPackage(in Input) *loadedPackage {
+	if in.File == "" {
+		return nil
+	}
+
+	filePath := in.File
+	if abs, err := filepath.Abs(filePath); err == nil {
+		filePath = abs
+	}
+	filePath = filepath.Clean(filePath)
+	overlays := packageOverlays(in, filePath)
+	return packageCache.load(filePath, overlays, func() *loadedPackage {
+		return loadPackageFiles(filePath, overlays)
+	})
+}
+
+// packageOverlays returns the unsaved text of the files in the directory
+// of target, keyed by absolute path: in.Overlays for the files there and
+// in.Content for target itself. Empty texts stand for the file on disk.
+func packageOverlays(in Input, target string) map[string]string {
+	dir := filepath.Dir(target)
+	overlays := make(map[string]string)
+	for path, text := range in.Overlays {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		path = filepath.Clean(path)
+		if text != "" && filepath.Dir(path) == dir {
+			overlays[path] = text
+		}
+	}
+	if in.Content != "" {
+		overlays[target] = in.Content
+	}
+	return overlays
+}
+
+// loadPackageFiles parses and type-checks the package of filePath, using
+// the text in overlays instead of the files on disk where there is one.
+func loadPackageFiles(filePath string, overlays map[string]string) *loadedPackage {
+	fset := token.NewFileSet()
+	file, files, diags := parsePackageFiles(fset, filePath, overlays)
+	if file == nil || len(files) == 0 {
+		return nil
+	}
+
+	res := checkPackage(fset, file, files)
+	degraded := false
+	if hasConflictingDecls(files) {
+		// Conflicting declarations usually come from files for another
+		// platform, e.g. when the target itself is impl_windows.go on a
+		// Linux host. Retry with the target and unconstrained files only.
+		var kept []*ast.File
+		for _, f := range files {
+			if f == file || !hasBuildConstraints(fset, f) {
+				kept = append(kept, f)
+				continue
+			}
+			diags = append(diags, LoadDiagnostic{
+				File:    fset.Position(f.Pos()).Filename,
+				Reason:  "build_constraints",
+				Message: "dropped to resolve conflicting declarations",
+			})
+		}
+		if len(kept) < len(files) {
+			files = kept
+			res = checkPackage(fset, file, files)
+		}
+		degraded = hasConflictingDecls(files)
+	}
+	return &loadedPackage{
+		fset:           fset,
+		file:           file,
+		files:          files,
+		pkg:            res.pkg,
+		info:           res.info,
+		diagnostics:    diags,
+		degraded:       degraded || res.importsFailed,
+		syntaxOnly:     res.importsFailed,
+		targetSpecific: hasBuildConstraints(fset, file),
+	}
+}
+
+type checkResult struct {
+	pkg  *types.Package
+	info *types.Info
+	// importsFailed is set when the package has imports and none of them
+	// could be loaded, typically because GOROOT is unusable.
+	importsFailed bool
+}
+
+func checkPackage(fset *token.FileSet, file *ast.File, files []*ast.File) *checkResult {
+	info := &types.Info{
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Instances:  make(map[*ast.Ident]types.Instance),
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Implicits:  make(map[ast.Node]types.Object),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	res := &checkResult{info: info}
+	imp := newVendorImporter(fset)
+	config := &types.Config{
+		Importer: imp,
+		Error:    func(error) {},
+	}
+	res.pkg, _ = config.Check(file.Name.Name, fset, files, info)
+	res.importsFailed = imp.failed > 0 && imp.loaded == 0
+	return res
+}
+
+// hasConflictingDecls reports whether two package-level declarations of
+// files share a name, or two methods share a receiver type and name, which
+// the checker rejects as redeclarations. Blank identifiers and init
+// functions may repeat.
+func hasConflictingDecls(files []*ast.File) bool {
+	seen := make(map[string]bool)
+	declare := func(name string) bool {
+		if seen[name] {
+			return true
+		}
+		seen[name] = true
+		return false
+	}
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				name := decl.Name.Name
+				if decl.Recv != nil && len(decl.Recv.List) > 0 {
+					name = receiverTypeName(decl.Recv.List[0].Type) + "." + name
+				} else if name == "init" {
+					continue
+				}
+				if name != "_" && declare(name) {
+					return true
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						for _, id := range spec.Names {
+							if id.Name != "_" && declare(id.Name) {
+								return true
+							}
+						}
+					case *ast.TypeSpec:
+						if spec.Name.Name != "_" && declare(spec.Name.Name) {
+							return true
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasBuildConstraints reports whether f is restricted to some platforms,
+// either by a //go:build or // +build line or by a GOOS/GOARCH file name
+// suffix.
+func hasBuildConstraints(fset *token.FileSet, f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if constraint.IsGoBuild(c.Text) || constraint.IsPlusBuild(c.Text) {
+				return true
+			}
+		}
+	}
+	path := fset.Position(f.Pos()).Filename
+	ctx := build.Default
+	ctx.GOOS, ctx.GOARCH = "none", "none"
+	match, err := ctx.MatchFile(filepath.Dir(path), filepath.Base(path))
+	return err == nil && !match
+}
+
+// parsePackageFiles parses the target file and the files of the same
+// package in its directory, taking the text of a file from overlays when
+// it has one; overlays of files not yet saved join the package as well.
+// Files excluded by build constraints or failing to parse are skipped
+// individually and reported as load diagnostics, so one broken file does
+// not reduce the whole request to single-file mode.
+func parsePackageFiles(fset *token.FileSet, targetFile string, overlays map[string]string) (*ast.File, []*ast.File, []LoadDiagnostic) {
+	targetFile = filepath.Clean(targetFile)
+	target, targetFiles := parseSingleFile(fset, targetFile, overlays[targetFile])
+	if target == nil {
+		return nil, nil, nil
+	}
+	dir := filepath.Dir(targetFile)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return target, targetFiles, nil
+	}
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasSuffix(name, ".go") {
+			names[name] = true
+		}
+	}
+	for path := range overlays {
+		if name := filepath.Base(path); filepath.Dir(path) == dir && strings.HasSuffix(name, ".go") {
+			names[name] = true
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	files := targetFiles
+	var diags []LoadDiagnostic
+	if importsC(target) {
+		diags = append(diags, LoadDiagnostic{File: targetFile, Reason: "cgo", Message: "cgo declarations from import \"C\" are not resolved"})
+	}
+	ctx := build.Default
+	ctx.OpenFile = func(path string) (io.ReadCloser, error) {
+		if text, ok := overlays[filepath.Clean(path)]; ok {
+			return io.NopCloser(strings.NewReader(text)), nil
+		}
+		return os.Open(path)
+	}
+	for _, name := range sorted {
+		path := filepath.Join(dir, name)
+		if path == targetFile {
+			continue
+		}
+		// MatchFile evaluates //go:build lines and, in files without one,
+		// legacy // +build lines, the same way the go command does.
+		if match, err := ctx.MatchFile(dir, name); err == nil && !match {
+			diags = append(diags, LoadDiagnostic{File: path, Reason: "build_constraints", Message: "excluded by build constraints or file name"})
+			continue
+		}
+		var src interface{}
+		if text, ok := overlays[path]; ok {
+			src = text
+		}
+		f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+		if err != nil {
+			diags = append(diags, parseDiagnostic(path, err))
+			continue
+		}
+		if f.Name.Name != target.Name.Name {
+			continue
+		}
+		if importsC(f) {
+			diags = append(diags, LoadDiagnostic{File: path, Reason: "cgo", Message: "cgo declarations from import \"C\" are not resolved"})
+		}
+		files = append(files, f)
+	}
+
+	return target, files, diags
+}
+
+func parseDiagnostic(path string, err error) LoadDiagnostic {
+	diag := LoadDiagnostic{File: path, Reason: "parse_error", Message: err.Error()}
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		first := list[0]
+		diag.Message = first.Msg
+		if len(list) > 1 {
+			diag.Message += " (and " + strconv.Itoa(len(list)-1) + " more errors)"
+		}
+		pos := Pos{Line: first.Pos.Line - 1, Col: first.Pos.Column - 1}
+		diag.Range = &Range{File: path, Start: pos, End: pos}
+	}
+	return diag
+}
+
+func importsC(f *ast.File) bool {
+	for _, imp := range f.Imports {
+		if imp.Path != nil && imp.Path.Value == `"C"` {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSingleFile(fset *token.FileSet, targetFile string, content string) (*ast.File, []*ast.File) {
+	var (
+		file *ast.File
+		err  error
+	)
+	if content != "" {
+		file, err = parser.ParseFile(fset, targetFile, content, parser.ParseComments)
+	} else {
+		file, err = parser.ParseFile(fset, targetFile, nil, parser.ParseComments)
+	}
+	if err != nil || file == nil {
+		return nil, nil
+	}
+	return file, []*ast.File{file}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// localPointerAnalyzer flags functions that return the address of one of
+// their local variables. The variable escapes to the heap and lives as long
+// as the returned pointer; for large values this is often unintended.
+// Composite literals such as &T{...} are the usual constructor idiom and
+// are not reported.
+var localPointerAnalyzer = &analyzer{
+	name:     "localptr",
+	code:     "GA206",
+	summary:  "Function returns the address of a local variable",
+	severity: "info",
+	report:   "retention",
+	run:      runLocalPointer,
+}
+
+func runLocalPointer(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		ret, ok := n.(*ast.ReturnStmt)
+		if !ok {
+			return true
+		}
+		for _, result := range ret.Results {
+			addr, ok := passes.Unparen(result).(*ast.UnaryExpr)
+			if !ok || addr.Op != token.AND {
+				continue
+			}
+			id, ok := passes.Unparen(addr.X).(*ast.Ident)
+			if !ok {
+				continue
+			}
+			v, ok := p.info.Uses[id].(*types.Var)
+			if !ok || v.Parent() == p.pkg.Scope() {
+				continue
+			}
+			finding := Finding{
+				Message: "returning &" + v.Name() + " moves the local variable to the heap for as long as the pointer lives",
+				Range:   p.rangeForNode(addr),
+			}
+			if decl, ok := p.objectRange(v); ok {
+				finding.Related = []RelatedRange{{Range: decl, Message: v.Name() + " is declared here"}}
+			}
+			findings = append(findings, finding)
+		}
+		return true
+	})
+	return findings
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// lockBlockAnalyzer flags operations that can block indefinitely while a
+// mutex is held: channel sends and receives, selects without a default,
+// ranging over a channel, and calls to known blocking functions such as
+// time.Sleep, WaitGroup.Wait and network or file I/O. Every other goroutine
+// that needs the lock stalls for as long as the operation does. CPU-bound
+// work under a lock is not reported.
+var lockBlockAnalyzer = &analyzer{
+	name:     "lockblock",
+	code:     "GA301",
+	summary:  "Blocking operation while a mutex is held",
+	severity: "warning",
+	run:      runLockBlock,
+}
+
+// blockingFuncs lists blocking functions and methods by package path,
+// receiver type name (empty for functions) and name.
+var blockingFuncs = map[string]bool{
+	"time.Sleep":           true,
+	"sync.WaitGroup.Wait":  true,
+	"io.Copy":              true,
+	"io.CopyN":             true,
+	"io.ReadAll":           true,
+	"io.ReadFull":          true,
+	"io.Reader.Read":       true,
+	"io.Writer.Write":      true,
+	"os.ReadFile":          true,
+	"os.WriteFile":         true,
+	"os.File.Read":         true,
+	"os.File.Write":        true,
+	"os.File.Sync":         true,
+	"os/exec.Cmd.Run":      true,
+	"os/exec.Cmd.Wait":     true,
+	"os/exec.Cmd.Output":   true,
+	"net.Dial":             true,
+	"net.DialTimeout":      true,
+	"net.Conn.Read":        true,
+	"net.Conn.Write":       true,
+	"net.Listener.Accept":  true,
+	"net/http.Get":         true,
+	"net/http.Post":        true,
+	"net/http.Head":        true,
+	"net/http.Client.Do":   true,
+	"net/http.Client.Get":  true,
+	"net/http.Client.Post": true,
+}
+
+// isBlocking reports whether fn is in blockingFuncs or the configured
+// blocking functions.
+func (p *pass) isBlocking(fn *types.Func) bool {
+	key := funcKey(fn)
+	if blockingFuncs[key] {
+		return true
+	}
+	for _, name := range p.config.BlockingFuncs {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+func runLockBlock(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		var what string
+		switch node := n.(type) {
+		case *ast.SendStmt:
+			if !p.inSelectCase(node) {
+				what = "channel send"
+			}
+		case *ast.UnaryExpr:
+			if node.Op == token.ARROW && !p.inSelectCase(node) {
+				what = "channel receive"
+			}
+		case *ast.SelectStmt:
+			if !hasDefaultCase(node) {
+				what = "select without default"
+			}
+		case *ast.RangeStmt:
+			if t := p.info.TypeOf(node.X); t != nil {
+				if _, ok := t.Underlying().(*types.Chan); ok {
+					what = "range over channel"
+				}
+			}
+		case *ast.CallExpr:
+			if fn := passes.CalledFunc(node, p.info); fn != nil && p.isBlocking(fn) {
+				what = "call to " + funcKey(fn)
+			}
+		}
+		if what == "" {
+			return true
+		}
+		held := passes.HeldLockCalls(n, p.parents, p.info)
+		if len(held) == 0 {
+			return true
+		}
+		related := make([]RelatedRange, 0, len(held))
+		for obj, call := range held {
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(call),
+				Message: obj.Name() + " locked here",
+			})
+		}
+		sort.Slice(related, func(i, j int) bool {
+			return rangeLess(related[i].Range, related[j].Range)
+		})
+		node := n
+		if rs, ok := n.(*ast.RangeStmt); ok {
+			node = rs.X
+		}
+		findings = append(findings, Finding{
+			Message: what + " while holding a lock can stall every goroutine waiting for it",
+			Range:   p.rangeForNode(node),
+			Related: related,
+		})
+		return true
+	})
+	return findings
+}
+
+// inSelectCase reports whether n is part of the communication of a select
+// case; the select statement itself decides whether that blocks.
+func (p *pass) inSelectCase(n ast.Node) bool {
+	child := n
+	for cur := p.parents[n]; cur != nil; cur = p.parents[cur] {
+		if cc, ok := cur.(*ast.CommClause); ok {
+			return cc.Comm == child
+		}
+		if _, ok := cur.(ast.Stmt); ok {
+			if _, ok := cur.(*ast.ExprStmt); !ok {
+				if _, ok := cur.(*ast.AssignStmt); !ok {
+					return false
+				}
+			}
+		}
+		child = cur
+	}
+	return false
+}
+
+func hasDefaultCase(sel *ast.SelectStmt) bool {
+	for _, stmt := range sel.Body.List {
+		if cc, ok := stmt.(*ast.CommClause); ok && cc.Comm == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// funcKey names fn as "pkgpath.Name" or "pkgpath.Type.Name" for methods.
+func funcKey(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return fn.Name()
+	}
+	key := fn.Pkg().Path() + "."
+	if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+		if named := receiverNamed(sig.Recv().Type()); named != nil {
+			key += named.Obj().Name() + "."
+		}
+	}
+	return key + fn.Name()
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// LockReportOutput is the response of "lock_report" mode: what the mutex at
+// Line/Col guards, inferred from the accesses made while it is held.
+type LockReportOutput struct {
+	Name string `json:"name"`
+	Decl Range  `json:"decl"`
+	// Protects names the fields and package-level variables accessed at
+	// least once while the mutex is held.
+	Protects []string `json:"protects"`
+	// Members has an entry for every name in Protects and, for a mutex
+	// field, for every other field of its struct.
+	Members []LockMember `json:"members"`
+	// Acquirers are the functions that call Lock or RLock on the mutex,
+	// "T.m" for methods.
+	Acquirers []string `json:"acquirers"`
+	// MaxCriticalSection is the largest number of statements run while one
+	// Lock or RLock call holds the mutex.
+	MaxCriticalSection int `json:"max_critical_section"`
+}
+
+// LockMember counts the accesses of one field or variable inside and
+// outside the mutex's critical sections; sync/atomic accesses are counted
+// apart. Status is "guarded" when every plain access holds the lock,
+// "partial" when only some do and "unguarded" when none do.
+type LockMember struct {
+	Name    string `json:"name"`
+	Decl    Range  `json:"decl"`
+	Inside  int    `json:"inside"`
+	Outside int    `json:"outside"`
+	Atomic  int    `json:"atomic,omitempty"`
+	Status  string `json:"status"`
+	// SplitWith names other mutexes held, without this one, at some access
+	// of a member that is also accessed under this one.
+	SplitWith []string `json:"split_with,omitempty"`
+}
+
+func (o *LockReportOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Members {
+		f(&o.Members[i].Decl)
+	}
+}
+
+type lockMemberState struct {
+	obj    types.Object
+	member LockMember
+	split  map[types.Object]bool
+}
+
+// lockReport relates the mutex field or variable at Line/Col to the fields
+// and package-level variables of the package accessed while it is held. A
+// mutex that is never locked is not reported.
+func lockReport(in Input) *LockReportOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	mu, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.Var)
+	if !ok {
+		return nil
+	}
+
+	members := make(map[types.Object]*lockMemberState)
+	member := func(obj types.Object) *lockMemberState {
+		m := members[obj]
+		if m == nil {
+			m = &lockMemberState{obj: obj, member: LockMember{Name: obj.Name()}, split: make(map[types.Object]bool)}
+			m.member.Decl, _ = lp.objectRange(obj)
+			members[obj] = m
+		}
+		return m
+	}
+	count := func(obj types.Object, locks map[types.Object]bool, atomic bool) {
+		m := member(obj)
+		switch {
+		case atomic:
+			m.member.Atomic++
+		case locks[mu]:
+			m.member.Inside++
+		default:
+			m.member.Outside++
+			for lock := range locks {
+				m.split[lock] = true
+			}
+		}
+	}
+
+	acquirers := make(map[string]bool)
+	sections := make(map[*ast.CallExpr]int)
+	for _, file := range lp.files {
+		p := newPass(lp, file, Config{})
+		for field, accesses := range p.FieldAccesses() {
+			for _, a := range accesses {
+				count(field, a.Locks, a.Atomic)
+			}
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.Ident:
+				v, ok := p.info.Uses[node].(*types.Var)
+				if ok && v != mu && v.Parent() == p.pkg.Scope() {
+					count(v, passes.HeldLocks(node, p.parents, p.info), false)
+				}
+			case *ast.CallExpr:
+				if obj, method := passes.LockCall(node, p.info); obj == mu && (method == "Lock" || method == "RLock") {
+					acquirers[callerName(node, p.parents)] = true
+				}
+			}
+			switch n.(type) {
+			case nil, *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			case ast.Stmt:
+				if call := passes.HeldLockCalls(n, p.parents, p.info)[mu]; call != nil {
+					sections[call]++
+				}
+			}
+			return true
+		})
+	}
+	if len(acquirers) == 0 {
+		return nil
+	}
+
+	out := &LockReportOutput{Name: mu.Name(), Protects: make([]string, 0), Members: make([]LockMember, 0), Acquirers: make([]string, 0, len(acquirers))}
+	out.Decl, _ = lp.objectRange(mu)
+	for name := range acquirers {
+		out.Acquirers = append(out.Acquirers, name)
+	}
+	sort.Strings(out.Acquirers)
+	for _, n := range sections {
+		if n > out.MaxCriticalSection {
+			out.MaxCriticalSection = n
+		}
+	}
+
+	var list []*lockMemberState
+	for _, m := range members {
+		if m.member.Inside > 0 {
+			list = append(list, m)
+		}
+	}
+	if mu.IsField() {
+		for _, sibling := range structFieldsOf(lp.pkg, mu) {
+			if sibling != mu && (members[sibling] == nil || members[sibling].member.Inside == 0) {
+				list = append(list, member(sibling))
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].obj.Pos() < list[j].obj.Pos() })
+	for _, m := range list {
+		switch {
+		case m.member.Inside == 0:
+			m.member.Status = "unguarded"
+		case m.member.Outside == 0:
+			m.member.Status = "guarded"
+			out.Protects = append(out.Protects, m.member.Name)
+		default:
+			m.member.Status = "partial"
+			out.Protects = append(out.Protects, m.member.Name)
+		}
+		if m.member.Inside > 0 {
+			for lock := range m.split {
+				m.member.SplitWith = append(m.member.SplitWith, lock.Name())
+			}
+			sort.Strings(m.member.SplitWith)
+		}
+		out.Members = append(out.Members, m.member)
+	}
+	return out
+}
+
+// structFieldsOf returns the fields of the package-level struct type that
+// declares field, or nil when there is none.
+func structFieldsOf(pkg *types.Package, field *types.Var) []*types.Var {
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		var fields []*types.Var
+		found := false
+		for i := 0; i < st.NumFields(); i++ {
+			fields = append(fields, st.Field(i))
+			found = found || st.Field(i) == field
+		}
+		if found {
+			return fields
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// loopInvariantAnalyzer flags calls in a loop body that compute the same
+// value on every iteration and are costly enough to be worth hoisting, such
+// as compiling a regular expression. It is deliberately narrow: only calls
+// to the functions in hoistableFuncs are considered, and only when every
+// argument is a literal, a constant, another such call or a local variable
+// of basic type that is declared before the loop and never written or
+// addressed inside it. Calls in nested loops and function literals are
+// judged against their own loop.
+var loopInvariantAnalyzer = &analyzer{
+	name:     "loopinvariant",
+	code:     "GA310",
+	summary:  "Loop-invariant call recomputed on every iteration",
+	severity: "info",
+	run:      runLoopInvariant,
+}
+
+// hoistableFuncs are standard library functions without side effects whose
+// result depends only on their arguments, keyed by package path and name.
+var hoistableFuncs = map[string]bool{
+	"regexp.Compile":          true,
+	"regexp.MustCompile":      true,
+	"regexp.CompilePOSIX":     true,
+	"regexp.MustCompilePOSIX": true,
+	"regexp.QuoteMeta":        true,
+	"strings.Fields":          true,
+	"strings.NewReplacer":     true,
+	"strings.Repeat":          true,
+	"strings.Replace":         true,
+	"strings.ReplaceAll":      true,
+	"strings.Split":           true,
+	"strings.ToLower":         true,
+	"strings.ToUpper":         true,
+	"strings.TrimSpace":       true,
+	"strconv.Atoi":            true,
+	"strconv.FormatFloat":     true,
+	"strconv.FormatInt":       true,
+	"strconv.Itoa":            true,
+	"strconv.ParseFloat":      true,
+	"strconv.ParseInt":        true,
+	"strconv.Quote":           true,
+	"fmt.Sprint":              true,
+	"fmt.Sprintf":             true,
+	"math.Exp":                true,
+	"math.Log":                true,
+	"math.Pow":                true,
+	"math.Sqrt":               true,
+	"path.Join":               true,
+	"path/filepath.Join":      true,
+	"time.LoadLocation":       true,
+	"time.ParseDuration":      true,
+}
+
+func runLoopInvariant(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		var body *ast.BlockStmt
+		switch loop := n.(type) {
+		case *ast.ForStmt:
+			body = loop.Body
+		case *ast.RangeStmt:
+			body = loop.Body
+		default:
+			return true
+		}
+		ast.Inspect(body, func(m ast.Node) bool {
+			switch node := m.(type) {
+			case *ast.FuncLit, *ast.ForStmt, *ast.RangeStmt:
+				return false
+			case *ast.CallExpr:
+				if !p.loopInvariantCall(node, n) {
+					return true
+				}
+				fn := passes.CalledFunc(node, p.info)
+				findings = append(findings, Finding{
+					Message: fn.Pkg().Name() + "." + fn.Name() + " is called with the same arguments on every iteration; hoist it out of the loop",
+					Range:   p.rangeForNode(node),
+					Related: []RelatedRange{{Range: p.rangeForNode(n), Message: "enclosing loop"}},
+				})
+				return false
+			}
+			return true
+		})
+		return true
+	})
+	return findings
+}
+
+// loopInvariantCall reports whether call is a hoistable call whose
+// arguments do not change across the iterations of loop.
+func (p *pass) loopInvariantCall(call *ast.CallExpr, loop ast.Node) bool {
+	fn := passes.CalledFunc(call, p.info)
+	if fn == nil || fn.Pkg() == nil || fn.Type().(*types.Signature).Recv() != nil || !hoistableFuncs[fn.Pkg().Path()+"."+fn.Name()] {
+		return false
+	}
+	for _, arg := range call.Args {
+		if !p.loopInvariantExpr(arg, loop) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *pass) loopInvariantExpr(expr ast.Expr, loop ast.Node) bool {
+	switch e := passes.Unparen(expr).(type) {
+	case *ast.BasicLit:
+		return true
+	case *ast.Ident:
+		switch obj := p.info.Uses[e].(type) {
+		case *types.Const:
+			return true
+		case *types.Var:
+			if _, ok := obj.Type().Underlying().(*types.Basic); !ok || obj.Pkg() == nil || obj.Parent() == obj.Pkg().Scope() {
+				return false
+			}
+			return (obj.Pos() < loop.Pos() || obj.Pos() >= loop.End()) && !writesVar(loop, obj, p.info)
+		}
+	case *ast.SelectorExpr:
+		_, ok := p.info.Uses[e.Sel].(*types.Const)
+		return ok
+	case *ast.BinaryExpr:
+		return p.loopInvariantExpr(e.X, loop) && p.loopInvariantExpr(e.Y, loop)
+	case *ast.UnaryExpr:
+		return (e.Op == token.SUB || e.Op == token.NOT) && p.loopInvariantExpr(e.X, loop)
+	case *ast.CallExpr:
+		return p.loopInvariantCall(e, loop)
+	}
+	return false
+}
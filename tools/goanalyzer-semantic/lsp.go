@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSON-RPC error codes used by the language server.
+const (
+	lspInvalidRequest = -32600
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+)
+
+// lspServer is a minimal Language Server speaking over a stream with
+// Content-Length framing. It answers documentHighlight, definition and
+// hover from the resolve, "definition" and "hover" modes, keeping the text
+// of open documents as overlays for the package being queried. Documents are
+// synchronized incrementally: didChange carries ranged edits, applied in
+// order to the overlay.
+type lspServer struct {
+	in  *bufio.Reader
+	out io.Writer
+	// encoding is the negotiated position encoding: "utf-16" unless the
+	// client offers "utf-8" or "utf-32".
+	encoding string
+	overlays map[string]string
+	// versions holds the version of each open document, so that changes
+	// arriving out of order are rejected instead of corrupting it.
+	versions map[string]int
+	shutdown bool
+}
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+// Document highlight kinds.
+const (
+	lspHighlightRead  = 2
+	lspHighlightWrite = 3
+)
+
+type lspHighlight struct {
+	Range lspRange `json:"range"`
+	Kind  int      `json:"kind"`
+}
+
+// serveLSP runs the language server until the client sends exit or closes
+// the stream, and returns the process exit code: 0 when shutdown preceded
+// exit, 1 otherwise.
+func serveLSP(r io.Reader, w io.Writer) int {
+	s := &lspServer{in: bufio.NewReader(r), out: w, encoding: "utf-16", overlays: make(map[string]string), versions: make(map[string]int)}
+	for {
+		msg, err := s.read()
+		if err != nil {
+			return 1
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return 0
+			}
+			return 1
+		}
+		result, rpcErr := s.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		reply := lspMessage{JSONRPC: "2.0", ID: msg.ID, Error: rpcErr}
+		if rpcErr == nil {
+			reply.Result = nullable(result)
+		}
+		if s.write(reply) != nil {
+			return 1
+		}
+	}
+}
+
+// nullable makes a nil result encode as null, which the protocol requires
+// for empty responses, instead of being dropped as omitempty.
+func nullable(v interface{}) interface{} {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	return v
+}
+
+func (s *lspServer) read() (*lspMessage, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("bad Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var msg lspMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *lspServer) write(msg lspMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// handle answers one message. Notifications get their result discarded,
+// and unknown notifications are ignored as the protocol requires.
+func (s *lspServer) handle(msg *lspMessage) (interface{}, *lspError) {
+	if s.shutdown && msg.Method != "exit" {
+		return nil, &lspError{Code: lspInvalidRequest, Message: "server is shut down"}
+	}
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(msg.Params)
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		path := uriToPath(p.TextDocument.URI)
+		s.overlays[path] = p.TextDocument.Text
+		s.versions[path] = p.TextDocument.Version
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Range *lspRange `json:"range"`
+				Text  string    `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		path := uriToPath(p.TextDocument.URI)
+		text, ok := s.overlays[path]
+		if !ok {
+			return nil, &lspError{Code: lspInvalidParams, Message: "document is not open: " + p.TextDocument.URI}
+		}
+		if p.TextDocument.Version <= s.versions[path] {
+			return nil, &lspError{Code: lspInvalidParams, Message: fmt.Sprintf("version %d of %s is not newer than %d", p.TextDocument.Version, p.TextDocument.URI, s.versions[path])}
+		}
+		// A change without a range replaces the whole text.
+		for _, c := range p.ContentChanges {
+			if c.Range == nil {
+				text = c.Text
+				continue
+			}
+			start, end := s.offset(text, c.Range.Start), s.offset(text, c.Range.End)
+			if end < start {
+				start, end = end, start
+			}
+			text = text[:start] + c.Text + text[end:]
+		}
+		s.overlays[path] = text
+		s.versions[path] = p.TextDocument.Version
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		delete(s.overlays, uriToPath(p.TextDocument.URI))
+		delete(s.versions, uriToPath(p.TextDocument.URI))
+		return nil, nil
+	case "textDocument/documentHighlight", "textDocument/definition", "textDocument/hover":
+		var p lspPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		in := s.input(p)
+		switch msg.Method {
+		case "textDocument/documentHighlight":
+			return s.documentHighlight(in)
+		case "textDocument/definition":
+			return s.definition(in)
+		default:
+			return s.hover(in)
+		}
+	}
+	return nil, &lspError{Code: lspMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func (s *lspServer) initialize(params json.RawMessage) (interface{}, *lspError) {
+	var p struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+	}
+	// UTF-8 needs no conversion at all, and UTF-32 counts runes; UTF-16 is
+	// the protocol's mandatory default.
+	s.encoding = "utf-16"
+	for _, preferred := range []string{"utf-8", "utf-32"} {
+		if containsString(p.Capabilities.General.PositionEncodings, preferred) {
+			s.encoding = preferred
+			break
+		}
+	}
+	return map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"positionEncoding":          s.encoding,
+			"textDocumentSync":          map[string]interface{}{"openClose": true, "change": 2},
+			"documentHighlightProvider": true,
+			"definitionProvider":        true,
+			"hoverProvider":             true,
+		},
+		"serverInfo": map[string]string{"name": "goanalyzer-semantic"},
+	}, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// input builds the query for a position, converting its character offset
+// to a byte column of the document's current text.
+func (s *lspServer) input(p lspPositionParams) Input {
+	path := uriToPath(p.TextDocument.URI)
+	line := p.Position.Line
+	return Input{
+		File:     path,
+		Line:     line,
+		Col:      s.toByte(s.lineText(path, line), p.Position.Character),
+		Content:  s.overlays[path],
+		Overlays: s.overlays,
+	}
+}
+
+func (s *lspServer) documentHighlight(in Input) (interface{}, *lspError) {
+	out := resolve(in)
+	if out == nil {
+		return nil, nil
+	}
+	var highlights []lspHighlight
+	if sameFile(out.Decl.File, in.File) {
+		highlights = append(highlights, lspHighlight{Range: s.lspRange(out.Decl), Kind: lspHighlightWrite})
+	}
+	for _, u := range out.Uses {
+		if !sameFile(u.Range.File, in.File) {
+			continue
+		}
+		kind := lspHighlightRead
+		if u.Reassign {
+			kind = lspHighlightWrite
+		}
+		highlights = append(highlights, lspHighlight{Range: s.lspRange(u.Range), Kind: kind})
+	}
+	if highlights == nil {
+		return nil, nil
+	}
+	return highlights, nil
+}
+
+func (s *lspServer) definition(in Input) (interface{}, *lspError) {
+	out := definition(in)
+	if out == nil || out.Decl.File == "" {
+		return nil, nil
+	}
+	return lspLocation{URI: pathToURI(out.Decl.File), Range: s.lspRange(out.Decl)}, nil
+}
+
+func (s *lspServer) hover(in Input) (interface{}, *lspError) {
+	out := hover(in)
+	if out == nil {
+		return nil, nil
+	}
+	text := "```go\n" + out.DeclKind + " " + out.Name
+	if out.Type != "" {
+		text += " " + out.Type
+	}
+	if out.Value != "" {
+		text += " = " + out.Value
+	}
+	text += "\n```"
+	if out.Doc != "" {
+		text += "\n\n" + out.Doc
+	}
+	return map[string]interface{}{
+		"contents": map[string]string{"kind": "markdown", "value": text},
+	}, nil
+}
+
+// lspRange converts a byte-column range of the analyzer to the negotiated
+// encoding.
+func (s *lspServer) lspRange(r Range) lspRange {
+	file := r.File
+	return lspRange{
+		Start: lspPosition{Line: r.Start.Line, Character: s.fromByte(s.lineText(file, r.Start.Line), r.Start.Col)},
+		End:   lspPosition{Line: r.End.Line, Character: s.fromByte(s.lineText(file, r.End.Line), r.End.Col)},
+	}
+}
+
+// lineText returns a line of the open document for path, or of the file
+// on disk.
+func (s *lspServer) lineText(path string, line int) string {
+	src, ok := s.overlays[filepath.Clean(path)]
+	if !ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		src = string(data)
+	}
+	lines := strings.Split(src, "\n")
+	if line < 0 || line >= len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line], "\r")
+}
+
+// offset converts a position in the negotiated encoding to a byte offset
+// of text. A "\r" ending a line is not part of it, so CRLF text has the
+// same positions as LF text; characters past the end of a line and lines
+// past the end of the text stop there.
+func (s *lspServer) offset(text string, pos lspPosition) int {
+	start := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[start:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		start += i + 1
+	}
+	line := text[start:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSuffix(line, "\r")
+	col := s.toByte(line, pos.Character)
+	if col > len(line) {
+		col = len(line)
+	}
+	return start + col
+}
+
+// toByte converts a character offset in the negotiated encoding to a byte
+// column of text. Offsets past the end of the line count as bytes.
+func (s *lspServer) toByte(text string, char int) int {
+	if s.encoding == "utf-8" {
+		return char
+	}
+	units := 0
+	for i, r := range text {
+		if units >= char {
+			return i
+		}
+		units += s.runeUnits(r)
+	}
+	return len(text) + char - units
+}
+
+// fromByte converts a byte column of text to a character offset in the
+// negotiated encoding.
+func (s *lspServer) fromByte(text string, col int) int {
+	if s.encoding == "utf-8" {
+		return col
+	}
+	units := 0
+	for i := 0; i < col; {
+		if i >= len(text) {
+			return units + col - i
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		units += s.runeUnits(r)
+		i += size
+	}
+	return units
+}
+
+// runeUnits is the length of r in the negotiated encoding's code units,
+// for the encodings other than UTF-8.
+func (s *lspServer) runeUnits(r rune) int {
+	if s.encoding == "utf-16" && r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func sameFile(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// uriToPath converts a file:// URI to a cleaned local path.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return filepath.Clean(uri)
+	}
+	path := u.Path
+	// file:///C:/dir/x.go has the path /C:/dir/x.go on Windows.
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
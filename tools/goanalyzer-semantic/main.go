@@ -2,21 +2,132 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"go/ast"
-	"go/importer"
-	"go/parser"
 	"go/token"
 	"go/types"
 	"os"
-	"path/filepath"
-	"strconv"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"goanalyzer-semantic/passes"
 )
 
+// protocolVersion is bumped whenever the helper's observable behavior
+// changes in a way clients may depend on.
+//
+//	2: cursor positions are end-exclusive; the column just past an
+//	   identifier no longer selects it.
+//	3: the column just past an identifier selects it again, unless another
+//	   identifier starts at that column.
+const protocolVersion = 3
+
 type Input struct {
 	File    string `json:"file"`
 	Line    int    `json:"line"`
 	Col     int    `json:"col"`
 	Content string `json:"content"`
+	// Overlays holds the unsaved text of other open files, keyed by path.
+	// Those in the target's directory replace the files on disk when the
+	// package is loaded.
+	Overlays map[string]string `json:"overlays,omitempty"`
+	// Offset, when set, is the byte offset of the query position in the
+	// target file and replaces Line and Col.
+	Offset *int `json:"offset,omitempty"`
+	// Mode selects the query: "" resolves the symbol at Line/Col,
+	// "definition" returns only its declaration, "hover" describes it
+	// without uses, "callers" lists the call sites of a function,
+	// "implementations" relates interfaces and their implementations,
+	// "outline" lists the file's declarations as a tree,
+	// "prepare_rename" reports whether it can be renamed,
+	// "rename_check" validates renaming it to NewName, "unused" reports
+	// variables that are never read, "shadow_report" lists declarations
+	// that shadow an outer variable, "race_report" and "retention_report"
+	// run the race and memory retention analyzers, "concurrency" runs the
+	// race analyzers with the goroutine panic and lock blocking checks,
+	// "metrics" measures every function, "lifetime" reports how long the
+	// locals of the function at Line/Col stay live, "closure_report" lists
+	// what the function literal at Line/Col captures, "struct_layout" lays
+	// out the struct type at Line/Col, "lock_report" lists what the mutex
+	// at Line/Col guards, "goroutine_map" describes every go statement of
+	// the file, "defer_report" lists its defer statements,
+	// "channel_report" lists every operation on the channel at Line/Col,
+	// "error_flow" classifies how the error variable at Line/Col is
+	// handled, "const_group" lists the const declaration holding the
+	// constant at Line/Col, "interface_usage" reports which methods of the
+	// interface value at Line/Col are called, "callgraph" builds the call
+	// graph of the package, "test_refs" lists the tests referring to the
+	// symbol at Line/Col, "semantic_tokens" classifies every variable,
+	// field and constant of the target file for highlighting,
+	// "capabilities" lists the supported modes and finding codes, and
+	// "analyze" runs the registered analyzers over the target file.
+	Mode string `json:"mode,omitempty"`
+	// Analyzers restricts "analyze" mode to the named analyzers.
+	Analyzers []string `json:"analyzers,omitempty"`
+	// ColumnMode is "byte" (default) for go/token byte columns or "visual"
+	// for editor columns where tabs expand to TabSize-wide tab stops. It
+	// applies to Col and to every range in the response.
+	ColumnMode string `json:"column_mode,omitempty"`
+	TabSize    int    `json:"tab_size,omitempty"`
+	// CaptureGlobals marks uses of package-level variables inside goroutine
+	// function literals as captured; by default only locals can be captured.
+	CaptureGlobals bool `json:"capture_globals,omitempty"`
+	// TimeoutMs bounds the whole request. When it elapses a null result is
+	// written and the helper exits, so pathological inputs degrade instead
+	// of hanging the caller.
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+	// WantDiagnostics adds load_diagnostics for skipped or partially
+	// analyzed files to the response.
+	WantDiagnostics bool `json:"want_diagnostics,omitempty"`
+	// RelativeToDecl reports use lines as deltas from the declaration line;
+	// columns stay absolute.
+	RelativeToDecl bool `json:"relative_to_decl,omitempty"`
+	// WantDoc adds the declaration's doc comment to the response and lets
+	// types and functions resolve as well as variables.
+	WantDoc bool `json:"want_doc,omitempty"`
+	// Stream writes a resolve response incrementally as JSON lines: the
+	// Output with a null uses list, one UseEntry per line, then a
+	// {"done":true} line. See useStream.
+	Stream bool `json:"stream,omitempty"`
+	// NewName is the proposed name in "rename_check" mode.
+	NewName string `json:"new_name,omitempty"`
+	// UnusedScope is "file" (default) or "function" to restrict "unused"
+	// mode to the function enclosing Line/Col.
+	UnusedScope string `json:"unused_scope,omitempty"`
+	// IncludeUnderscore makes "unused" mode report names starting with an
+	// underscore, which are skipped by convention.
+	IncludeUnderscore bool `json:"include_underscore,omitempty"`
+	// ReportScope is "file" (default) or "package" to run "race_report",
+	// "retention_report" and "concurrency" over every file of the target's
+	// package.
+	ReportScope string `json:"report_scope,omitempty"`
+	// Format "sarif" renders the findings of "analyze", "race_report",
+	// "retention_report" and "concurrency" as a SARIF 2.1.0 log instead of
+	// the usual response. Other modes ignore it.
+	Format string `json:"format,omitempty"`
+	// GOARCH selects the architecture "struct_layout" mode computes sizes
+	// for; it defaults to the host's.
+	GOARCH string `json:"goarch,omitempty"`
+	// DeferScope is "file" (default) or "function" to restrict
+	// "defer_report" mode to the function enclosing Line/Col.
+	DeferScope string `json:"defer_scope,omitempty"`
+	// Interfaces are the interfaces "hover" mode checks the symbol's type
+	// against, "error" or a package path and name such as "fmt.Stringer"
+	// or "encoding/json.Marshaler". It defaults to defaultInterfaces.
+	Interfaces []string `json:"interfaces,omitempty"`
+	// Plugins are external checks run by "analyze" and the report modes
+	// after the built-in analyzers; see PluginSpec.
+	Plugins []PluginSpec `json:"plugins,omitempty"`
+	// Config overrides the project's goanalyzer.json for this request;
+	// WantConfig adds the merged settings to the response of "analyze"
+	// and the report modes.
+	Config     *Config `json:"config,omitempty"`
+	WantConfig bool    `json:"want_config,omitempty"`
 }
 
 type Pos struct {
@@ -25,14 +136,31 @@ type Pos struct {
 }
 
 type Range struct {
-	Start Pos `json:"start"`
-	End   Pos `json:"end"`
+	File  string `json:"file,omitempty"`
+	Start Pos    `json:"start"`
+	End   Pos    `json:"end"`
 }
 
 type UseEntry struct {
 	Range    Range `json:"range"`
 	Reassign bool  `json:"reassign"`
 	Captured bool  `json:"captured"`
+	// PackageInit is set for uses that run during package initialization:
+	// package-level var initializers and the bodies of init functions.
+	PackageInit bool `json:"package_init,omitempty"`
+	// InComparison is set when the use is an operand of ==, !=, <, <=, >
+	// or >=, directly or as the field of a selector.
+	InComparison bool `json:"in_comparison,omitempty"`
+	// PassedByPointer is set for &x passed directly as a call argument, where
+	// the callee may write the variable through the pointer.
+	PassedByPointer bool `json:"passed_by_pointer,omitempty"`
+	// InSelect is set for uses in a case of a select statement, either its
+	// communication or its body, within the same function.
+	InSelect bool `json:"in_select,omitempty"`
+	// NilChecked is "true", "false" or "unknown" for uses that dereference
+	// a pointer variable, telling whether a nil check dominates them; see
+	// nilChecked. It is empty for other uses and without type information.
+	NilChecked string `json:"nil_checked,omitempty"`
 }
 
 type Output struct {
@@ -40,6 +168,56 @@ type Output struct {
 	Decl      Range      `json:"decl"`
 	Uses      []UseEntry `json:"uses"`
 	IsPointer bool       `json:"is_pointer"`
+	// SizeBytes is the size of the symbol's type, or -1 when it is unknown,
+	// as for type parameters or without type information.
+	SizeBytes       int64            `json:"size_bytes"`
+	LoadDiagnostics []LoadDiagnostic `json:"load_diagnostics,omitempty"`
+	// Doc is the declaration's doc comment, filled when the request sets
+	// want_doc.
+	Doc string `json:"doc,omitempty"`
+	// Degraded is set when the package could not be type-checked cleanly,
+	// so some uses may be missing.
+	Degraded bool `json:"degraded,omitempty"`
+	// Error explains why a recognized symbol could not be resolved, e.g.
+	// a selector while only syntax information is available.
+	Error string `json:"error,omitempty"`
+	// ReassignedInLoop is set when the symbol is written on every
+	// iteration of a loop: from the body or post statement of a loop it
+	// is declared outside of, or by a range clause assigning it with =.
+	ReassignedInLoop bool `json:"reassigned_in_loop,omitempty"`
+	// ScopeRange spans the scope the symbol is declared in: its block,
+	// function or, for package-level symbols, the declaring file. Go makes
+	// a local visible only from the end of its declaration on. It is
+	// omitted for fields, methods and symbols of other packages.
+	ScopeRange *Range `json:"scope_range,omitempty"`
+	// PassedToGoroutine is set when some use of the symbol anywhere in the
+	// package is part of a go statement: captured by its function literal,
+	// passed as an argument or used as the receiver of the started method.
+	PassedToGoroutine bool `json:"passed_to_goroutine,omitempty"`
+	// InferredType is the type the symbol gets from its declaration: for
+	// a := declaration the type of its right-hand side, resolved from the
+	// recorded expression types, and otherwise the declared type.
+	InferredType string `json:"inferred_type,omitempty"`
+}
+
+// relativizeUses rewrites every use's start and end line as a delta from
+// the declaration line. It runs last, after any column conversion, since
+// that needs absolute lines.
+func (o *Output) relativizeUses() {
+	if o == nil {
+		return
+	}
+	for i := range o.Uses {
+		r := &o.Uses[i].Range
+		r.Start.Line -= o.Decl.Start.Line
+		r.End.Line -= o.Decl.Start.Line
+	}
+}
+
+// useOptions carries request options that affect how individual uses are
+// classified.
+type useOptions struct {
+	captureGlobals bool
 }
 
 type typeSwitchTarget struct {
@@ -48,225 +226,542 @@ type typeSwitchTarget struct {
 }
 
 func main() {
+	lsp := flag.Bool("lsp", false, "serve the Language Server Protocol over stdin and stdout")
+	sarifPath := flag.String("sarif", "", "write the findings of a report mode as SARIF to this file instead of stdout")
+	schema := flag.Bool("schema", false, "print a JSON Schema of the request and of every mode's response")
+	caps := flag.Bool("capabilities", false, "print the supported modes, finding codes and features")
+	watchFlag := flag.Bool("watch", false, "re-run the report mode of the request whenever a Go file of its package changes")
+	watchDiff := flag.Bool("watch-diff", false, "with -watch, print only the findings added and removed by each run")
+	complexity := flag.Int("complexity-threshold", 0, "report functions whose cyclomatic complexity exceeds this score instead of the default")
+	baseline := flag.String("baseline", "", "drop the findings recorded in this baseline file; \"write path\" records the current findings instead")
+	flag.Parse()
+	if *lsp {
+		os.Exit(serveLSP(os.Stdin, os.Stdout))
+	}
+	if *schema {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(protocolSchema())
+		return
+	}
+	if *caps {
+		writeOutput(capabilities())
+		return
+	}
 	var in Input
 	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
 		encodeNil()
 		return
 	}
-	out := resolve(in)
+	if *sarifPath != "" {
+		if reportModes[in.Mode] == nil {
+			fmt.Fprintln(os.Stderr, "-sarif needs the analyze mode or a report mode")
+			os.Exit(2)
+		}
+		in.Format = "sarif"
+	}
+	if *complexity > 0 {
+		if in.Config == nil {
+			in.Config = &Config{}
+		}
+		in.Config.ComplexityThreshold = *complexity
+	}
+	baselineWrite := *baseline == "write"
+	baselinePath := *baseline
+	if baselineWrite {
+		if baselinePath = flag.Arg(0); baselinePath == "" {
+			fmt.Fprintln(os.Stderr, "-baseline write needs the path of the baseline file")
+			os.Exit(2)
+		}
+	}
+	if *watchFlag {
+		run := reportModes[in.Mode]
+		if run == nil {
+			fmt.Fprintln(os.Stderr, "-watch needs the analyze mode or a report mode")
+			os.Exit(2)
+		}
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		os.Exit(watch(in, run, *watchDiff, watchInterval, os.Stdout, stop))
+	}
+	if in.TimeoutMs > 0 {
+		time.AfterFunc(time.Duration(in.TimeoutMs)*time.Millisecond, func() {
+			encodeNil()
+			os.Exit(0)
+		})
+	}
+	cols := newColumnMapper(in)
+	if cols != nil {
+		in.Col = cols.toByte(cols.target, in.Line, in.Col)
+	}
+	if in.Offset != nil {
+		line, col, ok := offsetPosition(in)
+		if !ok {
+			encodeNil()
+			return
+		}
+		in.Line, in.Col = line, col
+	}
+	if in.Stream && in.Mode == "" {
+		streamResolve(in, cols)
+		return
+	}
+	var out rangeMapper
+	switch in.Mode {
+	case "analyze":
+		out = analyze(in)
+	case "definition":
+		out = definition(in)
+	case "hover":
+		out = hover(in)
+	case "callers":
+		out = callers(in)
+	case "implementations":
+		out = implementations(in)
+	case "outline":
+		out = outline(in)
+	case "unused":
+		out = unused(in)
+	case "shadow_report":
+		out = shadowReport(in)
+	case "race_report":
+		out = raceReport(in)
+	case "retention_report":
+		out = retentionReport(in)
+	case "concurrency":
+		out = concurrency(in)
+	case "metrics":
+		out = metrics(in)
+	case "lifetime":
+		out = lifetime(in)
+	case "closure_report":
+		out = closureReport(in)
+	case "struct_layout":
+		out = structLayout(in)
+	case "lock_report":
+		out = lockReport(in)
+	case "goroutine_map":
+		out = goroutineMap(in)
+	case "defer_report":
+		out = deferReport(in)
+	case "channel_report":
+		out = channelReport(in)
+	case "error_flow":
+		out = errorFlow(in)
+	case "const_group":
+		out = constGroup(in)
+	case "interface_usage":
+		out = interfaceUsage(in)
+	case "callgraph":
+		out = callGraph(in)
+	case "test_refs":
+		out = testRefs(in)
+	case "semantic_tokens":
+		out = semanticTokens(in)
+	case "capabilities":
+		out = capabilities()
+	case "prepare_rename":
+		out = prepareRename(in)
+	case "rename_check":
+		out = renameCheck(in)
+	default:
+		out = resolve(in)
+	}
+	if ao, ok := out.(*AnalyzeOutput); ok && ao != nil && baselinePath != "" {
+		var err error
+		if baselineWrite {
+			err = writeBaseline(baselinePath, ao, in)
+		} else {
+			err = applyBaseline(baselinePath, ao, in)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if ao, ok := out.(*AnalyzeOutput); ok && in.Format == "sarif" {
+		// SARIF has columns of its own, counted in code points.
+		if err := writeSARIF(*sarifPath, toSARIF(ao, in)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if cols != nil {
+		out.mapRanges(cols.mapRange)
+	}
+	if o, ok := out.(*Output); ok && in.RelativeToDecl {
+		o.relativizeUses()
+	}
+	writeOutput(out)
+}
+
+// outputMu makes sure exactly one response is written even when the
+// timeout fires while the result is being encoded. It is never released.
+var outputMu sync.Mutex
+
+func writeOutput(v interface{}) {
+	outputMu.Lock()
 	enc := json.NewEncoder(os.Stdout)
-	_ = enc.Encode(out)
+	_ = enc.Encode(v)
 }
 
 func encodeNil() {
-	enc := json.NewEncoder(os.Stdout)
-	_ = enc.Encode((*Output)(nil))
+	writeOutput((*Output)(nil))
+}
+
+// offsetPosition converts in.Offset to the zero-based line and byte column
+// used everywhere else. It fails for offsets outside the target file.
+func offsetPosition(in Input) (line, col int, ok bool) {
+	lp := loadPackage(in)
+	if lp == nil {
+		return 0, 0, false
+	}
+	pos, ok := offsetPos(lp.fset, lp.file, *in.Offset)
+	if !ok {
+		return 0, 0, false
+	}
+	p := lp.fset.Position(pos)
+	return p.Line - 1, p.Column - 1, true
+}
+
+// offsetPos maps a byte offset in file to its token.Pos.
+func offsetPos(fset *token.FileSet, file *ast.File, offset int) (token.Pos, bool) {
+	tf := fset.File(file.Pos())
+	if tf == nil || offset < 0 || offset > tf.Size() {
+		return token.NoPos, false
+	}
+	return tf.Pos(offset), true
 }
 
 func resolve(in Input) *Output {
-	if in.File == "" {
+	return resolveTo(in, nil)
+}
+
+// resolveTo runs a resolve query. With a non-nil stream the typed path
+// writes the symbol and its uses to the stream as they are found and
+// returns the Output without uses; the syntax-only fallback always
+// returns a complete Output.
+func resolveTo(in Input, stream *useStream) *Output {
+	lp := loadPackage(in)
+	if lp == nil {
 		return nil
 	}
+	if lp.syntaxOnly {
+		out := syntaxResolve(lp, in)
+		finishOutput(out, lp, in)
+		return out
+	}
+	return resolveLoaded(lp, in, stream)
+}
 
-	filePath := in.File
-	if abs, err := filepath.Abs(filePath); err == nil {
-		filePath = abs
+// finishOutput adds the package-level load state to a resolve response.
+func finishOutput(out *Output, lp *loadedPackage, in Input) {
+	if out == nil {
+		return
+	}
+	if in.WantDiagnostics {
+		out.LoadDiagnostics = lp.diagnostics
 	}
+	out.Degraded = out.Degraded || lp.degraded
+}
+
+func resolveLoaded(lp *loadedPackage, in Input, stream *useStream) *Output {
+	fset, info := lp.fset, lp.info
+	opts := useOptions{captureGlobals: in.CaptureGlobals}
 
-	fset := token.NewFileSet()
-	file, files := parsePackageFiles(fset, filePath, in.Content)
-	if file == nil || len(files) == 0 {
+	parentMap := buildPackageParentMap(lp.files)
+	t := resolveTarget(lp, in.Line, in.Col, parentMap, in.WantDoc)
+	if t == nil {
 		return nil
 	}
-
-	info := &types.Info{
-		Defs:       make(map[*ast.Ident]types.Object),
-		Uses:       make(map[*ast.Ident]types.Object),
-		Selections: make(map[*ast.SelectorExpr]*types.Selection),
-		Types:      make(map[ast.Expr]types.TypeAndValue),
-		Implicits:  make(map[ast.Node]types.Object),
+	var out *Output
+	var declFunc ast.Node
+	if t.declIdent == nil {
+		out = &Output{
+			Name:      t.obj.Name(),
+			Decl:      t.externalDecl,
+			IsPointer: isPointerType(t.obj.Type()),
+		}
+	} else {
+		isPointer := false
+		for _, o := range t.objects {
+			if isPointerType(o.Type()) {
+				isPointer = true
+				break
+			}
+		}
+		out = &Output{
+			Name:      t.declIdent.Name,
+			Decl:      rangeForIdent(fset, t.declIdent),
+			IsPointer: isPointer,
+		}
+		declFunc = enclosingFunc(t.declIdent, parentMap)
+		if in.WantDoc && t.typeSwitch == nil {
+			out.Doc = docForIdent(t.declIdent, parentMap)
+		}
+	}
+	out.SizeBytes = sizeBytes(t.typ(info))
+	out.ReassignedInLoop = reassignedInLoop(info, lp.files, t.objects, t.declIdent, parentMap)
+	out.PassedToGoroutine = passedToGoroutine(info, lp.files, t.objects, parentMap)
+	if typ := inferredType(info, t, parentMap); typ != nil {
+		out.InferredType = types.TypeString(typ, types.RelativeTo(lp.pkg))
 	}
-	config := &types.Config{
-		Importer: importer.Default(),
-		Error:    func(error) {},
+	if t.declIdent != nil {
+		out.ScopeRange = scopeRange(fset, t.obj, t.declIdent)
 	}
-	pkgName := file.Name.Name
-	_, _ = config.Check(pkgName, fset, files, info)
+	finishOutput(out, lp, in)
 
-	parentMap := buildParentMap(file)
-	ident, selMap := findIdentAtPosition(fset, file, in.Line, in.Col)
+	if stream != nil {
+		stream.header(out)
+		visitUses(info, fset, lp.files, t.objects, out.Decl, declFunc, parentMap, opts, stream.use)
+		stream.end()
+		return out
+	}
+	out.Uses = collectUses(info, fset, lp.files, t.objects, out.Decl, declFunc, parentMap, opts)
+	return out
+}
+
+// symbolTarget is the symbol a query position refers to.
+type symbolTarget struct {
+	// obj is the resolved object; for a type switch guard it is nil.
+	obj types.Object
+	// objects are the objects whose uses belong to the symbol: obj itself,
+	// or the per-clause implicit objects of a type switch.
+	objects []types.Object
+	// declIdent is nil for objects declared outside the checked package or
+	// without an identifier in info.Defs; externalDecl describes their
+	// declaration instead.
+	declIdent    *ast.Ident
+	externalDecl Range
+	typeSwitch   *ast.TypeSwitchStmt
+}
+
+// resolveTarget finds the symbol at line/col of the target file. Types and
+// functions are only accepted when allowNamed is set; package names,
+// builtins and labels never are.
+func resolveTarget(lp *loadedPackage, line, col int, parentMap map[ast.Node]ast.Node, allowNamed bool) *symbolTarget {
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, line, col)
 	if ident == nil {
 		return nil
 	}
+	return resolveIdent(lp, ident, selMap[ident], parentMap, allowNamed)
+}
 
+// identObject returns the object ident denotes; sel is the selector whose
+// Sel is ident, if any.
+func identObject(info *types.Info, pkg *types.Package, ident *ast.Ident, sel *ast.SelectorExpr) types.Object {
 	obj := info.Defs[ident]
 	if obj == nil {
 		obj = info.Uses[ident]
 	}
-	if obj == nil {
-		if sel := selMap[ident]; sel != nil {
-			if selInfo := info.Selections[sel]; selInfo != nil {
-				obj = selInfo.Obj()
-			}
+	if obj == nil && sel != nil {
+		if selInfo := info.Selections[sel]; selInfo != nil {
+			obj = selInfo.Obj()
 		}
 	}
-	var tsTarget *typeSwitchTarget
+	if sel == nil {
+		obj = preferUserObject(ident, obj, pkg)
+	}
+	return obj
+}
+
+// resolveIdent is resolveTarget for an identifier that has already been
+// found.
+func resolveIdent(lp *loadedPackage, ident *ast.Ident, sel *ast.SelectorExpr, parentMap map[ast.Node]ast.Node, allowNamed bool) *symbolTarget {
+	fset, info, pkg := lp.fset, lp.info, lp.pkg
+	obj := identObject(info, pkg, ident, sel)
 	if obj == nil {
-		tsTarget = resolveTypeSwitchTargetFromIdent(ident, info, parentMap)
-		if tsTarget == nil {
+		return typeSwitchSymbol(resolveTypeSwitchTargetFromIdent(ident, info, parentMap), parentMap)
+	}
+	switch obj := obj.(type) {
+	case *types.Func, *types.TypeName:
+		if !allowNamed {
 			return nil
 		}
-	} else {
-		switch obj.(type) {
-		case *types.Func, *types.TypeName, *types.PkgName, *types.Builtin, *types.Label:
+	case *types.PkgName:
+		if !allowNamed {
 			return nil
 		}
+		return pkgNameSymbol(lp, obj)
+	case *types.Builtin, *types.Label:
+		return nil
 	}
 
-	if tsTarget != nil {
-		if tsTarget.declIdent == nil {
-			return nil
-		}
-		decl := rangeForIdent(fset, tsTarget.declIdent)
-		declFunc := enclosingFunc(tsTarget.declIdent, parentMap)
-		uses := collectUsesForObjects(info, fset, tsTarget.objects, decl, declFunc, parentMap)
-		isPointer := false
-		for _, o := range tsTarget.objects {
-			if isPointerType(o.Type()) {
-				isPointer = true
-				break
-			}
-		}
-		return &Output{
-			Name:      tsTarget.declIdent.Name,
-			Decl:      decl,
-			Uses:      uses,
-			IsPointer: isPointer,
+	declIdent := findDeclIdent(info, obj)
+	if declIdent == nil && obj.Pkg() != nil && obj.Pkg() != pkg {
+		if decl, ok := rangeForObject(fset, obj); ok {
+			return &symbolTarget{obj: obj, objects: []types.Object{obj}, externalDecl: decl}
 		}
 	}
-
-	declIdent := findDeclIdent(info, obj)
 	if declIdent == nil {
-		tsTarget = resolveTypeSwitchTargetFromObj(obj, info, parentMap)
-		if tsTarget == nil || tsTarget.declIdent == nil {
-			return nil
-		}
-		decl := rangeForIdent(fset, tsTarget.declIdent)
-		declFunc := enclosingFunc(tsTarget.declIdent, parentMap)
-		uses := collectUsesForObjects(info, fset, tsTarget.objects, decl, declFunc, parentMap)
-		isPointer := false
-		for _, o := range tsTarget.objects {
-			if isPointerType(o.Type()) {
-				isPointer = true
-				break
-			}
+		if t := typeSwitchSymbol(resolveTypeSwitchTargetFromObj(obj, info, parentMap), parentMap); t != nil {
+			return t
 		}
-		return &Output{
-			Name:      tsTarget.declIdent.Name,
-			Decl:      decl,
-			Uses:      uses,
-			IsPointer: isPointer,
+		// Implicitly declared objects have no entry in info.Defs but
+		// usually still carry a position; use it like an external one.
+		if decl, ok := rangeForObject(fset, obj); ok {
+			return &symbolTarget{obj: obj, objects: []types.Object{obj}, externalDecl: decl}
 		}
+		return nil
 	}
-	decl := rangeForIdent(fset, declIdent)
-	declFunc := enclosingFunc(declIdent, parentMap)
-	uses := collectUses(info, fset, obj, decl, declFunc, parentMap)
+	return &symbolTarget{obj: obj, objects: []types.Object{obj}, declIdent: declIdent}
+}
 
-	return &Output{
-		Name:      obj.Name(),
-		Decl:      decl,
-		Uses:      uses,
-		IsPointer: isPointerType(obj.Type()),
+// pkgNameSymbol targets the name an import declares in one file. Every
+// import has its own PkgName object, so two imports with the same base name,
+// one of them aliased, never share uses. An aliased import is declared by
+// its alias; otherwise the import path stands in for the declaration.
+func pkgNameSymbol(lp *loadedPackage, obj *types.PkgName) *symbolTarget {
+	if declIdent := findDeclIdent(lp.info, obj); declIdent != nil {
+		return &symbolTarget{obj: obj, objects: []types.Object{obj}, declIdent: declIdent}
+	}
+	for _, f := range lp.files {
+		for _, spec := range f.Imports {
+			if lp.info.Implicits[spec] == obj {
+				decl := rangeForPos(lp.fset, spec.Path.Pos(), spec.Path.End())
+				return &symbolTarget{obj: obj, objects: []types.Object{obj}, externalDecl: decl}
+			}
+		}
 	}
+	return nil
 }
 
-func parsePackageFiles(fset *token.FileSet, targetFile string, content string) (*ast.File, []*ast.File) {
-	dir := filepath.Dir(targetFile)
-	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
-	if err != nil {
-		return parseSingleFile(fset, targetFile, content)
+func typeSwitchSymbol(ts *typeSwitchTarget, parents map[ast.Node]ast.Node) *symbolTarget {
+	if ts == nil || ts.declIdent == nil {
+		return nil
 	}
+	return &symbolTarget{
+		objects:    ts.objects,
+		declIdent:  ts.declIdent,
+		typeSwitch: enclosingTypeSwitch(ts.declIdent, parents),
+	}
+}
 
-	var targetPkg *ast.Package
-	var targetAst *ast.File
-	targetFile = filepath.Clean(targetFile)
-
-	for _, pkg := range pkgs {
-		for filename, f := range pkg.Files {
-			if filepath.Clean(filename) == targetFile {
-				targetPkg = pkg
-				targetAst = f
-			}
+// docForIdent returns the leading doc comment of the declaration that
+// declares ident, without comment markers. A grouped var/const/type spec
+// without its own doc falls back to nothing rather than the group's doc.
+func docForIdent(ident *ast.Ident, parents map[ast.Node]ast.Node) string {
+	var doc *ast.CommentGroup
+	switch decl := parents[ident].(type) {
+	case *ast.Field:
+		doc = decl.Doc
+	case *ast.FuncDecl:
+		if decl.Name == ident {
+			doc = decl.Doc
+		}
+	case *ast.ValueSpec:
+		doc = decl.Doc
+		if gd, ok := parents[decl].(*ast.GenDecl); ok && doc == nil && !gd.Lparen.IsValid() {
+			doc = gd.Doc
+		}
+	case *ast.TypeSpec:
+		doc = decl.Doc
+		if gd, ok := parents[decl].(*ast.GenDecl); ok && doc == nil && !gd.Lparen.IsValid() {
+			doc = gd.Doc
 		}
 	}
-
-	if targetPkg == nil {
-		return parseSingleFile(fset, targetFile, content)
+	if doc == nil {
+		return ""
 	}
+	return strings.TrimRight(doc.Text(), "\n")
+}
 
-	// If we have overlay content for the target file, replace it
-	if content != "" {
-		if parsed, err := parser.ParseFile(fset, targetFile, content, parser.ParseComments); err == nil {
-			targetPkg.Files[targetFile] = parsed
-			targetAst = parsed
+// preferUserObject replaces a builtin resolution of ident with a variable
+// of the same name that is in scope at ident, such as `len := 5`. The
+// checker can leave such uses pointing at the universe object when the
+// shadowing declaration failed to check. A missing resolution is left alone:
+// type switch guards have no object of their own, and looking their name up
+// would find an outer variable they shadow.
+func preferUserObject(ident *ast.Ident, obj types.Object, pkg *types.Package) types.Object {
+	if _, ok := obj.(*types.Builtin); !ok || pkg == nil {
+		return obj
+	}
+	scope := pkg.Scope().Innermost(ident.Pos())
+	if scope == nil {
+		return obj
+	}
+	if _, found := scope.LookupParent(ident.Name, ident.Pos()); found != nil {
+		if v, ok := found.(*types.Var); ok {
+			return v
 		}
 	}
+	return obj
+}
 
-	files := make([]*ast.File, 0, len(targetPkg.Files))
-	for _, f := range targetPkg.Files {
-		files = append(files, f)
+// findIdentAtPosition returns the identifier covering the zero-based
+// line/col. An identifier containing the column wins; otherwise the one
+// ending exactly at the column is used, so a cursor placed just past the
+// last character (as after a double-click selection) still resolves it.
+// Matching is done on file offsets computed once from the line start, so
+// very long generated lines cost one comparison per identifier instead of a
+// Position lookup. Columns count bytes, so any column inside a multi-byte
+// letter of a Unicode identifier selects that identifier.
+func findIdentAtPosition(fset *token.FileSet, file *ast.File, line, col int) (*ast.Ident, map[*ast.Ident]*ast.SelectorExpr) {
+	target, ok := filePos(fset, file, line, col)
+	if !ok {
+		return nil, make(map[*ast.Ident]*ast.SelectorExpr)
+	}
+	if ident, selMap := identContaining(file, target); ident != nil || col == 0 {
+		return ident, selMap
 	}
+	return identContaining(file, target-1)
+}
 
-	return targetAst, files
+// findIdentAtOffset is findIdentAtPosition for a byte offset into file.
+func findIdentAtOffset(fset *token.FileSet, file *ast.File, offset int) (*ast.Ident, map[*ast.Ident]*ast.SelectorExpr) {
+	pos, ok := offsetPos(fset, file, offset)
+	if !ok {
+		return nil, make(map[*ast.Ident]*ast.SelectorExpr)
+	}
+	p := fset.Position(pos)
+	return findIdentAtPosition(fset, file, p.Line-1, p.Column-1)
 }
 
-func parseSingleFile(fset *token.FileSet, targetFile string, content string) (*ast.File, []*ast.File) {
-	var (
-		file *ast.File
-		err  error
-	)
-	if content != "" {
-		file, err = parser.ParseFile(fset, targetFile, content, parser.ParseComments)
-	} else {
-		file, err = parser.ParseFile(fset, targetFile, nil, parser.ParseComments)
+// filePos converts a zero-based line/col to a position in file. It fails
+// for lines outside the file and columns past the end of the line.
+func filePos(fset *token.FileSet, file *ast.File, line, col int) (token.Pos, bool) {
+	tokFile := fset.File(file.Pos())
+	line++
+	if tokFile == nil || line < 1 || line > tokFile.LineCount() || col < 0 {
+		return token.NoPos, false
 	}
-	if err != nil || file == nil {
-		return nil, nil
+	lineStart := tokFile.LineStart(line)
+	lineEnd := token.Pos(tokFile.Base() + tokFile.Size())
+	if line < tokFile.LineCount() {
+		lineEnd = tokFile.LineStart(line+1) - 1
 	}
-	return file, []*ast.File{file}
+	target := lineStart + token.Pos(col)
+	if target > lineEnd {
+		return token.NoPos, false
+	}
+	return target, true
 }
 
-func findIdentAtPosition(fset *token.FileSet, file *ast.File, line, col int) (*ast.Ident, map[*ast.Ident]*ast.SelectorExpr) {
-	line++
-	col++
-	var best *ast.Ident
-	bestSpan := 1 << 30
+// identContaining returns the identifier whose extent contains target.
+// Identifiers never overlap, so at most one does; only the subtrees
+// containing it are visited. Every SelectorExpr on the way down is recorded
+// so that any level of a chain like a.b.c maps to its own selector.
+func identContaining(file *ast.File, target token.Pos) (*ast.Ident, map[*ast.Ident]*ast.SelectorExpr) {
 	selMap := make(map[*ast.Ident]*ast.SelectorExpr)
-
+	var best *ast.Ident
 	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || best != nil {
+			return false
+		}
+		if target < n.Pos() || target >= n.End() {
+			return false
+		}
 		switch node := n.(type) {
 		case *ast.SelectorExpr:
 			if node.Sel != nil {
 				selMap[node.Sel] = node
 			}
 		case *ast.Ident:
-			pos := fset.Position(node.Pos())
-			end := fset.Position(node.End())
-			if pos.Line != line {
-				return true
-			}
-			if col < pos.Column || col > end.Column {
-				return true
-			}
-			span := end.Column - pos.Column
-			if span < bestSpan {
-				bestSpan = span
-				best = node
-			}
+			best = node
 		}
 		return true
 	})
-
 	return best, selMap
 }
 
@@ -279,83 +774,76 @@ func findDeclIdent(info *types.Info, obj types.Object) *ast.Ident {
 	return nil
 }
 
-func collectUses(info *types.Info, fset *token.FileSet, obj types.Object, decl Range, declFunc ast.Node, parentMap map[ast.Node]ast.Node) []UseEntry {
+func collectUses(info *types.Info, fset *token.FileSet, files []*ast.File, objs []types.Object, decl Range, declFunc ast.Node, parentMap map[ast.Node]ast.Node, opts useOptions) []UseEntry {
 	uses := make([]UseEntry, 0)
-	seen := make(map[string]bool)
-	objSet := map[types.Object]bool{obj: true}
-
-	add := func(r Range, reassign bool, captured bool) {
-		key := keyForRange(r)
-		if seen[key] {
-			return
-		}
-		if sameRange(r, decl) {
-			return
-		}
-		seen[key] = true
-		uses = append(uses, UseEntry{
-			Range:    r,
-			Reassign: reassign,
-			Captured: captured,
-		})
-	}
-
-	for ident, o := range info.Uses {
-		if objSet[o] {
-			r := rangeForIdent(fset, ident)
-			add(r, isReassign(ident, info, parentMap), isCaptured(ident, obj, declFunc, parentMap))
-		}
-	}
-	for sel, selInfo := range info.Selections {
-		if selInfo != nil && objSet[selInfo.Obj()] {
-			r := rangeForIdent(fset, sel.Sel)
-			add(r, isReassign(sel.Sel, info, parentMap), isCaptured(sel.Sel, obj, declFunc, parentMap))
-		}
-	}
-
+	visitUses(info, fset, files, objs, decl, declFunc, parentMap, opts, func(u UseEntry) {
+		uses = append(uses, u)
+	})
 	return uses
 }
 
-func collectUsesForObjects(info *types.Info, fset *token.FileSet, objs []types.Object, decl Range, declFunc ast.Node, parentMap map[ast.Node]ast.Node) []UseEntry {
+// visitUses calls yield for every use of objs other than the declaration
+// itself. Files are walked in file name order and each file in source
+// order, so uses arrive in the same order sortUses would put them in and
+// can be emitted as soon as they are found.
+func visitUses(info *types.Info, fset *token.FileSet, files []*ast.File, objs []types.Object, decl Range, declFunc ast.Node, parentMap map[ast.Node]ast.Node, opts useOptions, yield func(UseEntry)) {
 	objSet := make(map[types.Object]bool)
 	for _, o := range objs {
 		if o != nil {
 			objSet[o] = true
 		}
 	}
-	uses := make([]UseEntry, 0)
-	seen := make(map[string]bool)
-
-	add := func(r Range, reassign bool, captured bool) {
-		key := keyForRange(r)
-		if seen[key] {
-			return
-		}
-		if sameRange(r, decl) {
-			return
-		}
-		seen[key] = true
-		uses = append(uses, UseEntry{
-			Range:    r,
-			Reassign: reassign,
-			Captured: captured,
-		})
-	}
+	sorted := make([]*ast.File, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return fset.Position(sorted[i].Pos()).Filename < fset.Position(sorted[j].Pos()).Filename
+	})
 
-	for ident, o := range info.Uses {
-		if objSet[o] {
+	for _, f := range sorted {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			o := info.Uses[ident]
+			if !objSet[o] {
+				// Field and method selectors are normally in info.Uses
+				// too; the selection covers the remaining cases.
+				sel, ok := parentMap[ident].(*ast.SelectorExpr)
+				if !ok || sel.Sel != ident || info.Selections[sel] == nil || !objSet[info.Selections[sel].Obj()] {
+					return true
+				}
+				o = info.Selections[sel].Obj()
+			}
 			r := rangeForIdent(fset, ident)
-			add(r, isReassign(ident, info, parentMap), isCaptured(ident, o, declFunc, parentMap))
-		}
-	}
-	for sel, selInfo := range info.Selections {
-		if selInfo != nil && objSet[selInfo.Obj()] {
-			r := rangeForIdent(fset, sel.Sel)
-			add(r, isReassign(sel.Sel, info, parentMap), isCaptured(sel.Sel, selInfo.Obj(), declFunc, parentMap))
-		}
+			if sameRange(r, decl) {
+				return true
+			}
+			use := UseEntry{
+				Range:           r,
+				Reassign:        isReassign(ident, info, parentMap),
+				Captured:        isCaptured(ident, o, declFunc, parentMap, opts),
+				PackageInit:     isPackageInit(ident, parentMap),
+				InComparison:    isComparisonOperand(ident, parentMap),
+				PassedByPointer: isPointerArgument(ident, parentMap),
+				InSelect:        inSelect(ident, parentMap),
+			}
+			if v, ok := o.(*types.Var); ok {
+				use.NilChecked = nilChecked(ident, v, info, parentMap)
+			}
+			yield(use)
+			return true
+		})
 	}
+}
 
-	return uses
+// sortUses orders uses by file, line and column. The syntax-only fallback
+// walks files in load order, so without this the output order would depend
+// on the order files were handed to it.
+func sortUses(uses []UseEntry) {
+	sort.Slice(uses, func(i, j int) bool {
+		return rangeLess(uses[i].Range, uses[j].Range)
+	})
 }
 
 func resolveTypeSwitchTargetFromIdent(ident *ast.Ident, info *types.Info, parents map[ast.Node]ast.Node) *typeSwitchTarget {
@@ -449,11 +937,29 @@ func rangeForIdent(fset *token.FileSet, ident *ast.Ident) Range {
 	start := fset.Position(ident.Pos())
 	end := fset.Position(ident.End())
 	return Range{
+		File:  start.Filename,
 		Start: Pos{Line: start.Line - 1, Col: start.Column - 1},
 		End:   Pos{Line: end.Line - 1, Col: end.Column - 1},
 	}
 }
 
+// rangeForObject derives a decl range from obj.Pos() for objects declared
+// outside the checked package, e.g. in a source-imported vendored package.
+func rangeForObject(fset *token.FileSet, obj types.Object) (Range, bool) {
+	if !obj.Pos().IsValid() {
+		return Range{}, false
+	}
+	start := fset.Position(obj.Pos())
+	if !start.IsValid() {
+		return Range{}, false
+	}
+	return Range{
+		File:  start.Filename,
+		Start: Pos{Line: start.Line - 1, Col: start.Column - 1},
+		End:   Pos{Line: start.Line - 1, Col: start.Column - 1 + len(obj.Name())},
+	}, true
+}
+
 func isPointerType(t types.Type) bool {
 	if t == nil {
 		return false
@@ -467,35 +973,22 @@ func isPointerType(t types.Type) bool {
 }
 
 func sameRange(a, b Range) bool {
-	return a.Start.Line == b.Start.Line &&
+	return a.File == b.File &&
+		a.Start.Line == b.Start.Line &&
 		a.Start.Col == b.Start.Col &&
 		a.End.Line == b.End.Line &&
 		a.End.Col == b.End.Col
 }
 
-func keyForRange(r Range) string {
-	return strconv.Itoa(r.Start.Line) + ":" +
-		strconv.Itoa(r.Start.Col) + ":" +
-		strconv.Itoa(r.End.Line) + ":" +
-		strconv.Itoa(r.End.Col)
-}
-
-func buildParentMap(root ast.Node) map[ast.Node]ast.Node {
+// buildPackageParentMap builds one parent map over every file of the
+// package so that uses outside the target file are classified too.
+func buildPackageParentMap(files []*ast.File) map[ast.Node]ast.Node {
 	parents := make(map[ast.Node]ast.Node)
-	var stack []ast.Node
-	ast.Inspect(root, func(n ast.Node) bool {
-		if n == nil {
-			if len(stack) > 0 {
-				stack = stack[:len(stack)-1]
-			}
-			return false
-		}
-		if len(stack) > 0 {
-			parents[n] = stack[len(stack)-1]
+	for _, f := range files {
+		for child, parent := range passes.ParentMap(f) {
+			parents[child] = parent
 		}
-		stack = append(stack, n)
-		return true
-	})
+	}
 	return parents
 }
 
@@ -511,7 +1004,10 @@ func enclosingFunc(node ast.Node, parents map[ast.Node]ast.Node) ast.Node {
 	return nil
 }
 
-func isCaptured(ident *ast.Ident, obj types.Object, declFunc ast.Node, parents map[ast.Node]ast.Node) bool {
+func isCaptured(ident *ast.Ident, obj types.Object, declFunc ast.Node, parents map[ast.Node]ast.Node, opts useOptions) bool {
+	if opts.captureGlobals && isPackageLevel(obj) {
+		return inGoFuncLit(ident, parents)
+	}
 	useFunc := enclosingFunc(ident, parents)
 	if useFunc == nil {
 		return false
@@ -528,6 +1024,115 @@ func isCaptured(ident *ast.Ident, obj types.Object, declFunc ast.Node, parents m
 	return useFunc != declFunc
 }
 
+// isPackageInit reports whether ident is evaluated during package
+// initialization, i.e. it sits in a package-level declaration outside any
+// function literal, or directly in the body of an init function.
+func isPackageInit(ident *ast.Ident, parents map[ast.Node]ast.Node) bool {
+	if _, ok := parents[ident]; !ok {
+		return false
+	}
+	switch fn := enclosingFunc(ident, parents).(type) {
+	case nil:
+		return true
+	case *ast.FuncDecl:
+		return fn.Recv == nil && fn.Name.Name == "init"
+	}
+	return false
+}
+
+// inSelect reports whether ident is inside a select case of its own
+// function.
+func inSelect(ident *ast.Ident, parents map[ast.Node]ast.Node) bool {
+	for n := parents[ident]; n != nil; n = parents[n] {
+		switch n.(type) {
+		case *ast.CommClause:
+			return true
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
+
+// isComparisonOperand reports whether ident, or the selector whose field
+// it names, is an operand of a comparison.
+func isComparisonOperand(ident *ast.Ident, parents map[ast.Node]ast.Node) bool {
+	var operand ast.Node = ident
+	for {
+		switch p := parents[operand].(type) {
+		case *ast.ParenExpr:
+			operand = p
+			continue
+		case *ast.SelectorExpr:
+			if p.Sel == operand {
+				operand = p
+				continue
+			}
+		case *ast.BinaryExpr:
+			switch p.Op {
+			case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isPointerArgument reports whether ident is the operand of an address-of
+// expression that is itself an argument of a call.
+func isPointerArgument(ident *ast.Ident, parents map[ast.Node]ast.Node) bool {
+	var operand ast.Node = ident
+	for {
+		p, ok := parents[operand].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		operand = p
+	}
+	addr, ok := parents[operand].(*ast.UnaryExpr)
+	if !ok || addr.Op != token.AND {
+		return false
+	}
+	operand = addr
+	for {
+		switch p := parents[operand].(type) {
+		case *ast.ParenExpr:
+			operand = p
+		case *ast.CallExpr:
+			for _, arg := range p.Args {
+				if arg == operand {
+					return true
+				}
+			}
+			return false
+		default:
+			return false
+		}
+	}
+}
+
+func isPackageLevel(obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	return ok && !v.IsField() && v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// inGoFuncLit reports whether node is inside a function literal started by a
+// go statement, at any nesting depth.
+func inGoFuncLit(node ast.Node, parents map[ast.Node]ast.Node) bool {
+	for cur := node; cur != nil; cur = parents[cur] {
+		lit, ok := cur.(*ast.FuncLit)
+		if !ok {
+			continue
+		}
+		if call, ok := parents[lit].(*ast.CallExpr); ok && call.Fun == lit {
+			if _, ok := parents[call].(*ast.GoStmt); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func isReassign(ident *ast.Ident, info *types.Info, parents map[ast.Node]ast.Node) bool {
 	for n := ast.Node(ident); n != nil; n = parents[n] {
 		parent := parents[n]
@@ -543,6 +1148,10 @@ func isReassign(ident *ast.Ident, info *types.Info, parents map[ast.Node]ast.Nod
 		case *ast.IncDecStmt:
 			return identIsDirectTarget(ident, stmt.X)
 		case *ast.RangeStmt:
+			// Key and Value are nil in `for range ch` and `for range 10`.
+			// Range-over-func variables are recorded as Defs of the range
+			// clause like any other loop variable, even though the compiler
+			// lowers them to parameters of the yield closure.
 			if !identIsDirectTarget(ident, stmt.Key) && !identIsDirectTarget(ident, stmt.Value) {
 				return false
 			}
@@ -555,6 +1164,133 @@ func isReassign(ident *ast.Ident, info *types.Info, parents map[ast.Node]ast.Nod
 	return false
 }
 
+// reassignedInLoop reports whether one of objs is written inside a loop
+// whose body does not contain its declaration, so that the value carries
+// over from one iteration to the next, or is the key or value of a range
+// clause using =. A for clause variable incremented by its post statement
+// counts as written. Writes inside function literals are attributed to the
+// loops within the literal only.
+//
+// A variable declared inside the loop body is a new variable on every
+// iteration, so writes to it are ordinary assignments; the accumulator
+// pattern this hint is for, such as sum in sumResults, is declared before
+// the loop and updated inside it.
+func reassignedInLoop(info *types.Info, files []*ast.File, objs []types.Object, declIdent *ast.Ident, parents map[ast.Node]ast.Node) bool {
+	objSet := make(map[types.Object]bool)
+	for _, o := range objs {
+		if o != nil {
+			objSet[o] = true
+		}
+	}
+	declaredIn := func(body *ast.BlockStmt) bool {
+		return declIdent != nil && body.Pos() <= declIdent.Pos() && declIdent.Pos() < body.End()
+	}
+	found := false
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if found || !ok || !objSet[info.Uses[ident]] || !isReassign(ident, info, parents) {
+				return !found
+			}
+			for cur, p := ast.Node(ident), parents[ident]; p != nil && !found; cur, p = p, parents[p] {
+				switch loop := p.(type) {
+				case *ast.ForStmt:
+					found = (cur == loop.Body || cur == loop.Post) && !declaredIn(loop.Body)
+				case *ast.RangeStmt:
+					found = cur == loop.Key || cur == loop.Value || cur == loop.Body && !declaredIn(loop.Body)
+				case *ast.FuncLit, *ast.FuncDecl:
+					return false
+				}
+			}
+			return !found
+		})
+		if found {
+			break
+		}
+	}
+	return found
+}
+
+// inferredType returns the type of the right-hand side assigned to t's
+// declaration by :=, picking its element of a multi-value call or comma-ok
+// expression, or the type of t for any other declaration.
+func inferredType(info *types.Info, t *symbolTarget, parents map[ast.Node]ast.Node) types.Type {
+	if t.declIdent == nil || t.typeSwitch != nil {
+		return t.typ(info)
+	}
+	as, ok := parents[t.declIdent].(*ast.AssignStmt)
+	if !ok || as.Tok != token.DEFINE {
+		return t.typ(info)
+	}
+	i := 0
+	for i < len(as.Lhs) && as.Lhs[i] != t.declIdent {
+		i++
+	}
+	switch {
+	case i == len(as.Lhs):
+	case len(as.Rhs) == len(as.Lhs):
+		if typ := info.TypeOf(as.Rhs[i]); typ != nil {
+			return typ
+		}
+	case len(as.Rhs) == 1:
+		if tuple, ok := info.TypeOf(as.Rhs[0]).(*types.Tuple); ok && i < tuple.Len() {
+			return tuple.At(i).Type()
+		}
+	}
+	return t.typ(info)
+}
+
+// passedToGoroutine reports whether one of objs is used inside a go
+// statement, at any depth of its call.
+func passedToGoroutine(info *types.Info, files []*ast.File, objs []types.Object, parents map[ast.Node]ast.Node) bool {
+	objSet := make(map[types.Object]bool)
+	for _, o := range objs {
+		if o != nil {
+			objSet[o] = true
+		}
+	}
+	found := false
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ident, ok := n.(*ast.Ident)
+			if found || !ok || !objSet[info.Uses[ident]] {
+				return !found
+			}
+			for cur := parents[ident]; cur != nil; cur = parents[cur] {
+				if _, ok := cur.(*ast.GoStmt); ok {
+					found = true
+					break
+				}
+			}
+			return !found
+		})
+		if found {
+			break
+		}
+	}
+	return found
+}
+
+// scopeRange returns the extent of the scope obj is declared in, or nil
+// when obj belongs to no scope.
+func scopeRange(fset *token.FileSet, obj types.Object, declIdent *ast.Ident) *Range {
+	if obj == nil || obj.Parent() == nil {
+		return nil
+	}
+	scope := obj.Parent()
+	if scope.Pos().IsValid() {
+		r := rangeForPos(fset, scope.Pos(), scope.End())
+		return &r
+	}
+	// The package scope has no position; use the declaring file.
+	tf := fset.File(declIdent.Pos())
+	if tf == nil {
+		return nil
+	}
+	r := rangeForPos(fset, token.Pos(tf.Base()), token.Pos(tf.Base()+tf.Size()))
+	return &r
+}
+
 func identIsAssignTargetInList(ident *ast.Ident, list []ast.Expr) bool {
 	for _, expr := range list {
 		if identIsDirectTarget(ident, expr) {
@@ -0,0 +1,2710 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fixtureDir = "../../golang_test"
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join(fixtureDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type useWant struct {
+	line     int
+	reassign bool
+	captured bool
+	// col is checked only when non-zero.
+	col int
+}
+
+func checkUses(t *testing.T, out *Output, want []useWant) {
+	t.Helper()
+	if out == nil {
+		t.Fatal("expected a resolved symbol, got nil")
+	}
+	if len(out.Uses) != len(want) {
+		t.Fatalf("got %d uses %+v, want %d", len(out.Uses), out.Uses, len(want))
+	}
+	for i, w := range want {
+		got := out.Uses[i]
+		if w.col != 0 && got.Range.Start.Col != w.col {
+			t.Errorf("use %d: got col %d, want %d", i, got.Range.Start.Col, w.col)
+		}
+		if got.Range.Start.Line != w.line || got.Reassign != w.reassign || got.Captured != w.captured {
+			t.Errorf("use %d: got line %d reassign=%v captured=%v, want line %d reassign=%v captured=%v",
+				i, got.Range.Start.Line, got.Reassign, got.Captured, w.line, w.reassign, w.captured)
+		}
+	}
+}
+
+// TestResolveTypeShadowedByVariable resolves each position of a local
+// variable named like the package's struct type to the right object.
+func TestResolveTypeShadowedByVariable(t *testing.T) {
+	file := fixture(t, "type_var_shadow_check.go")
+	tests := []struct {
+		line, col int
+		declLine  int
+		kind      string
+	}{
+		{2, 5, 2, "type"},
+		{6, 23, 2, "type"},
+		{7, 8, 2, "type"},
+		{12, 1, 12, "var"},
+		{13, 1, 12, "var"},
+		{14, 8, 12, "var"},
+	}
+	for _, tt := range tests {
+		in := Input{File: file, Line: tt.line, Col: tt.col}
+		def := definition(in)
+		if def == nil || def.Name != "shadowedKind" || def.Decl.Start.Line != tt.declLine || def.DeclKind != tt.kind {
+			t.Fatalf("%d:%d: got %+v, want the %s declared on line %d", tt.line, tt.col, def, tt.kind, tt.declLine)
+		}
+		// Resolve only follows variables, so type positions give nil
+		// rather than the variable of the same name.
+		out := resolve(in)
+		if tt.kind == "type" {
+			if out != nil {
+				t.Errorf("%d:%d: got %+v, want nil for the type", tt.line, tt.col, out)
+			}
+			continue
+		}
+		if out == nil || out.Decl.Start.Line != 12 {
+			t.Fatalf("%d:%d: got %+v, want the variable", tt.line, tt.col, out)
+		}
+		checkUses(t, out, []useWant{{line: 13, col: 1, reassign: true}, {line: 14, col: 8}})
+	}
+}
+
+func TestResolveOuterAcrossFunctionBoundaries(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "semantic_check.go"), Line: 35, Col: 1})
+	if out != nil && out.Name != "outer" {
+		t.Fatalf("resolved %q, want outer", out.Name)
+	}
+	checkUses(t, out, []useWant{
+		{line: 37, reassign: true, captured: true}, // outer++ in the named closure f
+		{line: 38, captured: true},                 // return outer in f
+		{line: 44, captured: true},                 // goroutine
+		{line: 54},                                 // p := &outer in the outer scope
+		{line: 58, captured: true},                 // fn closure
+	})
+}
+
+func TestResolveOnVeryLongLine(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("package gen\n\nvar table = []int{")
+	for b.Len() < 100*1024 {
+		b.WriteString("1, 2, 3, 4, 5, 6, 7, 8, 9, ")
+	}
+	b.WriteString("0}; func lookup(i int) int { v := table[i]; return v + len(table) }\n")
+	src := b.String()
+	path := filepath.Join(t.TempDir(), "gen.go")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	line := strings.Split(src, "\n")[2]
+	col := strings.Index(line, "v := ")
+	out := resolve(Input{File: path, Line: 2, Col: col})
+	if out == nil || out.Name != "v" {
+		t.Fatalf("got %+v, want v", out)
+	}
+	if out.Decl.Start.Col != col {
+		t.Fatalf("decl col %d, want %d", out.Decl.Start.Col, col)
+	}
+	if len(out.Uses) != 1 || out.Uses[0].Range.Start.Col != strings.Index(line, "v + len") {
+		t.Fatalf("got uses %+v", out.Uses)
+	}
+
+	if out := resolve(Input{File: path, Line: 2, Col: len(line) + 10}); out != nil {
+		t.Fatalf("column past the end of the line resolved %q", out.Name)
+	}
+}
+
+func TestResolveRangeKeyAndValueIndependently(t *testing.T) {
+	file := fixture(t, "main.go")
+	cases := []struct {
+		name      string
+		line, col int
+		want      []useWant
+	}{
+		{"i", 67, 5, []useWant{{line: 68, col: 13}}},
+		{"v", 67, 8, []useWant{{line: 68, col: 9}}},
+		{"i", 71, 5, []useWant{{line: 72, col: 6}}},
+		{"v", 71, 8, []useWant{{line: 73, col: 5}, {line: 76, col: 15}, {line: 76, col: 20}}},
+		{"v", 35, 8, []useWant{{line: 36, col: 9}}}, // for _, v := range p.results
+	}
+	for _, tc := range cases {
+		out := resolve(Input{File: file, Line: tc.line, Col: tc.col})
+		if out == nil || out.Name != tc.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tc.line, tc.col, out, tc.name)
+		}
+		if out.Decl.Start.Line != tc.line || out.Decl.Start.Col != tc.col {
+			t.Errorf("%d:%d: decl at %+v", tc.line, tc.col, out.Decl.Start)
+		}
+		checkUses(t, out, tc.want)
+	}
+}
+
+func TestResolveForPostStatement(t *testing.T) {
+	// for i := 0; i < 2; i++ { go func() { ... globalCounter += i ... }() }
+	file := fixture(t, "main.go")
+	for _, col := range []int{5, 13, 20} {
+		out := resolve(Input{File: file, Line: 82, Col: col})
+		if out == nil || out.Name != "i" || out.Decl.Start.Line != 82 || out.Decl.Start.Col != 5 {
+			t.Fatalf("col %d: got %+v, want the loop variable declared at 82:5", col, out)
+		}
+		checkUses(t, out, []useWant{
+			{line: 82, col: 13},                 // condition
+			{line: 82, col: 20, reassign: true}, // i++ reads and writes i
+			{line: 85, col: 20, captured: true}, // body
+		})
+	}
+}
+
+func TestRelativizeUses(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "semantic_check.go"), Line: 35, Col: 1})
+	if out == nil {
+		t.Fatal("expected outer to resolve")
+	}
+	abs := make([]UseEntry, len(out.Uses))
+	copy(abs, out.Uses)
+	out.relativizeUses()
+	for i, u := range out.Uses {
+		if want := abs[i].Range.Start.Line - 35; u.Range.Start.Line != want {
+			t.Errorf("use %d: start line delta %d, want %d", i, u.Range.Start.Line, want)
+		}
+		if want := abs[i].Range.End.Line - 35; u.Range.End.Line != want {
+			t.Errorf("use %d: end line delta %d, want %d", i, u.Range.End.Line, want)
+		}
+		if u.Range.Start.Col != abs[i].Range.Start.Col {
+			t.Errorf("use %d: column changed from %d to %d", i, abs[i].Range.Start.Col, u.Range.Start.Col)
+		}
+	}
+	if out.Decl.Start.Line != 35 {
+		t.Errorf("decl line changed to %d", out.Decl.Start.Line)
+	}
+}
+
+func TestFindIdentAtEveryPosition(t *testing.T) {
+	src := "package p\n\nfunc f(ab int) int {\n\tc := ab + 1\n\treturn c\n}\n"
+	type span struct {
+		name            string
+		line, from, end int
+	}
+	idents := []span{
+		{"p", 0, 8, 9},
+		{"f", 2, 5, 6},
+		{"ab", 2, 7, 9},
+		{"int", 2, 10, 13},
+		{"int", 2, 15, 18},
+		{"c", 3, 1, 2},
+		{"ab", 3, 6, 8},
+		{"c", 4, 8, 9},
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(src, "\n")
+	for line, text := range lines {
+		for col := 0; col <= len(text)+2; col++ {
+			// An identifier containing col wins over one ending at it.
+			var want, ending *span
+			for i := range idents {
+				id := &idents[i]
+				if id.line == line && id.from <= col && col < id.end {
+					want = id
+				}
+				if id.line == line && id.end == col {
+					ending = id
+				}
+			}
+			if want == nil {
+				want = ending
+			}
+			got, _ := findIdentAtPosition(fset, file, line, col)
+			switch {
+			case want == nil && got != nil:
+				t.Errorf("%d:%d: got %q, want none", line, col, got.Name)
+			case want != nil && got == nil:
+				t.Errorf("%d:%d: got none, want %q", line, col, want.name)
+			case want != nil && got != nil:
+				pos := fset.Position(got.Pos())
+				if got.Name != want.name || pos.Line-1 != want.line || pos.Column-1 != want.from {
+					t.Errorf("%d:%d: got %q at %d:%d, want %q at %d:%d",
+						line, col, got.Name, pos.Line-1, pos.Column-1, want.name, want.line, want.from)
+				}
+			}
+		}
+	}
+}
+
+func TestResolveAtIdentifierBoundaries(t *testing.T) {
+	// outer occupies columns 1-6 of line 35; column 6 is just past it.
+	file := fixture(t, "semantic_check.go")
+	for _, col := range []int{1, 3, 6} {
+		out := resolve(Input{File: file, Line: 35, Col: col})
+		if out == nil || out.Name != "outer" {
+			t.Errorf("col %d: got %+v, want outer", col, out)
+		}
+	}
+	if out := resolve(Input{File: file, Line: 35, Col: 0}); out != nil {
+		t.Errorf("col 0: got %q, want none", out.Name)
+	}
+}
+
+func TestResolveEachLevelOfSelectorChain(t *testing.T) {
+	file := fixture(t, "chains/main.go")
+	cases := []struct {
+		line, col int
+		want      string // "" means no symbol
+	}{
+		// r.Mid.Leaf.Value = 1
+		{19, 1, "r"},
+		{19, 2, "r"}, // dot, just past r
+		{19, 3, "Mid"},
+		{19, 7, "Leaf"},
+		{19, 12, "Value"},
+		// r.Mid.Ptr.Bump()
+		{20, 1, "r"},
+		{20, 3, "Mid"},
+		{20, 7, "Ptr"},
+		{20, 11, ""}, // method terminal
+		// x, y := ...
+		{21, 1, "x"},
+		{21, 4, "y"},
+	}
+	for _, tc := range cases {
+		out := resolve(Input{File: file, Line: tc.line, Col: tc.col})
+		switch {
+		case tc.want == "" && out != nil:
+			t.Errorf("%d:%d: got %q, want none", tc.line, tc.col, out.Name)
+		case tc.want != "" && (out == nil || out.Name != tc.want):
+			t.Errorf("%d:%d: got %+v, want %q", tc.line, tc.col, out, tc.want)
+		}
+	}
+}
+
+func TestResolveInMultiPlatformPackage(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "multios/main.go"), Line: 5, Col: 1})
+	if out == nil || out.Name != "conn" || out.Degraded {
+		t.Fatalf("got %+v, want conn without degradation", out)
+	}
+
+	// impl_windows.go is the target, so it is analyzed even on other hosts;
+	// its connect conflicts with impl_linux.go unless that file is dropped.
+	out = resolve(Input{File: fixture(t, "multios/impl_windows.go"), Line: 3, Col: 1, WantDiagnostics: true})
+	if out == nil || out.Name != "addr" || out.Degraded {
+		t.Fatalf("got %+v, want addr without degradation", out)
+	}
+	for _, d := range out.LoadDiagnostics {
+		if filepath.Base(d.File) == "impl_windows.go" {
+			t.Errorf("target file reported as skipped: %+v", d)
+		}
+	}
+}
+
+// TestConflictingDecls finds redeclarations across files from the syntax
+// alone, without relying on the wording of the checker's errors.
+func TestConflictingDecls(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  bool
+	}{
+		{[]string{"func connect() {}", "func connect() {}"}, true},
+		{[]string{"var limit = 1", "func limit() {}"}, true},
+		{[]string{"type conn struct{}", "const (\n\ta = 1\n\tconn = 2\n)"}, true},
+		{[]string{"type T struct{}\nfunc (T) m() {}", "func (*T) m() {}"}, true},
+		{[]string{"type T struct{}\ntype U struct{}\nfunc (T) m() {}", "func (U) m() {}\nfunc m() {}"}, false},
+		{[]string{"func init() {}\nvar _ = 1", "func init() {}\nvar _ = 2"}, false},
+	}
+	for _, tt := range tests {
+		fset := token.NewFileSet()
+		var files []*ast.File
+		for i, src := range tt.files {
+			f, err := parser.ParseFile(fset, fmt.Sprintf("f%d.go", i), "package p\n\n"+src+"\n", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, f)
+		}
+		if got := hasConflictingDecls(files); got != tt.want {
+			t.Errorf("%q: got %v, want %v", tt.files, got, tt.want)
+		}
+	}
+}
+
+// TestResolveWithRemainingConflicts degrades the result when conflicting
+// declarations are not explained by build constraints, while still
+// resolving an unrelated variable.
+func TestResolveWithRemainingConflicts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module conflict\n\ngo 1.20\n",
+		"a.go":   "package conflict\n\nfunc connect() {}\n\nfunc run() int {\n\tn := 1\n\treturn n\n}\n",
+		"b.go":   "package conflict\n\nfunc connect() {}\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := resolve(Input{File: filepath.Join(dir, "a.go"), Line: 5, Col: 1})
+	if out == nil || out.Name != "n" || !out.Degraded {
+		t.Fatalf("got %+v, want n with a degraded result", out)
+	}
+	checkUses(t, out, []useWant{{line: 6, col: 8}})
+}
+
+func TestResolveOutsideModule(t *testing.T) {
+	dir := t.TempDir()
+	if root := findModuleRoot(dir); root != "" {
+		t.Skipf("temp dir is inside the module at %s", root)
+	}
+	files := map[string]string{
+		"main.go":  "package main\n\nimport \"strings\"\n\nfunc main() {\n\tname := strings.TrimSpace(\" x \")\n\tgreet(name)\n\tprintln(name, limit)\n}\n",
+		"greet.go": "package main\n\nconst limit = 3\n\nfunc greet(s string) { println(s) }\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := filepath.Join(dir, "main.go")
+	out := resolve(Input{File: target, Line: 5, Col: 1})
+	if out == nil || out.Name != "name" {
+		t.Fatalf("got %+v, want name", out)
+	}
+	if out.Degraded {
+		t.Error("got a degraded result, want strings imported from the standard library")
+	}
+	checkUses(t, out, []useWant{{line: 6, col: 7}, {line: 7, col: 9}})
+
+	// Sibling files of the directory form the package.
+	def := definition(Input{File: target, Line: 7, Col: 15})
+	if def == nil || def.Name != "limit" || filepath.Base(def.Decl.File) != "greet.go" {
+		t.Errorf("got %+v, want limit declared in greet.go", def)
+	}
+}
+
+func TestResolveWithBrokenSiblingFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good.go":   "package p\n\nfunc total(xs []int) int {\n\tsum := 0\n\tfor _, x := range xs {\n\t\tsum += x\n\t}\n\treturn sum + helper()\n}\n",
+		"helper.go": "package p\n\nfunc helper() int { return 1 }\n",
+		"broken.go": "package p\n\nfunc broken( {\n\treturn\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target := filepath.Join(dir, "good.go")
+	out := resolve(Input{File: target, Line: 3, Col: 1, WantDiagnostics: true})
+	if out == nil || out.Name != "sum" {
+		t.Fatalf("got %+v, want sum", out)
+	}
+	checkUses(t, out, []useWant{{line: 5, reassign: true}, {line: 7}})
+	skipped := false
+	for _, d := range out.LoadDiagnostics {
+		skipped = skipped || filepath.Base(d.File) == "broken.go" && d.Reason == "parse_error"
+	}
+	if !skipped {
+		t.Errorf("got diagnostics %+v, want broken.go skipped with a parse error", out.LoadDiagnostics)
+	}
+
+	lp := loadPackage(Input{File: target})
+	if lp == nil || lp.syntaxOnly {
+		t.Fatal("package did not type-check")
+	}
+	for _, f := range lp.files {
+		if f == nil || filepath.Base(lp.fset.Position(f.Pos()).Filename) == "broken.go" {
+			t.Fatalf("files contains a nil or broken file: %v", lp.files)
+		}
+	}
+	if len(lp.files) != 2 {
+		t.Errorf("got %d files, want good.go and helper.go", len(lp.files))
+	}
+	def := definition(Input{File: target, Line: 7, Col: 14})
+	if def == nil || def.Name != "helper" || filepath.Base(def.Decl.File) != "helper.go" {
+		t.Errorf("got %+v, want helper declared in helper.go", def)
+	}
+}
+
+func TestBuildIgnoredFilesExcluded(t *testing.T) {
+	dir := t.TempDir()
+	// Each ignored file redeclares limit with another type, which would
+	// make the checker report a conflict if it were loaded.
+	files := map[string]string{
+		"main.go":       "package p\n\nvar limit = 10\n\nfunc twice() int { return limit * 2 }\n",
+		"gen.go":        "//go:build ignore\n\npackage p\n\nvar limit = \"generated\"\n",
+		"_scratch.go":   "package p\n\nvar limit = 1.5\n",
+		".backup.go":    "package p\n\nvar limit = []int{}\n",
+		"testdata/x.go": "package p\n\nvar limit = false\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "testdata"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lp := loadPackage(Input{File: filepath.Join(dir, "main.go")})
+	if lp == nil || lp.degraded || len(lp.files) != 1 {
+		t.Fatalf("got %+v, want main.go alone without degradation", lp)
+	}
+	excluded := make(map[string]bool)
+	for _, d := range lp.diagnostics {
+		if d.Reason == "build_constraints" {
+			excluded[filepath.Base(d.File)] = true
+		}
+	}
+	for _, name := range []string{"gen.go", "_scratch.go", ".backup.go"} {
+		if !excluded[name] {
+			t.Errorf("%s not reported as excluded: %+v", name, lp.diagnostics)
+		}
+	}
+	def := definition(Input{File: filepath.Join(dir, "main.go"), Line: 4, Col: 26})
+	if def == nil || def.Name != "limit" || def.Type != "int" {
+		t.Fatalf("got %+v, want limit of type int", def)
+	}
+}
+
+func TestLegacyPlusBuildFileExcluded(t *testing.T) {
+	lp := loadPackage(Input{File: fixture(t, "business_heavy.go")})
+	if lp == nil {
+		t.Fatal("package did not load")
+	}
+	for _, f := range lp.files {
+		if name := filepath.Base(lp.fset.Position(f.Pos()).Filename); name == "legacy_build_tag.go" {
+			t.Fatalf("%s with // +build ignore was loaded", name)
+		}
+	}
+	excluded := false
+	for _, d := range lp.diagnostics {
+		if filepath.Base(d.File) == "legacy_build_tag.go" && d.Reason == "build_constraints" {
+			excluded = true
+		}
+	}
+	if !excluded {
+		t.Errorf("no build_constraints diagnostic for legacy_build_tag.go in %+v", lp.diagnostics)
+	}
+}
+
+func TestResolveThroughConversions(t *testing.T) {
+	file := fixture(t, "conversions/main.go")
+	x := resolve(Input{File: file, Line: 9, Col: 1})
+	if x == nil || x.Name != "x" {
+		t.Fatalf("got %+v, want x", x)
+	}
+	checkUses(t, x, []useWant{{line: 10, col: 15}}) // int64(x)
+
+	pool := resolve(Input{File: file, Line: 11, Col: 1})
+	if pool == nil || pool.Name != "pool" {
+		t.Fatalf("got %+v, want pool", pool)
+	}
+	checkUses(t, pool, []useWant{
+		{line: 12, col: 20}, // interface{}(pool)
+		{line: 13, col: 20}, // (*WorkerPool)(pool)
+	})
+}
+
+func TestResolveDocComments(t *testing.T) {
+	file := fixture(t, "comments_layout_check.go")
+	cases := []struct {
+		line, col int
+		name, doc string
+	}{
+		{11, 5, "CommentNoiseState", "CommentNoiseState is a playground for comment-heavy code paths."},
+		{14, 1, "plainCounter", "plainCounter is intentionally touched from goroutine without lock in one place."},
+		{15, 1, "guarded", ""},
+	}
+	for _, tc := range cases {
+		out := resolve(Input{File: file, Line: tc.line, Col: tc.col, WantDoc: true})
+		if out == nil || out.Name != tc.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tc.line, tc.col, out, tc.name)
+		}
+		if out.Doc != tc.doc {
+			t.Errorf("%s: doc %q, want %q", tc.name, out.Doc, tc.doc)
+		}
+	}
+	if out := resolve(Input{File: file, Line: 11, Col: 5}); out != nil {
+		t.Errorf("type resolved without want_doc: %+v", out.Name)
+	}
+}
+
+func TestDefinitionMode(t *testing.T) {
+	file := fixture(t, "business_heavy.go")
+	cases := []struct {
+		line, col       int
+		name, kind, typ string
+		declLine        int
+	}{
+		{39, 6, "p", "param", "*FixedPricing", 39},
+		{40, 4, "o", "param", "*Order", 39},
+		{77, 3, "byUser", "field", "map[int64][]int64", 53},
+		{73, 10, "App", "type", "App", 50},
+		{63, 5, "NewApp", "func", "func(queueSize int) *App", 63},
+		{80, 14, "Enqueue", "method", "func(id int64) error", 80},
+		{237, 8, "v", "var", "interface{}", 237}, // type switch guard
+		{140, 1, "o", "var", "*Order", 140},      // local at the top of a body
+	}
+	for _, tc := range cases {
+		out := definition(Input{File: file, Line: tc.line, Col: tc.col})
+		if out == nil {
+			t.Fatalf("%d:%d: got nil, want %s", tc.line, tc.col, tc.name)
+		}
+		if out.Name != tc.name || out.DeclKind != tc.kind || out.Type != tc.typ || out.Decl.Start.Line != tc.declLine {
+			t.Errorf("%d:%d: got %s %s %q at line %d, want %s %s %q at line %d", tc.line, tc.col,
+				out.Name, out.DeclKind, out.Type, out.Decl.Start.Line, tc.name, tc.kind, tc.typ, tc.declLine)
+		}
+	}
+}
+
+type failingImporter struct{}
+
+func (failingImporter) Import(path string) (*types.Package, error) {
+	return nil, errors.New("no toolchain")
+}
+
+func TestSyntaxOnlyFallbackWhenImportsFail(t *testing.T) {
+	saved := defaultImporter
+	defaultImporter = func() types.Importer { return failingImporter{} }
+	packageCache.reset()
+	defer func() {
+		defaultImporter = saved
+		packageCache.reset()
+	}()
+
+	out := resolve(Input{File: fixture(t, "semantic_check.go"), Line: 35, Col: 1})
+	if out == nil || !out.Degraded {
+		t.Fatalf("got %+v, want a degraded result", out)
+	}
+	checkUses(t, out, []useWant{
+		{line: 37, reassign: true, captured: true},
+		{line: 38, captured: true},
+		{line: 44, captured: true},
+		{line: 54},
+		{line: 58, captured: true},
+	})
+
+	global := resolve(Input{File: fixture(t, "main.go"), Line: 9, Col: 4})
+	if global == nil || global.Name != "globalCounter" {
+		t.Fatalf("got %+v, want globalCounter", global)
+	}
+	checkUses(t, global, []useWant{{line: 12, reassign: true}, {line: 85, reassign: true}})
+
+	sel := resolve(Input{File: fixture(t, "main.go"), Line: 67, Col: 24}) // pool.tasks
+	if sel == nil || sel.Error == "" {
+		t.Fatalf("got %+v, want a requires-type-information error", sel)
+	}
+}
+
+func TestHoverGolden(t *testing.T) {
+	if strconv.IntSize != 64 {
+		t.Skip("golden sizes assume a 64-bit host")
+	}
+	cases := []struct {
+		file      string
+		line, col int
+		want      string
+	}{
+		{"main.go", 23, 1, `{"name":"results","decl":{"start":{"line":23,"col":1},"end":{"line":23,"col":8}},"decl_kind":"field","type":"map[int]int","size":8,"owner":"WorkerPool"}`},
+		{"hover_check.go", 10, 1, `{"name":"pending","decl":{"start":{"line":10,"col":1},"end":{"line":10,"col":8}},"decl_kind":"field","type":"[]string","size":24,"doc":"pending holds jobs that have not been handed to a worker yet.","owner":"jobQueue","guarded_by":"mu"}`},
+		{"hover_check.go", 30, 21, `{"name":"maxRetries","decl":{"start":{"line":5,"col":6},"end":{"line":5,"col":16}},"decl_kind":"const","type":"untyped int","doc":"maxRetries bounds how often a failed job is put back on the queue.","value":"3"}`},
+		{"main.go", 51, 1, `{"name":"result","decl":{"start":{"line":46,"col":21},"end":{"line":46,"col":27}},"decl_kind":"result","type":"int","size":8}`},
+		{"business_heavy.go", 237, 8, `{"name":"v","decl":{"start":{"line":237,"col":8},"end":{"line":237,"col":9}},"decl_kind":"var","type":"interface{}","size":16}`},
+	}
+	for _, tc := range cases {
+		out := hover(Input{File: fixture(t, tc.file), Line: tc.line, Col: tc.col})
+		if out == nil {
+			t.Fatalf("%s:%d:%d: got nil", tc.file, tc.line, tc.col)
+		}
+		out.Decl.File = ""
+		got, err := json.Marshal(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s:%d:%d:\n got %s\nwant %s", tc.file, tc.line, tc.col, got, tc.want)
+		}
+	}
+}
+
+func TestHoverImplements(t *testing.T) {
+	file := fixture(t, "implements_check.go")
+	cases := []struct {
+		line, col     int
+		name          string
+		err, stringer bool
+		implements    string
+	}{
+		{4, 5, "level", false, true, "[fmt.Stringer]"},
+		// Error has a pointer receiver, which counts for the type name.
+		{8, 5, "parseError", true, false, "[error]"},
+		{13, 5, "current", false, true, "[fmt.Stringer]"},
+		{14, 1, "err", true, false, "[error]"},
+		{15, 1, "plain", false, false, "[]"},
+	}
+	for _, tc := range cases {
+		out := hover(Input{File: file, Line: tc.line, Col: tc.col})
+		if out == nil || out.Name != tc.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tc.line, tc.col, out, tc.name)
+		}
+		if out.ImplementsError != tc.err || out.ImplementsStringer != tc.stringer || fmt.Sprint(out.Implements) != tc.implements {
+			t.Errorf("%s: got error=%v stringer=%v implements=%v", tc.name, out.ImplementsError, out.ImplementsStringer, out.Implements)
+		}
+	}
+
+	// The configured set replaces the defaults; unknown names are ignored.
+	out := hover(Input{File: file, Line: 8, Col: 5, Interfaces: []string{"io.Closer", "error", "nosuch.Iface"}})
+	if out == nil || fmt.Sprint(out.Implements) != "[error]" || !out.ImplementsError {
+		t.Fatalf("got %+v, want only error from the configured set", out)
+	}
+}
+
+func TestRenameCheck(t *testing.T) {
+	file := fixture(t, "renames/main.go")
+	cases := []struct {
+		line, col int
+		newName   string
+		edits     int
+		// conflicts are the start lines of the reported conflicts.
+		conflicts []int
+	}{
+		{7, 1, "mult", 3, nil},
+		{7, 1, "step", 3, []int{11}},  // shadowed inside the loop
+		{7, 1, "total", 3, []int{8}},  // same scope
+		{7, 1, "limit", 3, []int{9}},  // captures the loop's reference to limit
+		{7, 1, "func", 0, []int{7}},   // keyword
+		{7, 1, "2x", 0, []int{7}},     // not an identifier
+		{4, 4, "count", 3, []int{18}}, // shadowed by report's local
+		{4, 4, "fmt", 3, []int{2, 9, 18}},
+		{22, 1, "height", 2, []int{23}}, // existing field
+		{22, 1, "area", 2, []int{26}},   // existing method
+	}
+	for _, tc := range cases {
+		out := renameCheck(Input{File: file, Line: tc.line, Col: tc.col, NewName: tc.newName})
+		if out == nil {
+			t.Fatalf("%d:%d -> %s: got nil", tc.line, tc.col, tc.newName)
+		}
+		var lines []int
+		for _, c := range out.Conflicts {
+			lines = append(lines, c.Range.Start.Line)
+		}
+		if len(out.Edits) != tc.edits || fmt.Sprint(lines) != fmt.Sprint(tc.conflicts) {
+			t.Errorf("%s -> %s: got %d edits, conflicts %+v; want %d edits, conflicts on lines %v",
+				out.Name, tc.newName, len(out.Edits), out.Conflicts, tc.edits, tc.conflicts)
+		}
+	}
+}
+
+func TestStreamMatchesBatch(t *testing.T) {
+	in := Input{File: fixture(t, "multifile/b.go"), Line: 4, Col: 1}
+	batch := resolve(in)
+	if batch == nil || len(batch.Uses) != 3 {
+		t.Fatalf("got %+v, want shared with uses in both files", batch)
+	}
+
+	var buf bytes.Buffer
+	s := &useStream{enc: json.NewEncoder(&buf)}
+	resolveTo(in, s)
+	dec := json.NewDecoder(&buf)
+	var header Output
+	if err := dec.Decode(&header); err != nil {
+		t.Fatal(err)
+	}
+	if header.Name != "shared" || header.Uses != nil || header.Decl != batch.Decl {
+		t.Fatalf("got header %+v", header)
+	}
+	for i, want := range batch.Uses {
+		var got UseEntry
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("use %d: %v", i, err)
+		}
+		if got != want {
+			t.Errorf("use %d: got %+v, want %+v", i, got, want)
+		}
+	}
+	var end streamEnd
+	if err := dec.Decode(&end); err != nil || !end.Done || end.Count != len(batch.Uses) {
+		t.Fatalf("got end %+v (%v), want done with %d uses", end, err, len(batch.Uses))
+	}
+}
+
+func TestPrepareRename(t *testing.T) {
+	file := fixture(t, "renames/main.go")
+	cases := []struct {
+		line, col int
+		name      string
+		refusal   string
+	}{
+		{7, 1, "factor", ""},
+		{35, 1, "label", ""},
+		{39, 8, "x", ""}, // type switch guard
+		{42, 9, "x", ""}, // use of the guard in a clause
+		{41, 2, "_", "blank"},
+		{41, 16, "len", "builtin"},
+		{34, 1, "box", "embedded"},
+		{41, 11, "box", "embedded"},
+		{45, 8, "fmt", "package_name"},
+		{45, 12, "Sprint", "external"},
+	}
+	for _, tc := range cases {
+		out := prepareRename(Input{File: file, Line: tc.line, Col: tc.col})
+		if out == nil || out.Name != tc.name || out.Range.Start.Line != tc.line || out.Range.Start.Col != tc.col {
+			t.Fatalf("%d:%d: got %+v, want %s at the cursor", tc.line, tc.col, out, tc.name)
+		}
+		refusal := ""
+		if out.Refusal != nil {
+			refusal = out.Refusal.Code
+		}
+		if refusal != tc.refusal {
+			t.Errorf("%d:%d: got refusal %q, want %q", tc.line, tc.col, refusal, tc.refusal)
+		}
+
+		// rename_check must agree: a refused symbol always conflicts and an
+		// accepted one can be renamed to a fresh name.
+		check := renameCheck(Input{File: file, Line: tc.line, Col: tc.col, NewName: "fresh"})
+		if check == nil || (len(check.Conflicts) > 0) != (tc.refusal != "") {
+			t.Errorf("%d:%d: rename_check disagrees with prepare_rename: %+v", tc.line, tc.col, check)
+		}
+	}
+}
+
+func TestResolveTypeAssertionUses(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "business_heavy.go"), Line: 50, Col: 5, WantDoc: true})
+	if out == nil || out.Name != "App" {
+		t.Fatalf("got %+v, want App", out)
+	}
+	assertions := fixture(t, "type_assertion_check.go")
+	var got []string
+	for _, u := range out.Uses {
+		if u.Range.File == assertions {
+			got = append(got, fmt.Sprintf("%d:%d", u.Range.Start.Line, u.Range.Start.Col))
+		}
+	}
+	// Result type, comma-ok assertion, plain assertion, and both type
+	// switch case types.
+	if want := "[3:21 4:19 7:12 12:7 12:12]"; fmt.Sprint(got) != want {
+		t.Errorf("got uses %v in the assertion fixture, want %s", got, want)
+	}
+}
+
+func TestResolveComparisonOperands(t *testing.T) {
+	file := fixture(t, "main.go")
+	tests := []struct {
+		line, col int
+		name      string
+		want      string
+	}{
+		{107, 4, "err", "[108:4=true]"},          // if err == nil
+		{62, 4, "x", "[62:16=true 63:24=false]"}, // if x := x + 1; x > 1
+		{62, 9, "x", "[62:9=false 65:22=false]"}, // the outer x in x + 1
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tt.line, tt.col, out, tt.name)
+		}
+		var got []string
+		for _, u := range out.Uses {
+			got = append(got, fmt.Sprintf("%d:%d=%v", u.Range.Start.Line, u.Range.Start.Col, u.InComparison))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%d:%d: got %v, want %s", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePointerArguments(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "pointer_arg_check.go"), Line: 11, Col: 1})
+	if out == nil || out.Name != "n" {
+		t.Fatalf("got %+v, want n", out)
+	}
+	var got []string
+	for _, u := range out.Uses {
+		got = append(got, fmt.Sprintf("%d:%d=%v", u.Range.Start.Line, u.Range.Start.Col, u.PassedByPointer))
+	}
+	// scanInto(&n) and fmt.Sscan(..., &n) pass it; p := &n and return n do not.
+	if want := "[12:11=true 13:24=true 14:7=false 16:8=false]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestResolveInSelect(t *testing.T) {
+	file := fixture(t, "business_heavy.go")
+	tests := []struct {
+		line, col int
+		want      string
+	}{
+		// id := <-a.queue in the worker's select, used in the case body.
+		{99, 10, "[100:40=true]"},
+		// make(chan int64, ...) in NewApp, the send in Enqueue's select and
+		// the worker's receive.
+		{99, 20, "[68:2=false 82:8=true 99:19=true]"},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%d:%d: got nil", tt.line, tt.col)
+		}
+		var got []string
+		for _, u := range out.Uses {
+			got = append(got, fmt.Sprintf("%d:%d=%v", u.Range.Start.Line, u.Range.Start.Col, u.InSelect))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%d:%d: got %v, want %s", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestResolveReassignedInLoop(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		want      bool
+	}{
+		// sum accumulated by the range loop in main.
+		{"main.go", 66, 1, true},
+		// x, never written in a loop.
+		{"main.go", 61, 1, false},
+		// i, declared by the range clause with :=.
+		{"main.go", 67, 5, false},
+		// i of a for clause, incremented by the post statement.
+		{"business_heavy.go", 90, 5, true},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: fixture(t, tt.file), Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%s %d:%d: got nil", tt.file, tt.line, tt.col)
+		}
+		if out.ReassignedInLoop != tt.want {
+			t.Errorf("%s %d:%d (%s): ReassignedInLoop = %v, want %v", tt.file, tt.line, tt.col, out.Name, out.ReassignedInLoop, tt.want)
+		}
+	}
+}
+
+func TestResolvePassedToGoroutine(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		want      bool
+	}{
+		// globalCounter, updated by the goroutines of main's loop.
+		{"main.go", 9, 4, true},
+		// outer, captured by a goroutine after a plain closure.
+		{"semantic_check.go", 35, 1, true},
+		// out, passed as the argument of go produceInto(out).
+		{"unclosed_chan_check.go", 46, 1, true},
+		// total, only captured by a closure that is called directly.
+		{"main.go", 78, 1, false},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: fixture(t, tt.file), Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%s %d:%d: got nil", tt.file, tt.line, tt.col)
+		}
+		if out.PassedToGoroutine != tt.want {
+			t.Errorf("%s %d:%d (%s): PassedToGoroutine = %v, want %v", tt.file, tt.line, tt.col, out.Name, out.PassedToGoroutine, tt.want)
+		}
+	}
+}
+
+func TestResolveInferredType(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		want      string
+	}{
+		// fee, ok := p.FeeByTier[o.CustomerTier]
+		{"business_heavy.go", 43, 1, "int64"},
+		{"business_heavy.go", 43, 6, "bool"},
+		// for i := 0; ... gets the default type of the constant.
+		{"business_heavy.go", 90, 5, "int"},
+		{"business_heavy.go", 109, 2, "*time.Ticker"},
+		{"business_heavy.go", 146, 1, "Order"},
+		// v, err := engine.DynamicFee(&snapshot)
+		{"business_heavy.go", 161, 2, "int64"},
+		{"business_heavy.go", 161, 5, "error"},
+		// A var declaration keeps its declared type.
+		{"main.go", 9, 4, "int"},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: fixture(t, tt.file), Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%s %d:%d: got nil", tt.file, tt.line, tt.col)
+		}
+		if out.InferredType != tt.want {
+			t.Errorf("%s %d:%d (%s): InferredType = %q, want %q", tt.file, tt.line, tt.col, out.Name, out.InferredType, tt.want)
+		}
+	}
+}
+
+func TestResolveScopeRange(t *testing.T) {
+	file := fixture(t, "main.go")
+	tests := []struct {
+		line, col int
+		want      string
+	}{
+		// x declared by the if statement's init is scoped to the if.
+		{62, 4, "62:1-64:2"},
+		// The outer x belongs to main's function scope.
+		{61, 1, "55:0-113:1"},
+		// A package var spans the whole file.
+		{9, 5, "0:0-113:2"},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil || out.ScopeRange == nil {
+			t.Fatalf("%d:%d: got %+v, want a scope range", tt.line, tt.col, out)
+		}
+		r := out.ScopeRange
+		got := fmt.Sprintf("%d:%d-%d:%d", r.Start.Line, r.Start.Col, r.End.Line, r.End.Col)
+		if got != tt.want || r.File != file {
+			t.Errorf("%d:%d: got %s in %s, want %s", tt.line, tt.col, got, r.File, tt.want)
+		}
+	}
+
+	// Fields have no scope.
+	out := resolve(Input{File: file, Line: 35, Col: 21})
+	if out == nil || out.ScopeRange != nil {
+		t.Errorf("got %+v, want no scope range for the results field", out)
+	}
+}
+
+func TestResolveByOffset(t *testing.T) {
+	file := fixture(t, "main.go")
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sum at 66:1.
+	lines := strings.SplitAfter(string(src), "\n")
+	offset := 1
+	for _, l := range lines[:66] {
+		offset += len(l)
+	}
+
+	in := Input{File: file, Offset: &offset}
+	line, col, ok := offsetPosition(in)
+	if !ok || line != 66 || col != 1 {
+		t.Fatalf("got %d:%d (ok %v), want 66:1", line, col, ok)
+	}
+	byPos := resolve(Input{File: file, Line: 66, Col: 1})
+	in.Line, in.Col = line, col
+	byOffset := resolve(in)
+	if byPos == nil || byOffset == nil || byOffset.Name != "sum" || byOffset.Decl != byPos.Decl {
+		t.Errorf("got %+v, want the same symbol as %+v", byOffset, byPos)
+	}
+
+	lp := loadPackage(in)
+	if ident, _ := findIdentAtOffset(lp.fset, lp.file, offset); ident == nil || ident.Name != "sum" {
+		t.Errorf("got %v, want sum", ident)
+	}
+	for _, bad := range []int{-1, len(src) + 1} {
+		bad := bad
+		if _, _, ok := offsetPosition(Input{File: file, Offset: &bad}); ok {
+			t.Errorf("offset %d: got ok, want out of range", bad)
+		}
+	}
+}
+
+func TestResolveNilChecked(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		want      string
+	}{
+		// processOrder returns early when o is nil, so *o and the accesses
+		// in the goroutine and under the lock are checked.
+		{"business_heavy.go", 140, 1, "[142:4= 146:14=true 170:2=true 170:19=true 173:1=true 174:1=true 175:10=true]"},
+		// The loop condition guards its body and post statement.
+		{"nil_check_check.go", 8, 5, "[8:16= 8:26= 8:30=true 9:5=true 10:10=]"},
+		{"nil_check_check.go", 16, 15, "[17:10=false 18:15=]"},
+		// n != nil && n.val, the if and else branches, new(T) and a nil var.
+		{"nil_check_check.go", 18, 1, "[19:4= 19:16=true 20:11=true 21:11= 24:11=true]"},
+		{"nil_check_check.go", 26, 1, "[27:1=true 29:20=true]"},
+		{"nil_check_check.go", 28, 5, "[29:8=false]"},
+		// n is reassigned inside the loop.
+		{"nil_check_check.go", 34, 1, "[36:9=unknown 37:2= 37:6=unknown]"},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: fixture(t, tt.file), Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%s %d:%d: got nil", tt.file, tt.line, tt.col)
+		}
+		var got []string
+		for _, u := range out.Uses {
+			got = append(got, fmt.Sprintf("%d:%d=%s", u.Range.Start.Line, u.Range.Start.Col, u.NilChecked))
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s %d:%d: got %v, want %s", tt.file, tt.line, tt.col, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMethodOnTypeSwitchVariable(t *testing.T) {
+	file := fixture(t, "business_heavy.go")
+	// snap := v.SnapshotByUser(1) inside the type switch of
+	// complexBusinessFlow, then the method declaration itself. Methods are
+	// named symbols and resolve when WantDoc is set.
+	for _, pos := range [][2]int{{239, 14}, {199, 16}} {
+		out := resolve(Input{File: file, Line: pos[0], Col: pos[1], WantDoc: true})
+		if out == nil || out.Name != "SnapshotByUser" {
+			t.Fatalf("%d:%d: got %+v, want SnapshotByUser", pos[0], pos[1], out)
+		}
+		if out.Decl.Start.Line != 199 || out.Decl.Start.Col != 14 {
+			t.Errorf("%d:%d: got decl %+v, want 199:14", pos[0], pos[1], out.Decl.Start)
+		}
+		checkUses(t, out, []useWant{{line: 239, col: 12}})
+	}
+}
+
+func TestCallers(t *testing.T) {
+	type site struct {
+		file   string
+		line   int
+		caller string
+		flags  string
+	}
+	flags := func(c CallSite) string {
+		var fs []string
+		for _, f := range []struct {
+			set  bool
+			name string
+		}{{c.Go, "go"}, {c.Defer, "defer"}, {c.ViaValue, "value"}, {c.ViaInterface, "interface"}} {
+			if f.set {
+				fs = append(fs, f.name)
+			}
+		}
+		return strings.Join(fs, ",")
+	}
+	cases := []struct {
+		file      string
+		line, col int
+		name      string
+		want      []site
+	}{
+		{"main.go", 27, 20, "addTask", []site{
+			{"callers_check.go", 4, "fillPool", "value"},
+			{"callers_check.go", 5, "fillPool", "go"},
+			{"callers_check.go", 6, "fillPool", "defer"},
+			{"callers_check.go", 8, "fillPool", "go"}, // inside go func() {...}()
+			{"main.go", 60, "main", ""},
+		}},
+		// Inside the body of processOrder.
+		{"business_heavy.go", 150, 1, "processOrder", []site{
+			{"business_heavy.go", 100, "App.StartWorkers", "go"},
+		}},
+		// The concrete method is reached through PricingEngine.
+		{"business_heavy.go", 39, 25, "DynamicFee", []site{
+			{"business_heavy.go", 161, "App.processOrder", "interface"},
+		}},
+	}
+	for _, tc := range cases {
+		out := callers(Input{File: fixture(t, tc.file), Line: tc.line, Col: tc.col})
+		if out == nil || out.Name != tc.name {
+			t.Fatalf("%s:%d:%d: got %+v, want %s", tc.file, tc.line, tc.col, out, tc.name)
+		}
+		var got []site
+		for _, c := range out.Callers {
+			got = append(got, site{filepath.Base(c.Range.File), c.Range.Start.Line, c.Caller, flags(c)})
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s callers:\n got %v\nwant %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestImplementations(t *testing.T) {
+	file := fixture(t, "business_heavy.go")
+	cases := []struct {
+		line, col int
+		name      string
+		direction string
+		// want lists "Type@line" results, with a trailing * when only the
+		// pointer type satisfies the interface.
+		want string
+	}{
+		{31, 6, "PricingEngine", "implementations", "[FixedPricing@35* FreePricing@4]"},
+		{32, 2, "DynamicFee", "implementations", "[FixedPricing@39* FreePricing@6]"},
+		{35, 5, "FixedPricing", "interfaces", "[PricingEngine@31*]"},
+		{39, 25, "DynamicFee", "interfaces", "[PricingEngine@32*]"},
+	}
+	for _, tc := range cases {
+		out := implementations(Input{File: file, Line: tc.line, Col: tc.col})
+		if out == nil || out.Name != tc.name || out.Direction != tc.direction {
+			t.Fatalf("%d:%d: got %+v, want %s %s", tc.line, tc.col, out, tc.name, tc.direction)
+		}
+		var got []string
+		for _, r := range out.Results {
+			s := fmt.Sprintf("%s@%d", r.Type, r.Decl.Start.Line)
+			if r.PointerOnly {
+				s += "*"
+			}
+			got = append(got, s)
+		}
+		if fmt.Sprint(got) != tc.want {
+			t.Errorf("%s %s: got %v, want %s", tc.name, tc.direction, got, tc.want)
+		}
+	}
+}
+
+func TestResolveObjectWithoutDefsEntry(t *testing.T) {
+	in := Input{File: fixture(t, "multifile/a.go"), Line: 8, Col: 8}
+	lp := loadPackage(in)
+	if lp == nil {
+		t.Fatal("package did not load")
+	}
+	// The edit below changes the cached package; keep it out of other tests.
+	defer packageCache.reset()
+	// Simulate an implicitly declared object: it keeps its position but has
+	// no declaring identifier in info.Defs.
+	for ident, obj := range lp.info.Defs {
+		if obj != nil && obj.Name() == "shared" {
+			delete(lp.info.Defs, ident)
+		}
+	}
+	out := resolveLoaded(lp, in, nil)
+	if out == nil || out.Name != "shared" {
+		t.Fatalf("got %+v, want shared", out)
+	}
+	if out.Decl.Start.Line != 4 || out.Decl.Start.Col != 1 || out.Decl.End.Col != 7 {
+		t.Errorf("decl at %+v, want 4:1-4:7 from the object's position", out.Decl)
+	}
+	if len(out.Uses) != 3 {
+		t.Errorf("got %d uses, want 3", len(out.Uses))
+	}
+}
+
+// renderOutline prints symbols as "kind name@line[params]{children}".
+func renderOutline(symbols []OutlineSymbol) string {
+	var b strings.Builder
+	for i, s := range symbols {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		fmt.Fprintf(&b, "%s %s@%d", s.Kind, s.Name, s.SelectionRange.Start.Line)
+		if len(s.TypeParams) > 0 {
+			fmt.Fprintf(&b, "[%s]", strings.Join(s.TypeParams, ", "))
+		}
+		if len(s.Children) > 0 {
+			fmt.Fprintf(&b, "{%s}", renderOutline(s.Children))
+		}
+	}
+	return b.String()
+}
+
+func TestOutlineGolden(t *testing.T) {
+	cases := []struct {
+		file, want string
+	}{
+		{"business_heavy.go", "struct Item@14{field SKU@15 field Qty@16 field PriceCents@17} " +
+			"struct Order@20{field ID@21 field UserID@22 field Status@23 field Items@24 field Flags@25 field Notes@26 field CustomerTier@27 field TotalCents@28} " +
+			"interface PricingEngine@31{method DynamicFee@32} " +
+			"struct FixedPricing@35{field FeeByTier@36 method DynamicFee@39} " +
+			"struct App@50{field mu@51 field orders@52 field byUser@53 field totals@54 field queue@55 field stop@56 field wg@57 field processed@58 field hotCache@60 " +
+			"method AddOrder@73 method Enqueue@80 method StartWorkers@89 method Stop@123 method RecentCache@130 method processOrder@138 method SnapshotByUser@199} " +
+			"func NewApp@63 func makeDiscountFn@181 func complexBusinessFlow@215 func generateOrders@259"},
+		{"generics/set.go", "struct Set@5[K comparable]{field items@6 method Add@9[K] method Len@13[K]} " +
+			"struct Pair@16[A any, B fmt.Stringer]{field First@17 field Second@18} " +
+			"func Map@21[T any, U any]"},
+	}
+	for _, tc := range cases {
+		out := outline(Input{File: fixture(t, tc.file)})
+		if out == nil {
+			t.Fatalf("%s: got nil", tc.file)
+		}
+		if got := renderOutline(out.Symbols); got != tc.want {
+			t.Errorf("%s:\n got %s\nwant %s", tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestUnusedVariables(t *testing.T) {
+	file := fixture(t, "unused/main.go")
+	render := func(out *UnusedOutput) string {
+		var parts []string
+		for _, u := range out.Unused {
+			s := fmt.Sprintf("%s %s@%d", u.Kind, u.Name, u.Range.Start.Line)
+			if u.WrittenOnly {
+				s += " written"
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	out := unused(Input{File: file})
+	want := "param retries@8, result err@8, local unusedTotal@9, local written@10 written, param job@23"
+	if out == nil || render(out) != want {
+		t.Fatalf("got %+v, want %s", out, want)
+	}
+	if out.Unused[4].Function != "worker.run" {
+		t.Errorf("job reported in %q, want worker.run", out.Unused[4].Function)
+	}
+
+	out = unused(Input{File: file, UnusedScope: "function", Line: 10, Col: 1, IncludeUnderscore: true})
+	want = "param retries@8, param _debug@8, result err@8, local unusedTotal@9, local written@10 written, local _tmp@16"
+	if out == nil || render(out) != want {
+		t.Fatalf("handler with underscores: got %+v, want %s", out, want)
+	}
+}
+
+func TestShadowReport(t *testing.T) {
+	out := shadowReport(Input{File: fixture(t, "shadow_check.go"), Mode: "shadow_report"})
+	if out == nil {
+		t.Fatal("expected a report, got nil")
+	}
+	var got []string
+	for _, s := range out.Shadows {
+		got = append(got, fmt.Sprintf("%s %s %d->%d depth=%d idiom=%v after=%v", s.Name, s.Kind,
+			s.Decl.Start.Line, s.OuterDecl.Start.Line, s.Depth, s.Idiom, s.OuterUsedAfter))
+	}
+	want := []string{
+		"n if_init 9->8 depth=1 idiom=false after=true",
+		"it loop_copy 14->13 depth=1 idiom=true after=false",
+		"in type_switch 17->7 depth=2 idiom=false after=false",
+		"total local 26->23 depth=1 idiom=false after=false",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMetricsGolden(t *testing.T) {
+	out := metrics(Input{File: fixture(t, "business_heavy.go"), Mode: "metrics"})
+	if out == nil {
+		t.Fatal("metrics returned nil")
+	}
+	var got []string
+	for _, f := range out.Functions {
+		got = append(got, fmt.Sprintf("%s complexity=%d stmts=%d nesting=%d go=%d locks=%d locked=%d",
+			f.Name, f.Complexity, f.Statements, f.MaxNesting, f.Goroutines, f.Locks, f.LockedStatements))
+	}
+	want := []string{
+		"FixedPricing.DynamicFee complexity=3 stmts=6 nesting=1 go=0 locks=0 locked=0",
+		"NewApp complexity=1 stmts=1 nesting=0 go=0 locks=0 locked=0",
+		"App.AddOrder complexity=1 stmts=4 nesting=0 go=0 locks=1 locked=3",
+		"App.Enqueue complexity=2 stmts=4 nesting=1 go=0 locks=0 locked=0",
+		"App.StartWorkers complexity=9 stmts=24 nesting=5 go=2 locks=0 locked=0",
+		"App.Stop complexity=1 stmts=2 nesting=0 go=0 locks=0 locked=0",
+		"App.RecentCache complexity=2 stmts=4 nesting=1 go=0 locks=0 locked=0",
+		"App.processOrder complexity=7 stmts=31 nesting=2 go=1 locks=1 locked=6",
+		"makeDiscountFn complexity=4 stmts=9 nesting=2 go=0 locks=0 locked=0",
+		"App.SnapshotByUser complexity=3 stmts=11 nesting=2 go=0 locks=1 locked=7",
+		"complexBusinessFlow complexity=10 stmts=29 nesting=3 go=2 locks=0 locked=0",
+		"generateOrders complexity=4 stmts=15 nesting=3 go=0 locks=0 locked=0",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestResolveSizeBytes(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		name      string
+		want      int64
+	}{
+		{"field_signals_check.go", 32, 18, "e", 3608}, // LargeEvent parameter
+		{"realistic.go", 59, 1, "x", 8},
+		{"generics/set.go", 9, 21, "k", -1}, // type parameter K
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: fixture(t, tt.file), Line: tt.line, Col: tt.col})
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%s %d:%d: got %+v, want %s", tt.file, tt.line, tt.col, out, tt.name)
+		}
+		if out.SizeBytes != tt.want {
+			t.Errorf("%s: got size %d, want %d", tt.name, out.SizeBytes, tt.want)
+		}
+	}
+
+	out := resolve(Input{File: fixture(t, "field_signals_check.go"), Line: 11, Col: 6, WantDoc: true})
+	if out == nil || out.Name != "LargeEvent" || out.SizeBytes <= 2048 {
+		t.Errorf("got %+v, want LargeEvent larger than its 2048-byte payload", out)
+	}
+}
+
+func TestLifetimeGolden(t *testing.T) {
+	out := lifetime(Input{File: fixture(t, "main.go"), Mode: "lifetime", Line: 56, Col: 1})
+	if out == nil || out.Function != "main" {
+		t.Fatalf("got %+v, want the lifetimes of main", out)
+	}
+	var got []string
+	for _, v := range out.Variables {
+		got = append(got, fmt.Sprintf("%s %d-%d until %d:%d %s %s", v.Name, v.FirstUse.Start.Line, v.LastUse.Start.Line,
+			v.LiveUntil.Start.Line, v.LiveUntil.Start.Col, v.Extended, v.Gap))
+	}
+	want := []string{
+		"pool 60-112 until 112:17  ",
+		"x 62-65 until 65:23  dead_tail",
+		"x 62-63 until 63:25  ",
+		"sum 68-70 until 70:23  dead_tail",
+		"i 68-68 until 68:14  ",
+		"v 68-68 until 68:10  ",
+		"i 72-72 until 72:7  ",
+		"v 73-76 until 76:21  ",
+		"total 80-109 until 113:0 captured ",
+		"i 82-85 until 113:0 captured ",
+		"i 89-90 until 90:8  ",
+		"i 92-92 until 113:0 captured ",
+		"n 96-96 until 113:0 address ",
+		"p 97-97 until 97:2  dead_tail",
+		"any 99-99 until 99:16  dead_tail",
+		"node 106-106 until 106:17  dead_tail",
+		"r 109-109 until 109:26  ",
+		"err 108-108 until 108:7  ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestClosureReport(t *testing.T) {
+	tests := []struct {
+		file      string
+		line, col int
+		want      string
+	}{
+		// The StartWorkers worker gets workerID as a parameter and captures
+		// a and engine.
+		{"business_heavy.go", 95, 4, "go [a:*App:read:8 engine:PricingEngine:read:16]"},
+		// The note-appending goroutine in processOrder.
+		{"business_heavy.go", 170, 5, "go [o:*Order:read:8 workerID:int:read:8]"},
+		{"main.go", 80, 3, "call [total:int:read_write:8]"},
+	}
+	for _, tt := range tests {
+		out := closureReport(Input{File: fixture(t, tt.file), Mode: "closure_report", Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%s %d:%d: no function literal", tt.file, tt.line, tt.col)
+		}
+		var captures []string
+		for _, c := range out.Captures {
+			captures = append(captures, fmt.Sprintf("%s:%s:%s:%d", c.Name, c.Type, c.Access, c.Size))
+		}
+		if got := out.Launch + " " + fmt.Sprint(captures); got != tt.want {
+			t.Errorf("%s %d:%d: got %s, want %s", tt.file, tt.line, tt.col, got, tt.want)
+		}
+	}
+	if out := closureReport(Input{File: fixture(t, "business_heavy.go"), Mode: "closure_report", Line: 80, Col: 3}); out != nil {
+		t.Errorf("got %+v outside any function literal, want nil", out)
+	}
+}
+
+func TestPackageCacheInvalidatesChangedOverlay(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "a.go")
+	other := filepath.Join(dir, "b.go")
+	if err := os.WriteFile(target, []byte("package p\n\nvar x = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("package p\n\nfunc g() int { return x }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := Input{File: target, Line: 2, Col: 4, Content: "package p\n\nvar x = 1\n\nfunc f() int { return x }\n"}
+	if a, b := loadPackage(first), loadPackage(first); a == nil || a != b {
+		t.Fatalf("unchanged overlay reloaded the package: %p, %p", a, b)
+	}
+	if out := resolve(first); out == nil || len(out.Uses) != 2 {
+		t.Fatalf("first overlay: got %+v, want 2 uses", out)
+	}
+
+	second := first
+	second.Content = "package p\n\nvar x = 1\n\nfunc f() int { return x + x }\n"
+	if out := resolve(second); out == nil || len(out.Uses) != 3 {
+		t.Fatalf("changed overlay: got %+v, want 3 uses", out)
+	}
+
+	// A member file changing on disk evicts the entry as well.
+	if err := os.WriteFile(other, []byte("package p\n\nfunc g() int { return x * x }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(other, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if out := resolve(second); out == nil || len(out.Uses) != 4 {
+		t.Fatalf("changed member file: got %+v, want 4 uses", out)
+	}
+}
+
+func TestPackageCacheAppliesSiblingOverlays(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")
+	if err := os.WriteFile(a, []byte("package p\n\nvar x = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("package p\n\nfunc g() int { return x }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ext := filepath.Join(dir, "p_test.go")
+	if err := os.WriteFile(ext, []byte("package p_test\n\nvar z = 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	in := Input{File: a, Line: 2, Col: 4}
+	if out := resolve(in); out == nil || len(out.Uses) != 1 {
+		t.Fatalf("on disk: got %+v, want 1 use", out)
+	}
+	in.Overlays = map[string]string{b: "package p\n\nfunc g() int { return x + x }\n"}
+	if out := resolve(in); out == nil || len(out.Uses) != 2 {
+		t.Fatalf("sibling overlay: got %+v, want 2 uses", out)
+	}
+	// An overlay of a file not saved yet joins the package.
+	in.Overlays[c] = "package p\n\nvar y = x\n"
+	if out := resolve(in); out == nil || len(out.Uses) != 3 {
+		t.Fatalf("unsaved file: got %+v, want 3 uses", out)
+	}
+
+	// Another file of the package with the same overlays shares the entry.
+	la := loadPackage(in)
+	lb := loadPackage(Input{File: b, Content: in.Overlays[b], Overlays: map[string]string{c: in.Overlays[c]}})
+	if la == nil || lb == nil || la.pkg != lb.pkg {
+		t.Fatalf("got packages %p and %p, want one shared package", la, lb)
+	}
+	if name := lb.fset.Position(lb.file.Pos()).Filename; name != b {
+		t.Fatalf("shared package targets %s, want %s", name, b)
+	}
+	if lp := loadPackage(Input{File: ext}); lp == nil || lp.pkg.Name() != "p_test" {
+		t.Fatalf("external test package: got %+v", lp)
+	}
+}
+
+func TestStructLayout(t *testing.T) {
+	layout := func(file string, line, col int, arch string) *StructLayoutOutput {
+		t.Helper()
+		out := structLayout(Input{File: fixture(t, file), Line: line, Col: col, GOARCH: arch})
+		if out == nil {
+			t.Fatalf("%s:%d:%d: no layout", file, line, col)
+		}
+		return out
+	}
+	names := func(out *StructLayoutOutput) string { return strings.Join(out.Suggested, " ") }
+
+	padded := layout("struct_layout_check.go", 4, 6, "amd64")
+	if padded.Size != 32 || padded.Padding != 16 || padded.SuggestedSize != 16 {
+		t.Fatalf("paddedRecord: got size %d padding %d suggested %d", padded.Size, padded.Padding, padded.SuggestedSize)
+	}
+	if got := names(padded); got != "id count version active flag" {
+		t.Fatalf("paddedRecord: got suggestion %q", got)
+	}
+	if f := padded.Fields[0]; f.Name != "active" || f.Offset != 0 || f.Padding != 7 {
+		t.Fatalf("paddedRecord: got first field %+v", f)
+	}
+
+	guarded := layout("struct_layout_check.go", 12, 6, "amd64")
+	if f := guarded.Fields[0]; f.Name != "Mutex" || !f.Embedded || f.Size != 8 {
+		t.Fatalf("guardedRecord: got embedded field %+v", f)
+	}
+
+	generic := layout("struct_layout_check.go", 18, 6, "amd64")
+	if generic.Size != -1 || generic.Fields[2].Size != 1 || generic.Fields[0].Size != -1 || generic.Suggested != nil {
+		t.Fatalf("generic layoutPair: got %+v", generic)
+	}
+	inst := layout("struct_layout_check.go", 24, 22, "amd64")
+	if inst.Size != 24 || names(inst) != "value key seen" || inst.SuggestedSize != 16 {
+		t.Fatalf("layoutPair[int8, int64]: got size %d suggestion %q", inst.Size, names(inst))
+	}
+
+	// Order has no padding on either architecture, so the suggestion keeps
+	// the declared order.
+	for _, arch := range []string{"amd64", "386"} {
+		order := layout("business_heavy.go", 20, 6, arch)
+		if order.Padding != 0 || names(order) != "ID UserID Status Items Flags Notes CustomerTier TotalCents" {
+			t.Fatalf("Order on %s: got padding %d suggestion %q", arch, order.Padding, names(order))
+		}
+	}
+	if large := layout("field_signals_check.go", 11, 6, "amd64"); large.Size != 3608 || large.Padding != 0 {
+		t.Fatalf("LargeEvent: got size %d padding %d", large.Size, large.Padding)
+	}
+
+	if bad := layout("business_heavy.go", 20, 6, "vax"); bad.Error == "" {
+		t.Fatalf("unknown architecture: got %+v", bad)
+	}
+}
+
+func TestLockReport(t *testing.T) {
+	// CommentNoiseState.mu is documented to protect guarded and store.
+	out := lockReport(Input{File: fixture(t, "comments_layout_check.go"), Line: 12, Col: 1})
+	if out == nil {
+		t.Fatal("no report for CommentNoiseState.mu")
+	}
+	if got := strings.Join(out.Protects, " "); got != "guarded store" {
+		t.Fatalf("got protection set %q, want guarded store", got)
+	}
+	status := make(map[string]string)
+	for _, m := range out.Members {
+		status[m.Name] = m.Status
+	}
+	if status["plainCounter"] != "unguarded" || status["guarded"] != "partial" {
+		t.Fatalf("got member statuses %v", status)
+	}
+	if len(out.Acquirers) != 4 || out.MaxCriticalSection != 3 {
+		t.Fatalf("got acquirers %v and max critical section %d", out.Acquirers, out.MaxCriticalSection)
+	}
+
+	split := lockReport(Input{File: fixture(t, "lock_report_check.go"), Line: 5, Col: 1})
+	if split == nil {
+		t.Fatal("no report for splitGuardLedger.mu")
+	}
+	for _, m := range split.Members {
+		want := ""
+		if m.Name == "balance" {
+			want = "auditMu"
+		}
+		if got := strings.Join(m.SplitWith, ","); got != want {
+			t.Errorf("%s: got split_with %q, want %q", m.Name, got, want)
+		}
+	}
+
+	if out := lockReport(Input{File: fixture(t, "lock_report_check.go"), Line: 7, Col: 1}); out != nil {
+		t.Fatalf("balance is not a mutex, got %+v", out)
+	}
+}
+
+func TestResolveImportsWithSameBaseName(t *testing.T) {
+	file := fixture(t, "same_base_import_check.go")
+	aliased := resolve(Input{File: file, Line: 9, Col: 14, WantDoc: true}) // crand.Read
+	if aliased == nil || aliased.Name != "crand" || aliased.Decl.Start.Line != 3 {
+		t.Fatalf("got %+v, want crand declared by its alias", aliased)
+	}
+	checkUses(t, aliased, []useWant{{line: 9}})
+
+	plain := resolve(Input{File: file, Line: 12, Col: 9, WantDoc: true}) // rand.Intn
+	if plain == nil || plain.Name != "rand" || plain.Decl.Start.Line != 4 || plain.Decl.End.Col != 12 {
+		t.Fatalf("got %+v, want rand declared by the math/rand import path", plain)
+	}
+	checkUses(t, plain, []useWant{{line: 12}})
+
+	lp := loadPackage(Input{File: file})
+	for _, tc := range []struct {
+		line, col int
+		path      string
+	}{
+		{9, 14, "crypto/rand"},
+		{9, 20, "crypto/rand"},
+		{12, 9, "math/rand"},
+		{12, 13, "math/rand"},
+	} {
+		ident, selMap := findIdentAtPosition(lp.fset, lp.file, tc.line, tc.col)
+		obj := identObject(lp.info, lp.pkg, ident, selMap[ident])
+		var path string
+		switch obj := obj.(type) {
+		case *types.PkgName:
+			path = obj.Imported().Path()
+		case nil:
+		default:
+			path = obj.Pkg().Path()
+		}
+		if path != tc.path {
+			t.Errorf("%d:%d: %s resolved into %q, want %q", tc.line, tc.col, ident.Name, path, tc.path)
+		}
+	}
+}
+
+func renderGoroutines(out *GoroutineMapOutput) string {
+	var b strings.Builder
+	for _, g := range out.Goroutines {
+		fmt.Fprintf(&b, "%d %s %s in %s", g.Range.Start.Line, g.Kind, g.Target, g.Function)
+		if g.Loop != nil {
+			fmt.Fprintf(&b, " loop@%d", g.Loop.Start.Line)
+		}
+		for _, in := range g.Inputs {
+			fmt.Fprintf(&b, " %s:%s", in.Via, in.Name)
+		}
+		if len(g.LocksHeld) > 0 {
+			fmt.Fprintf(&b, " locked:%s", strings.Join(g.LocksHeld, ","))
+		}
+		fmt.Fprintf(&b, " -> %s\n", g.Termination)
+	}
+	return b.String()
+}
+
+func TestGoroutineMapGolden(t *testing.T) {
+	out := goroutineMap(Input{File: fixture(t, "business_heavy.go")})
+	if out == nil {
+		t.Fatal("no goroutine map")
+	}
+	want := `93 literal  in App.StartWorkers loop@90 capture:a capture:engine capture:a.wg capture:a.stop capture:a.queue argument:i -> stop_channel
+108 literal  in App.StartWorkers capture:a capture:a.stop capture:a.hotCache -> stop_channel
+169 literal  in App.processOrder capture:o capture:workerID capture:o.Notes -> bounded
+222 literal  in complexBusinessFlow loop@220 capture:app capture:o capture:o.ID -> bounded
+229 literal  in complexBusinessFlow loop@228 capture:ids capture:i capture:app -> bounded
+`
+	if got := renderGoroutines(out); got != want {
+		t.Fatalf("business_heavy.go:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	named := goroutineMap(Input{File: fixture(t, "goroutine_map_check.go")})
+	if named == nil {
+		t.Fatal("no goroutine map")
+	}
+	want = `36 func pollUntilCancelled in startPollers argument:ctx argument:s locked:mu -> context
+38 func spinForever in startPollers argument:s -> none
+39 value  in startPollers -> unknown
+`
+	if got := renderGoroutines(named); got != want {
+		t.Fatalf("goroutine_map_check.go:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	methods := goroutineMap(Input{File: fixture(t, "field_signals_check.go")})
+	if methods == nil || len(methods.Goroutines) < 2 {
+		t.Fatalf("got %+v", methods)
+	}
+	if g := methods.Goroutines[1]; g.Kind != "method" || g.Target != "FieldSignalState.incWithoutLock" || g.Inputs[0].Via != "receiver" {
+		t.Fatalf("got %+v, want the incWithoutLock method launch", g)
+	}
+}
+
+func TestDeferReport(t *testing.T) {
+	out := deferReport(Input{File: fixture(t, "defer_loop_check.go")})
+	if out == nil || len(out.Defers) != 3 {
+		t.Fatalf("got %+v, want three defers", out)
+	}
+	loop, closer, lit := out.Defers[0], out.Defers[1], out.Defers[2]
+	if loop.Target != "f.Close" || !loop.InLoop || loop.Cleanup != "close" || len(loop.EarlyReturns) != 2 {
+		t.Fatalf("got %+v, want f.Close deferred in the loop after two returns", loop)
+	}
+	if closer.Target != "close" || closer.Cleanup != "close" || strings.Join(closer.Eager, ",") != "done" {
+		t.Fatalf("got %+v, want close(done) with done evaluated eagerly", closer)
+	}
+	if lit.Target != "func literal" || len(lit.Eager) != 0 || strings.Join(lit.Lazy, ",") != "total" {
+		t.Fatalf("got %+v, want a literal reading total lazily", lit)
+	}
+
+	for _, tc := range []struct {
+		file      string
+		line, col int
+		target    string
+		cleanup   string
+	}{
+		{"business_heavy.go", 110, 3, "ticker.Stop", "stop"},
+		{"comments_layout_check.go", 42, 3, "s.mu.RUnlock", "unlock"},
+	} {
+		out := deferReport(Input{File: fixture(t, tc.file), Line: tc.line, Col: tc.col, DeferScope: "function"})
+		if out == nil {
+			t.Fatalf("%s: no report", tc.file)
+		}
+		var found *DeferEntry
+		for i := range out.Defers {
+			if out.Defers[i].Range.Start.Line == tc.line {
+				found = &out.Defers[i]
+			}
+		}
+		if found == nil || found.Target != tc.target || found.Cleanup != tc.cleanup || found.InLoop || found.EarlyReturns != nil {
+			t.Fatalf("%s:%d: got %+v, want %s (%s)", tc.file, tc.line, found, tc.target, tc.cleanup)
+		}
+	}
+}
+
+func TestResolveUnicodeIdentifiers(t *testing.T) {
+	file := fixture(t, "unicode_ident_check.go")
+	// "\tcafé := 1": café spans bytes 1-6, é taking bytes 4 and 5.
+	for col := 1; col <= 6; col++ {
+		out := resolve(Input{File: file, Line: 3, Col: col})
+		if out == nil || out.Name != "café" {
+			t.Fatalf("col %d: got %+v, want café", col, out)
+		}
+		if out.Decl.Start.Col != 1 || out.Decl.End.Col != 6 {
+			t.Fatalf("col %d: got decl %+v, want byte columns 1-6", col, out.Decl)
+		}
+		checkUses(t, out, []useWant{{line: 4, col: 16}, {line: 5, col: 25}, {line: 6, col: 17}})
+	}
+
+	// In visual mode columns count characters: 日本 follows a tab.
+	cols := newColumnMapper(Input{File: file, ColumnMode: "visual", TabSize: 4})
+	out := resolve(Input{File: file, Line: 5, Col: cols.toByte(cols.target, 5, 5)})
+	if out == nil || out.Name != "日本" {
+		t.Fatalf("got %+v, want 日本", out)
+	}
+	out.mapRanges(cols.mapRange)
+	if out.Decl.Start.Col != 4 || out.Decl.End.Col != 6 || out.Uses[0].Range.Start.Col != 11 {
+		t.Fatalf("got decl %+v and uses %+v, want visual columns", out.Decl, out.Uses)
+	}
+}
+
+func renderChannelOps(out *ChannelReportOutput) string {
+	var b strings.Builder
+	for _, op := range out.Ops {
+		fmt.Fprintf(&b, "%s@%d %s", op.Kind, op.Range.Start.Line, op.Function)
+		if op.Goroutine {
+			b.WriteString(" goroutine")
+		}
+		if op.Select {
+			b.WriteString(" select")
+		}
+		if op.NonBlocking {
+			b.WriteString(" non_blocking")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestChannelReport(t *testing.T) {
+	queue := channelReport(Input{File: fixture(t, "business_heavy.go"), Line: 55, Col: 2})
+	if queue == nil || queue.Name != "queue" || queue.ElemType != "int64" || queue.Buffer != -1 || queue.Closed {
+		t.Fatalf("got %+v, want the App.queue channel with a non-constant buffer", queue)
+	}
+	// Enqueue's default branch makes its send non-blocking.
+	want := "send@82 App.Enqueue select non_blocking\nreceive@99 App.StartWorkers goroutine select\n"
+	if got := renderChannelOps(queue); got != want {
+		t.Fatalf("App.queue:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	stop := channelReport(Input{File: fixture(t, "business_heavy.go"), Line: 56, Col: 2})
+	if stop == nil || stop.Buffer != 0 || !stop.Closed || stop.SendAfterClose != nil {
+		t.Fatalf("got %+v, want the closed, unbuffered App.stop channel", stop)
+	}
+
+	done := channelReport(Input{File: fixture(t, "semantic_check.go"), Line: 42, Col: 2})
+	want = "close@45 semanticCheck goroutine\nreceive@47 semanticCheck\n"
+	if done == nil || done.Buffer != 0 || renderChannelOps(done) != want {
+		t.Fatalf("got %+v, want done closed in a goroutine and received once", done)
+	}
+
+	results := channelReport(Input{File: fixture(t, "channel_report_check.go"), Line: 5, Col: 2})
+	if results == nil || results.Buffer != 4 || len(results.SendAfterClose) != 1 || results.SendAfterClose[0].Start.Line != 8 {
+		t.Fatalf("got %+v, want a buffered channel sent to after close", results)
+	}
+}
+
+func renderErrorUses(out *ErrorFlowOutput) string {
+	var b strings.Builder
+	for _, u := range out.Uses {
+		fmt.Fprintf(&b, "%s@%d:%d\n", u.Kind, u.Range.Start.Line, u.Range.Start.Col)
+	}
+	return b.String()
+}
+
+func TestErrorFlow(t *testing.T) {
+	// The named result is set once and returned by both bare returns.
+	compute := errorFlow(Input{File: fixture(t, "main.go"), Line: 46, Col: 33})
+	want := "assigned@48:2\nreturned@49:2\nreturned@52:1\n"
+	if compute == nil || compute.Function != "compute" || renderErrorUses(compute) != want {
+		t.Fatalf("got %+v, want compute's err assigned and returned twice", compute)
+	}
+
+	worker := errorFlow(Input{File: fixture(t, "business_heavy.go"), Line: 100, Col: 9})
+	want = "checked@100:53\nblank@101:10\n"
+	if worker == nil || renderErrorUses(worker) != want {
+		t.Fatalf("got %+v, want the processOrder error checked and blanked", worker)
+	}
+
+	// The first value of err is never read, and the err declared in the if
+	// statement's init is a separate variable.
+	load := errorFlow(Input{File: fixture(t, "error_flow_check.go"), Line: 12, Col: 1})
+	want = "overwritten@12:1\nassigned@13:1\nchecked@14:14\nwrapped@15:41\nblank@21:5\nreturned@22:8\n"
+	if load == nil || renderErrorUses(load) != want {
+		t.Fatalf("outer err:\ngot:\n%swant:\n%s", renderErrorUses(load), want)
+	}
+	if len(load.Discarded) != 1 || load.Discarded[0].Start.Line != 20 {
+		t.Fatalf("got discarded %+v, want the bare os.Remove call", load.Discarded)
+	}
+	shadow := errorFlow(Input{File: fixture(t, "error_flow_check.go"), Line: 17, Col: 4})
+	want = "checked@17:37\nlogged@18:28\n"
+	if shadow == nil || shadow.Decl.Start.Line != 17 || renderErrorUses(shadow) != want {
+		t.Fatalf("if-init err:\ngot:\n%swant:\n%s", renderErrorUses(shadow), want)
+	}
+
+	if out := errorFlow(Input{File: fixture(t, "main.go"), Line: 46, Col: 21}); out != nil {
+		t.Fatalf("got %+v for an int result, want nil", out)
+	}
+}
+
+func TestConstGroup(t *testing.T) {
+	render := func(out *ConstGroupOutput) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "type=%q iota=%q", out.Type, out.Iota)
+		for _, m := range out.Members {
+			fmt.Fprintf(&b, " %s=%s:%s@%d", m.Name, m.Value, m.Type, m.Decl.Start.Line)
+			if m.Used {
+				b.WriteString(" used")
+			}
+		}
+		return b.String()
+	}
+	tests := []struct {
+		line, col int
+		want      string
+	}{
+		// From a member and from a use in describeStatus.
+		{8, 2, `type="Status" iota="iota" StatusPending=0:Status@7 used StatusActive=1:Status@8 used StatusClosed=2:Status@9`},
+		{21, 11, `type="Status" iota="iota" StatusPending=0:Status@7 used StatusActive=1:Status@8 used StatusClosed=2:Status@9`},
+		{14, 1, `type="untyped int" iota="1 << (10 * iota)" _=0:untyped int@13 KB=1024:untyped int@14 used MB=1048576:untyped int@15`},
+		{18, 7, `type="Status" iota="" defaultStatus=1:Status@18 used`},
+	}
+	for _, tt := range tests {
+		out := constGroup(Input{File: fixture(t, "const_group_check.go"), Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%d:%d: got nil", tt.line, tt.col)
+		}
+		if got := render(out); got != tt.want {
+			t.Errorf("%d:%d:\ngot  %s\nwant %s", tt.line, tt.col, got, tt.want)
+		}
+	}
+	if out := constGroup(Input{File: fixture(t, "const_group_check.go"), Line: 20, Col: 20}); out != nil {
+		t.Errorf("got %+v for a parameter, want nil", out)
+	}
+}
+
+func TestInterfaceUsage(t *testing.T) {
+	render := func(out *InterfaceUsageOutput) string {
+		var b strings.Builder
+		for _, m := range out.Methods {
+			fmt.Fprintf(&b, "%s:%d ", m.Name, len(m.Calls))
+		}
+		for _, a := range out.Assertions {
+			fmt.Fprintf(&b, "%s@%d/%v ", a.Type, a.Range.Start.Line, a.Switch)
+		}
+		return b.String()
+	}
+
+	store := interfaceUsage(Input{File: fixture(t, "interface_usage_check.go"), Line: 12, Col: 1})
+	if store == nil || store.Interface != "orderStore" {
+		t.Fatalf("got %+v, want the orderStore field", store)
+	}
+	// The method value save := a.store.Save counts as a call.
+	want := "Delete:0 Load:1 Save:1 String:0 fmt.Stringer@24/false nil@28/true *memoryStore@30/true "
+	if got := render(store); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if fmt.Sprint(store.Uncalled) != "[Delete String]" {
+		t.Errorf("got uncalled %v, want [Delete String]", store.Uncalled)
+	}
+
+	engine := interfaceUsage(Input{File: fixture(t, "business_heavy.go"), Line: 138, Col: 60})
+	if engine == nil || render(engine) != "DynamicFee:1 " || len(engine.Uncalled) != 0 {
+		t.Fatalf("got %+v, want processOrder's engine calling DynamicFee once", engine)
+	}
+
+	any := interfaceUsage(Input{File: fixture(t, "semantic_check.go"), Line: 71, Col: 5})
+	if any == nil || render(any) != "map[string]int@73/true " {
+		t.Fatalf("got %+v, want one type switch case", any)
+	}
+
+	if out := interfaceUsage(Input{File: fixture(t, "semantic_check.go"), Line: 55, Col: 1}); out != nil {
+		t.Fatalf("got %+v for a slice, want nil", out)
+	}
+}
+
+func TestCallGraph(t *testing.T) {
+	render := func(out *CallGraphOutput) string {
+		var b strings.Builder
+		for _, n := range out.Nodes {
+			fmt.Fprintf(&b, "%s@%d:", n.Name, n.Range.Start.Line)
+			for _, e := range n.Calls {
+				fmt.Fprintf(&b, " %s@%d", e.Callee, e.Range.Start.Line)
+				flags := []struct {
+					name string
+					set  bool
+				}{{"go", e.Go}, {"defer", e.Defer}, {"value", e.ViaValue}, {"approx", e.Approx}}
+				for _, flag := range flags {
+					if flag.set {
+						b.WriteString("/" + flag.name)
+					}
+				}
+			}
+			b.WriteString("\n")
+		}
+		return b.String()
+	}
+
+	out := callGraph(Input{File: fixture(t, "callgraph/main.go")})
+	if out == nil {
+		t.Fatal("callgraph returned nil")
+	}
+	// s.area() reaches both implementations; the literal run by go and the
+	// one called in place are part of main. sync calls are not in the graph.
+	want := "square.area@10:\n" +
+		"rect.area@14:\n" +
+		"total@16: square.area@19/approx rect.area@19/approx\n" +
+		"report@24:\n" +
+		"main@26: report@33/go/value total@33/go report@36 square.area@36\n"
+	if got := render(out); got != want {
+		t.Errorf("got:\n%swant:\n%s", got, want)
+	}
+
+	out = callGraph(Input{File: fixture(t, "business_heavy.go")})
+	if out == nil {
+		t.Fatal("callgraph returned nil")
+	}
+	for _, n := range out.Nodes {
+		if n.Name != "App.StartWorkers" {
+			continue
+		}
+		// The worker goroutine calls processOrder.
+		if len(n.Calls) != 1 || n.Calls[0].Callee != "App.processOrder" || !n.Calls[0].Go {
+			t.Fatalf("got %+v, want the goroutine's call of processOrder", n.Calls)
+		}
+		return
+	}
+	t.Fatal("no App.StartWorkers node")
+}
+
+func TestTestRefs(t *testing.T) {
+	file := fixture(t, "testrefs/calc.go")
+	tests := []struct {
+		line, col int
+		want      string
+	}{
+		{3, 5, "[BenchmarkAdd[31] TestAdd[5] TestScaleNegative[24]]"},
+		// Scale is referenced from checkScale, which both tests call.
+		{6, 5, "[TestScale[12]via[checkScale] TestScaleNegative[12 24]via[checkScale]]"},
+		{9, 5, "[]"},
+	}
+	for _, tt := range tests {
+		out := testRefs(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil {
+			t.Fatalf("%d:%d: got nil", tt.line, tt.col)
+		}
+		var got []string
+		for _, ref := range out.Tests {
+			var lines []int
+			for _, r := range ref.Refs {
+				lines = append(lines, r.Start.Line)
+			}
+			s := fmt.Sprintf("%s%v", ref.Name, lines)
+			if len(ref.Via) > 0 {
+				s += fmt.Sprintf("via%v", ref.Via)
+			}
+			got = append(got, s)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("%s: got %v, want %s", out.Name, got, tt.want)
+		}
+	}
+}
+
+// lspSession feeds the messages to the language server and returns its
+// exit code and the replies, keyed by request id.
+func lspSession(t *testing.T, messages ...string) (int, map[string]map[string]json.RawMessage) {
+	t.Helper()
+	var in, out bytes.Buffer
+	for _, m := range messages {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	code := serveLSP(&in, &out)
+	replies := make(map[string]map[string]json.RawMessage)
+	for out.Len() > 0 {
+		var length int
+		if _, err := fmt.Fscanf(&out, "Content-Length: %d\r\n\r\n", &length); err != nil {
+			t.Fatalf("bad frame header: %v", err)
+		}
+		var reply map[string]json.RawMessage
+		if err := json.Unmarshal(out.Next(length), &reply); err != nil {
+			t.Fatal(err)
+		}
+		replies[string(reply["id"])] = reply
+	}
+	return code, replies
+}
+
+func TestLSPSession(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(path, []byte("package p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	// The emoji takes 4 bytes, 2 UTF-16 code units and 1 UTF-32 unit, so y
+	// on line 3 is at byte 15 but character 13 in UTF-16.
+	src := "package p\n\nfunc f(y string) string {\n\ts := \"\U0001F600\" + y\n\treturn s + y\n}\n"
+	text, _ := json.Marshal(src)
+	open := `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","languageId":"go","version":1,"text":` + string(text) + `}}}`
+	at := func(id, method string, line, char int) string {
+		return fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"method":"%s","params":{"textDocument":{"uri":"%s"},"position":{"line":%d,"character":%d}}}`, id, method, uri, line, char)
+	}
+
+	code, replies := lspSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		open,
+		at("2", "textDocument/documentHighlight", 4, 12),
+		at("3", "textDocument/definition", 3, 13),
+		at("4", "textDocument/hover", 3, 1),
+		at("5", "textDocument/completion", 3, 1),
+		`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":5}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if code != 0 {
+		t.Errorf("exit code %d after shutdown, want 0", code)
+	}
+	if len(replies) != 6 {
+		t.Errorf("got %d replies, want one per request", len(replies))
+	}
+	if got := string(replies["1"]["result"]); !strings.Contains(got, `"positionEncoding":"utf-16"`) {
+		t.Errorf("initialize: got %s, want the UTF-16 default", got)
+	}
+	want := `[{"range":{"start":{"line":2,"character":7},"end":{"line":2,"character":8}},"kind":3},` +
+		`{"range":{"start":{"line":3,"character":13},"end":{"line":3,"character":14}},"kind":2},` +
+		`{"range":{"start":{"line":4,"character":12},"end":{"line":4,"character":13}},"kind":2}]`
+	if got := string(replies["2"]["result"]); got != want {
+		t.Errorf("documentHighlight:\n got %s\nwant %s", got, want)
+	}
+	want = `{"uri":"` + uri + `","range":{"start":{"line":2,"character":7},"end":{"line":2,"character":8}}}`
+	if got := string(replies["3"]["result"]); got != want {
+		t.Errorf("definition:\n got %s\nwant %s", got, want)
+	}
+	if got := string(replies["4"]["result"]); !strings.Contains(got, "var s string") {
+		t.Errorf("hover: got %s, want the declaration of s", got)
+	}
+	if got := string(replies["5"]["error"]); !strings.Contains(got, `"code":-32601`) {
+		t.Errorf("completion: got %s, want MethodNotFound", got)
+	}
+	if got := string(replies["6"]["result"]); got != "null" {
+		t.Errorf("shutdown: got %s, want null", got)
+	}
+
+	// A client offering UTF-8 gets byte offsets; exit without shutdown
+	// fails.
+	code, replies = lspSession(t,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{"general":{"positionEncodings":["utf-16","utf-8"]}}}}`,
+		open,
+		at("2", "textDocument/definition", 3, 15),
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if code != 1 {
+		t.Errorf("exit code %d without shutdown, want 1", code)
+	}
+	if got := string(replies["1"]["result"]); !strings.Contains(got, `"positionEncoding":"utf-8"`) {
+		t.Errorf("initialize: got %s, want utf-8", got)
+	}
+	if got := string(replies["2"]["result"]); !strings.Contains(got, `"start":{"line":2,"character":7}`) {
+		t.Errorf("definition: got %s, want y's parameter", got)
+	}
+}
+
+// TestLSPIncrementalChanges renames a parameter of a CRLF document with an
+// emoji through ranged didChange edits, then checks the buffer and that
+// definition answers as for the same text opened in one piece.
+func TestLSPIncrementalChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(path, []byte("package p\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	newServer := func() *lspServer {
+		return &lspServer{encoding: "utf-16", overlays: make(map[string]string), versions: make(map[string]int)}
+	}
+	send := func(s *lspServer, method, params string) (interface{}, *lspError) {
+		t.Helper()
+		return s.handle(&lspMessage{JSONRPC: "2.0", Method: method, Params: json.RawMessage(params)})
+	}
+	open := func(s *lspServer, src string) {
+		t.Helper()
+		text, _ := json.Marshal(src)
+		if _, err := send(s, "textDocument/didOpen", `{"textDocument":{"uri":"`+uri+`","version":1,"text":`+string(text)+`}}`); err != nil {
+			t.Fatal(err.Message)
+		}
+	}
+	edit := func(line, char, endLine, endChar int, text string) string {
+		return fmt.Sprintf(`{"range":{"start":{"line":%d,"character":%d},"end":{"line":%d,"character":%d}},"text":%q}`, line, char, endLine, endChar, text)
+	}
+	change := func(s *lspServer, version int, edits ...string) *lspError {
+		_, err := send(s, "textDocument/didChange", fmt.Sprintf(`{"textDocument":{"uri":"%s","version":%d},"contentChanges":[%s]}`, uri, version, strings.Join(edits, ",")))
+		return err
+	}
+
+	s := newServer()
+	open(s, "package p\r\n\r\nfunc f(y string) string {\r\n\ts := \"\U0001F600\" + y\r\n\treturn s + y\r\n}\r\n")
+	// The y after the emoji is at UTF-16 character 13; the edit past the
+	// end of line 4 stops before its "\r".
+	if err := change(s, 2, edit(2, 7, 2, 8, "name"), edit(3, 13, 3, 14, "name")); err != nil {
+		t.Fatal(err.Message)
+	}
+	if err := change(s, 3, edit(4, 12, 4, 13, "name"), edit(4, 99, 4, 99, " // done")); err != nil {
+		t.Fatal(err.Message)
+	}
+	if err := change(s, 3, edit(0, 0, 0, 7, "")); err == nil {
+		t.Error("got a stale version applied, want it rejected")
+	}
+	want := "package p\r\n\r\nfunc f(name string) string {\r\n\ts := \"\U0001F600\" + name\r\n\treturn s + name // done\r\n}\r\n"
+	if got := s.overlays[path]; got != want {
+		t.Fatalf("got buffer %q, want %q", got, want)
+	}
+
+	baseline := newServer()
+	open(baseline, want)
+	query := lspPositionParams{Position: lspPosition{Line: 3, Character: 15}}
+	query.TextDocument.URI = uri
+	got, err := s.definition(s.input(query))
+	if err != nil {
+		t.Fatal(err.Message)
+	}
+	wantDef, _ := baseline.definition(baseline.input(query))
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(wantDef)
+	if string(gotJSON) != string(wantJSON) || !strings.Contains(string(gotJSON), `"start":{"line":2,"character":7},"end":{"line":2,"character":11}`) {
+		t.Errorf("definition: got %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestSemanticTokens(t *testing.T) {
+	file := fixture(t, "main.go")
+	out := semanticTokens(Input{File: file})
+	if out == nil {
+		t.Fatal("got nil")
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct{ Data []int }
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Data)%5 != 0 || len(decoded.Data)/5 != len(out.tokens) {
+		t.Fatalf("got %d integers for %d tokens", len(decoded.Data), len(out.tokens))
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(src), "\n")
+
+	// Undo the delta encoding; every token must land on an identifier.
+	got := make(map[string]string)
+	line, col := 0, 0
+	for i := 0; i < len(decoded.Data); i += 5 {
+		d := decoded.Data[i : i+5]
+		if d[0] > 0 {
+			col = 0
+		}
+		line, col = line+d[0], col+d[1]
+		if tok := out.tokens[i/5]; tok.Range.Start != (Pos{line, col}) {
+			t.Fatalf("token %d decodes to %d:%d, want %+v", i/5, line, col, tok.Range.Start)
+		}
+		name := lines[line][col : col+d[2]]
+		var mods []string
+		for bit, m := range semanticTokenModifiers {
+			if d[4]&(1<<bit) != 0 {
+				mods = append(mods, m)
+			}
+		}
+		got[fmt.Sprintf("%d:%d", line, col)] = fmt.Sprintf("%s %s %v", name, semanticTokenTypes[d[3]], mods)
+	}
+
+	for pos, want := range map[string]string{
+		// The receiver of sumResults and the results field it ranges over.
+		"33:6":  "p parameter [declaration readonly pointer]",
+		"35:21": "results property [readonly pointer]",
+		// sum is accumulated in main; x is never reassigned.
+		"66:1": "sum variable [declaration]",
+		"61:1": "x variable [declaration readonly]",
+		"68:9": "v variable [readonly]",
+		// total is incremented by the literal that captures it.
+		"80:2": "total variable [captured]",
+	} {
+		if got[pos] != want {
+			t.Errorf("%s: got %q, want %q", pos, got[pos], want)
+		}
+	}
+}
+
+func TestSchemaCoversProtocol(t *testing.T) {
+	schema := protocolSchema()
+	defs := schema["$defs"].(map[string]interface{})
+
+	// Every exported struct of the package is part of the protocol and
+	// must be reachable from the request or a registered response.
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := make(map[string]bool)
+	for _, f := range pkgs["main"].Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.TypeSpec:
+				if _, ok := node.Type.(*ast.StructType); ok && node.Name.IsExported() && defs[node.Name.Name] == nil {
+					t.Errorf("struct %s is not reachable from protocolModes", node.Name.Name)
+				}
+			case *ast.SwitchStmt:
+				if sel, ok := node.Tag.(*ast.SelectorExpr); ok && types.ExprString(sel) == "in.Mode" {
+					for _, stmt := range node.Body.List {
+						for _, e := range stmt.(*ast.CaseClause).List {
+							modes[strings.Trim(e.(*ast.BasicLit).Value, `"`)] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	// The registration table lists exactly the modes main dispatches.
+	registered := make(map[string]bool)
+	for _, m := range protocolModes {
+		registered[m.name] = true
+		if m.name != "" && !modes[m.name] {
+			t.Errorf("registered mode %q is not handled by main", m.name)
+		}
+	}
+	if len(modes) == 0 {
+		t.Fatal("found no mode switch in main")
+	}
+	for mode := range modes {
+		if !registered[mode] {
+			t.Errorf("mode %q is missing from protocolModes", mode)
+		}
+	}
+	for key := range schemaEnums {
+		typeName, field, _ := strings.Cut(key, ".")
+		def, ok := defs[typeName].(map[string]interface{})
+		if !ok || def["properties"].(map[string]interface{})[field] == nil {
+			t.Errorf("schemaEnums key %s names no field", key)
+		}
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatal(err)
+	}
+	input := defs["Input"].(map[string]interface{})["properties"].(map[string]interface{})
+	if enum := input["mode"].(map[string]interface{})["enum"].([]string); len(enum) != len(protocolModes) {
+		t.Errorf("got mode enum %v", enum)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := capabilities()
+	if caps.ProtocolVersion != protocolVersion || len(caps.Modes) != len(protocolModes)-1 {
+		t.Fatalf("got version %d and %d modes", caps.ProtocolVersion, len(caps.Modes))
+	}
+	modes := strings.Join(caps.Modes, " ")
+	for _, mode := range []string{"analyze", "race_report", "capabilities", "rename_check"} {
+		if !strings.Contains(" "+modes+" ", " "+mode+" ") {
+			t.Errorf("modes %q lack %s", modes, mode)
+		}
+	}
+	codes := make(map[string]FindingCode)
+	for _, f := range caps.Findings {
+		if _, dup := codes[f.Code]; dup {
+			t.Errorf("code %s listed twice", f.Code)
+		}
+		codes[f.Code] = f
+	}
+	if len(codes) != len(analyzers) {
+		t.Errorf("got %d codes for %d analyzers", len(codes), len(analyzers))
+	}
+	if f := codes["GA101"]; f.Analyzer != "fieldwrite" || f.Report != "race" || f.Severity != "warning" || f.Title == "" {
+		t.Errorf("got GA101 %+v", f)
+	}
+	if fmt.Sprint(caps.PositionEncodings) != "[byte visual]" {
+		t.Errorf("got position encodings %v", caps.PositionEncodings)
+	}
+}
+
+// TestResolveAcrossFiles resolves package variables used in both files of
+// the multifile fixture, where uses sit at the same line and column in each
+// file, and checks every use is reported against its own file.
+func TestResolveAcrossFiles(t *testing.T) {
+	a, b := fixture(t, "multifile/a.go"), fixture(t, "multifile/b.go")
+	type fileUse struct {
+		file      string
+		line, col int
+	}
+	tests := []struct {
+		name string
+		in   Input
+		decl fileUse
+		uses []fileUse
+	}{
+		{"shared", Input{File: a, Line: 4, Col: 1}, fileUse{a, 4, 1}, []fileUse{{a, 8, 8}, {b, 4, 1}, {b, 8, 8}}},
+		{"shared", Input{File: b, Line: 8, Col: 8}, fileUse{a, 4, 1}, []fileUse{{a, 8, 8}, {b, 4, 1}, {b, 8, 8}}},
+		{"x", Input{File: b, Line: 8, Col: 17}, fileUse{a, 3, 1}, []fileUse{{a, 8, 17}, {b, 8, 17}}},
+	}
+	for _, tt := range tests {
+		out := resolve(tt.in)
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%s %d:%d: got %+v, want %s", tt.in.File, tt.in.Line, tt.in.Col, out, tt.name)
+		}
+		if got := (fileUse{out.Decl.File, out.Decl.Start.Line, out.Decl.Start.Col}); got != tt.decl {
+			t.Errorf("%s: got decl %+v, want %+v", tt.name, got, tt.decl)
+		}
+		if len(out.Uses) != len(tt.uses) {
+			t.Fatalf("%s: got %d uses %+v, want %d", tt.name, len(out.Uses), out.Uses, len(tt.uses))
+		}
+		for i, w := range tt.uses {
+			r := out.Uses[i].Range
+			if got := (fileUse{r.File, r.Start.Line, r.Start.Col}); got != w {
+				t.Errorf("%s use %d: got %+v, want %+v", tt.name, i, got, w)
+			}
+		}
+	}
+}
+
+// TestResolveVendoredSelector resolves a field of a vendored package to its
+// declaration in the module's vendor tree.
+func TestResolveVendoredSelector(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+	// cfg.Loud = true
+	out := resolve(Input{File: fixture(t, "vendored/main.go"), Line: 10, Col: 5})
+	if out == nil || out.Name != "Loud" {
+		t.Fatalf("got %+v, want Loud", out)
+	}
+	if want := fixture(t, "vendored/vendor/example.com/greet/greet.go"); out.Decl.File != want {
+		t.Errorf("decl in %s, want %s", out.Decl.File, want)
+	}
+	if out.Decl.Start.Line != 4 || out.Decl.Start.Col != 1 || out.Decl.End.Line != 4 || out.Decl.End.Col != 5 {
+		t.Errorf("decl at %+v, want 4:1-4:5", out.Decl)
+	}
+	checkUses(t, out, []useWant{{line: 10, col: 5, reassign: true}, {line: 11, col: 27}})
+}
+
+// TestVendorMode gates vendored imports the way the go command does and
+// type-checks only the vendored files matching the host's build constraints.
+func TestVendorMode(t *testing.T) {
+	root := t.TempDir()
+	pkgDir := filepath.Join(root, "vendor", "example.com", "greet")
+	files := map[string]string{
+		"vendor/modules.txt":                                    "# example.com/greet v0.1.0\n## explicit\nexample.com/greet\n",
+		"vendor/example.com/greet/greet.go":                     "package greet\n\nfunc Hello() string { return \"hi\" }\n",
+		"vendor/example.com/greet/greet_bad.go":                 "//go:build ignore\n\npackage greet\n\nfunc Hello() int { return 0 }\n\nfunc Ignored() {}\n",
+		"vendor/example.com/greet/other_" + otherGOOS() + ".go": "package greet\n\nfunc Hello() bool { return true }\n\nfunc Foreign() {}\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeGoMod := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		gomod   string
+		goflags string
+		want    bool
+	}{
+		{"module m\n\ngo 1.20\n", "", true},
+		{"module m\n\ngo 1.20\n", "-mod=mod", false},
+		{"module m\n\ngo 1.20\n", "-trimpath -mod=readonly", false},
+		{"module m\n\ngo 1.13\n", "", false},
+		{"module m\n\ngo 1.13\n", "-mod=vendor", true},
+		{"module m\n", "", true},
+	}
+	for _, tt := range tests {
+		writeGoMod(tt.gomod)
+		t.Setenv("GOFLAGS", tt.goflags)
+		got := findVendoredPackage(root, "example.com/greet")
+		if (got == pkgDir) != tt.want {
+			t.Errorf("go.mod %q, GOFLAGS %q: got %q, want vendored=%v", tt.gomod, tt.goflags, got, tt.want)
+		}
+	}
+
+	vi := newVendorImporter(token.NewFileSet())
+	pkg, err := vi.importVendored("example.com/greet", pkgDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Scope().Lookup("Ignored") != nil || pkg.Scope().Lookup("Foreign") != nil {
+		t.Errorf("got scope %v, want build-constrained files excluded", pkg.Scope().Names())
+	}
+	hello, _ := pkg.Scope().Lookup("Hello").(*types.Func)
+	if hello == nil || hello.Type().String() != "func() string" {
+		t.Errorf("got Hello %v, want the unconstrained declaration", hello)
+	}
+}
+
+// otherGOOS returns an operating system other than the host's, for file
+// names that build constraints must exclude.
+func otherGOOS() string {
+	if build.Default.GOOS == "windows" {
+		return "linux"
+	}
+	return "windows"
+}
+
+// TestVisualColumnsAfterTabs converts columns after the leading tabs of the
+// select statements in business_heavy.go for both common tab sizes.
+func TestVisualColumnsAfterTabs(t *testing.T) {
+	file := fixture(t, "business_heavy.go")
+	selects := []struct{ line, tabs int }{{81, 1}, {96, 4}, {112, 3}, {250, 2}}
+	for _, tabSize := range []int{4, 8} {
+		cols := newColumnMapper(Input{File: file, ColumnMode: "visual", TabSize: tabSize})
+		for _, sel := range selects {
+			visual := sel.tabs * tabSize
+			if got := cols.toVisual(cols.target, sel.line, sel.tabs); got != visual {
+				t.Errorf("tab %d line %d: toVisual(%d) = %d, want %d", tabSize, sel.line, sel.tabs, got, visual)
+			}
+			if got := cols.toByte(cols.target, sel.line, visual); got != sel.tabs {
+				t.Errorf("tab %d line %d: toByte(%d) = %d, want %d", tabSize, sel.line, visual, got, sel.tabs)
+			}
+			// A column inside the last tab maps to that tab.
+			if got := cols.toByte(cols.target, sel.line, visual-1); got != sel.tabs-1 {
+				t.Errorf("tab %d line %d: toByte(%d) = %d, want %d", tabSize, sel.line, visual-1, got, sel.tabs-1)
+			}
+		}
+
+		// case id := <-a.queue: id starts at byte 9 after four tabs.
+		visual := 4*tabSize + 5
+		out := resolve(Input{File: file, Line: 99, Col: cols.toByte(cols.target, 99, visual)})
+		if out == nil || out.Name != "id" {
+			t.Fatalf("tab %d: got %+v, want id", tabSize, out)
+		}
+		out.mapRanges(cols.mapRange)
+		if out.Decl.Start.Col != visual || out.Decl.End.Col != visual+2 {
+			t.Errorf("tab %d: got decl %+v, want visual columns %d-%d", tabSize, out.Decl, visual, visual+2)
+		}
+	}
+}
+
+// requireGo skips t unless the toolchain supports the release, such as
+// "go1.23" for fixtures that range over functions.
+func requireGo(t *testing.T, release string) {
+	t.Helper()
+	for _, tag := range build.Default.ReleaseTags {
+		if tag == release {
+			return
+		}
+	}
+	t.Skipf("needs %s", release)
+}
+
+// TestResolveRangeLoops resolves the variables of range-over-int,
+// range-over-func and range-over-chan loops, including writes in the body
+// and captures by goroutines.
+func TestResolveRangeLoops(t *testing.T) {
+	requireGo(t, "go1.23")
+	file := fixture(t, "rangeloops/main.go")
+	tests := []struct {
+		name      string
+		line, col int
+		uses      []useWant
+	}{
+		// for i := range 10
+		{"i", 32, 5, []useWant{{line: 33, col: 2, reassign: true}, {line: 33, col: 6}, {line: 37, col: 15, captured: true}}},
+		// for n := range countTo(3)
+		{"n", 45, 5, []useWant{{line: 46, col: 2, reassign: true}, {line: 46, col: 6}, {line: 50, col: 15, captured: true}}},
+		// for k, v := range pairs(...)
+		{"k", 53, 5, []useWant{{line: 54, col: 14}, {line: 55, col: 14}}},
+		{"v", 53, 8, []useWant{{line: 54, col: 2, reassign: true}, {line: 54, col: 6}, {line: 55, col: 17}}},
+		// for x := range ch
+		{"x", 65, 5, []useWant{{line: 67, col: 12, captured: true}}},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tt.line, tt.col, out, tt.name)
+		}
+		if out.Decl.Start.Line != tt.line || out.Decl.Start.Col != tt.col {
+			t.Errorf("%s: got decl %+v, want %d:%d", tt.name, out.Decl.Start, tt.line, tt.col)
+		}
+		checkUses(t, out, tt.uses)
+	}
+}
+
+// TestResolveCommaOk resolves both variables of the map index, type
+// assertion and channel receive comma-ok forms. The map form's ok reuses the
+// ok declared by the earlier call, so it is a reassignment there.
+func TestResolveCommaOk(t *testing.T) {
+	file := fixture(t, "commaok/main.go")
+	tests := []struct {
+		name      string
+		line, col int
+		declLine  int
+		declCol   int
+		uses      []useWant
+	}{
+		// v, ok := m["a"]
+		{"v", 25, 1, 25, 1, []useWant{{line: 27, col: 14}}},
+		{"ok", 25, 4, 19, 4, []useWant{{line: 20, col: 4}, {line: 25, col: 4, reassign: true}, {line: 26, col: 4}}},
+		// w, isUser := x.(*User)
+		{"w", 31, 1, 31, 1, []useWant{{line: 33, col: 14}}},
+		{"isUser", 31, 4, 31, 4, []useWant{{line: 32, col: 4}}},
+		// n, open := <-ch
+		{"n", 38, 1, 38, 1, []useWant{{line: 40, col: 14}}},
+		{"open", 38, 4, 38, 4, []useWant{{line: 39, col: 4}}},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tt.line, tt.col, out, tt.name)
+		}
+		if out.Decl.Start.Line != tt.declLine || out.Decl.Start.Col != tt.declCol {
+			t.Errorf("%s: got decl %+v, want %d:%d", tt.name, out.Decl.Start, tt.declLine, tt.declCol)
+		}
+		checkUses(t, out, tt.uses)
+	}
+}
+
+// TestResolveUseOrder resolves a variable used in both files of the
+// multifile fixture repeatedly and expects its uses in file, line and
+// column order every time.
+func TestResolveUseOrder(t *testing.T) {
+	a, b := fixture(t, "multifile/a.go"), fixture(t, "multifile/b.go")
+	want := fmt.Sprint([]string{a + ":8:8", b + ":4:1", b + ":8:8"})
+	for i := 0; i < 20; i++ {
+		out := resolve(Input{File: a, Line: 4, Col: 1})
+		if out == nil || out.Name != "shared" {
+			t.Fatalf("run %d: got %+v, want shared", i, out)
+		}
+		var got []string
+		for _, u := range out.Uses {
+			got = append(got, fmt.Sprintf("%s:%d:%d", u.Range.File, u.Range.Start.Line, u.Range.Start.Col))
+		}
+		if fmt.Sprint(got) != want {
+			t.Fatalf("run %d: got %v, want %s", i, got, want)
+		}
+	}
+}
+
+// TestResolveShadowedBuiltins resolves user variables named after builtins
+// at function and package scope, leaves real builtin calls unresolved, and
+// keeps a type switch guard from resolving to the outer variable it shadows.
+func TestResolveShadowedBuiltins(t *testing.T) {
+	file := fixture(t, "shadowbuiltins/main.go")
+	tests := []struct {
+		name      string
+		line, col int
+		declLine  int
+		declCol   int
+		uses      []useWant
+	}{
+		{"len", 12, 1, 12, 1, []useWant{{line: 13, col: 13}}},
+		{"len", 13, 13, 12, 1, []useWant{{line: 13, col: 13}}},
+		{"min", 16, 13, 15, 1, []useWant{{line: 16, col: 13}}},
+		{"max", 16, 19, 5, 4, []useWant{{line: 16, col: 19}, {line: 53, col: 13}}},
+		{"max", 53, 13, 5, 4, []useWant{{line: 16, col: 19}, {line: 53, col: 13}}},
+		{"clear", 20, 5, 19, 1, []useWant{{line: 20, col: 4}}},
+		// len := undefinedCall() fails to check but still shadows len.
+		{"len", 34, 13, 33, 1, []useWant{{line: 34, col: 13}}},
+		// The type switch guard v, then its use in the case clause.
+		{"v", 41, 8, 41, 8, []useWant{{line: 43, col: 9}}},
+		{"v", 43, 9, 41, 8, []useWant{{line: 43, col: 9}}},
+	}
+	for _, tt := range tests {
+		out := resolve(Input{File: file, Line: tt.line, Col: tt.col})
+		if out == nil || out.Name != tt.name {
+			t.Fatalf("%d:%d: got %+v, want %s", tt.line, tt.col, out, tt.name)
+		}
+		if out.Decl.Start.Line != tt.declLine || out.Decl.Start.Col != tt.declCol {
+			t.Errorf("%d:%d: got decl %+v, want %d:%d", tt.line, tt.col, out.Decl.Start, tt.declLine, tt.declCol)
+		}
+		checkUses(t, out, tt.uses)
+	}
+	// len(s), min(1, 2) and clear(m) in builtins() call the real builtins.
+	for _, pos := range [][2]int{{27, 13}, {27, 21}, {29, 1}} {
+		if out := resolve(Input{File: file, Line: pos[0], Col: pos[1]}); out != nil {
+			t.Errorf("%d:%d: got %+v, want nil for the builtin", pos[0], pos[1], out)
+		}
+	}
+}
+
+// TestResolveCaptureGlobals marks the goroutine's update of globalCounter as
+// captured once CaptureGlobals is set, but not the write in init.
+func TestResolveCaptureGlobals(t *testing.T) {
+	in := Input{File: fixture(t, "main.go"), Line: 9, Col: 4}
+	checkUses(t, resolve(in), []useWant{{line: 12, reassign: true}, {line: 85, reassign: true}})
+
+	in.CaptureGlobals = true
+	out := resolve(in)
+	if out == nil || out.Name != "globalCounter" {
+		t.Fatalf("got %+v, want globalCounter", out)
+	}
+	checkUses(t, out, []useWant{
+		{line: 12, col: 1, reassign: true},                 // globalCounter = 42 in init
+		{line: 85, col: 3, reassign: true, captured: true}, // globalCounter += i in the goroutine
+	})
+}
+
+// TestResolvePackageInit flags uses in package-level initializers and in
+// init, but not in a function literal's body or in main.
+func TestResolvePackageInit(t *testing.T) {
+	out := resolve(Input{File: fixture(t, "initorder/main.go"), Line: 5, Col: 4})
+	if out == nil || out.Name != "b" {
+		t.Fatalf("got %+v, want b", out)
+	}
+	want := []struct {
+		line, col int
+		init      bool
+	}{
+		{4, 8, true},   // var a = b + 1
+		{7, 32, true},  // var table = map[string]int{"b": b}
+		{9, 31, false}, // var lazy = func() int { return b * 2 }
+		{12, 1, true},  // b = 3 in init
+		{13, 16, true}, // fmt.Println(a, b) in init
+		{17, 16, false},
+	}
+	if len(out.Uses) != len(want) {
+		t.Fatalf("got %d uses %+v, want %d", len(out.Uses), out.Uses, len(want))
+	}
+	for i, w := range want {
+		got := out.Uses[i]
+		if got.Range.Start.Line != w.line || got.Range.Start.Col != w.col || got.PackageInit != w.init {
+			t.Errorf("use %d: got %d:%d package_init=%v, want %d:%d package_init=%v",
+				i, got.Range.Start.Line, got.Range.Start.Col, got.PackageInit, w.line, w.col, w.init)
+		}
+	}
+}
+
+// TestSyntaxOnlyUseOrder resolves a variable used in both files of the
+// multifile fixture from syntax alone, walking the files in either order,
+// and expects the same file, line and column order every time.
+func TestSyntaxOnlyUseOrder(t *testing.T) {
+	a, b := fixture(t, "multifile/a.go"), fixture(t, "multifile/b.go")
+	in := Input{File: a, Line: 4, Col: 1}
+	lp := loadPackage(in)
+	if lp == nil || len(lp.files) != 2 {
+		t.Fatalf("got %+v, want both files loaded", lp)
+	}
+	want := fmt.Sprint([]string{a + ":8:8", b + ":4:1", b + ":8:8"})
+	for i := 0; i < 10; i++ {
+		order := *lp
+		order.files = []*ast.File{lp.files[i%2], lp.files[1-i%2]}
+		out := syntaxResolve(&order, in)
+		if out == nil || out.Name != "shared" {
+			t.Fatalf("run %d: got %+v, want shared", i, out)
+		}
+		var got []string
+		for _, u := range out.Uses {
+			got = append(got, fmt.Sprintf("%s:%d:%d", u.Range.File, u.Range.Start.Line, u.Range.Start.Col))
+		}
+		if fmt.Sprint(got) != want {
+			t.Fatalf("run %d: got %v, want %s", i, got, want)
+		}
+	}
+}
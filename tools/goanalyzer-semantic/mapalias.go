@@ -0,0 +1,47 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// mapAliasAnalyzer flags maps held in a variable that are stored in a field
+// or package-level variable. Both names then refer to one map: writes
+// through either are visible to both, and the map cannot be freed while
+// the long-lived reference exists.
+var mapAliasAnalyzer = &analyzer{
+	name:     "mapalias",
+	code:     "GA203",
+	summary:  "Map stored in a field or package-level variable",
+	severity: "warning",
+	report:   "retention",
+	run:      runMapAlias,
+}
+
+func runMapAlias(p *pass) []Finding {
+	var findings []Finding
+	p.LongLivedAssigns(func(lhs, rhs ast.Expr) {
+		id, ok := passes.Unparen(rhs).(*ast.Ident)
+		if !ok {
+			return
+		}
+		v, ok := p.info.Uses[id].(*types.Var)
+		if !ok {
+			return
+		}
+		if _, ok := v.Type().Underlying().(*types.Map); !ok {
+			return
+		}
+		finding := Finding{
+			Message: types.ExprString(lhs) + " now shares the map referenced by " + v.Name() + "; clone it to keep an independent copy",
+			Range:   p.rangeForNode(lhs),
+		}
+		if decl, ok := p.objectRange(v); ok {
+			finding.Related = []RelatedRange{{Range: decl, Message: v.Name() + " is declared here"}}
+		}
+		findings = append(findings, finding)
+	})
+	return findings
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// mapRaceAnalyzer flags writes to a map, through indexing or delete, on one
+// goroutine while another goroutine accesses the same map variable or field
+// without a common lock. Unlike other data races, concurrent map writes
+// crash the program with a fatal error.
+var mapRaceAnalyzer = &analyzer{
+	name:     "maprace",
+	code:     "GA106",
+	summary:  "Map written on one goroutine and accessed on another without a lock",
+	severity: "error",
+	report:   "race",
+	run:      runMapRace,
+}
+
+type mapAccess struct {
+	expr  ast.Expr
+	write bool
+	ctx   ast.Node
+	locks map[types.Object]bool
+}
+
+func runMapRace(p *pass) []Finding {
+	launched := passes.LaunchedFuncs(p.file, p.info)
+	accesses := make(map[types.Object][]*mapAccess)
+	record := func(m ast.Expr, site ast.Expr, write bool) {
+		typ := p.info.TypeOf(m)
+		if typ == nil {
+			return
+		}
+		if _, ok := typ.Underlying().(*types.Map); !ok {
+			return
+		}
+		obj := passes.ExprObject(m, p.info)
+		if obj == nil {
+			return
+		}
+		accesses[obj] = append(accesses[obj], &mapAccess{
+			expr:  site,
+			write: write,
+			ctx:   passes.GoroutineContext(site, p.parents, p.info, launched),
+			locks: passes.HeldLocks(site, p.parents, p.info),
+		})
+	}
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IndexExpr:
+			_, incDec := p.parents[n].(*ast.IncDecStmt)
+			record(n.X, n, incDec || p.IsAssignTarget(n))
+		case *ast.RangeStmt:
+			record(n.X, n.X, false)
+		case *ast.CallExpr:
+			if id, ok := passes.Unparen(n.Fun).(*ast.Ident); ok && len(n.Args) > 0 {
+				if b, ok := p.info.Uses[id].(*types.Builtin); ok && b.Name() == "delete" {
+					record(n.Args[0], n, true)
+				}
+			}
+		}
+		return true
+	})
+
+	var findings []Finding
+	for obj, list := range accesses {
+		for _, w := range list {
+			if !w.write {
+				continue
+			}
+			var related []RelatedRange
+			for _, a := range list {
+				if a.ctx == w.ctx || passes.SharesLock(a.locks, w.locks) {
+					continue
+				}
+				kind := "read"
+				if a.write {
+					kind = "write"
+				}
+				related = append(related, RelatedRange{
+					Range:   p.rangeForNode(a.expr),
+					Message: "unsynchronized " + kind + " of " + obj.Name() + " on another goroutine",
+				})
+			}
+			if len(related) == 0 {
+				continue
+			}
+			findings = append(findings, Finding{
+				Message: "map " + obj.Name() + " is written here while another goroutine accesses it without a common lock",
+				Range:   p.rangeForNode(w.expr),
+				Related: related,
+			})
+		}
+	}
+	return findings
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// MetricsOutput is the response of "metrics" mode: one entry per function
+// declaration of the target file, in source order.
+type MetricsOutput struct {
+	Functions []FunctionMetrics `json:"functions"`
+}
+
+// FunctionMetrics summarizes one function. Function literals count towards
+// the declaration they appear in.
+type FunctionMetrics struct {
+	// Name is "f" for functions and "T.m" for methods.
+	Name       string `json:"name"`
+	Range      Range  `json:"range"`
+	Complexity int    `json:"complexity"`
+	Statements int    `json:"statements"`
+	// MaxNesting is the deepest nesting of if, for, switch and select
+	// statements and function literals.
+	MaxNesting int `json:"max_nesting"`
+	Goroutines int `json:"goroutines"`
+	// Locks is the number of distinct mutexes locked.
+	Locks int `json:"locks"`
+	// LockedStatements counts the statements that run while any mutex is
+	// held, as tracked by passes.HeldLocks.
+	LockedStatements int `json:"locked_statements"`
+}
+
+func (o *MetricsOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Functions {
+		f(&o.Functions[i].Range)
+	}
+}
+
+func metrics(in Input) *MetricsOutput {
+	lp := loadPackage(in)
+	if lp == nil {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	out := &MetricsOutput{Functions: make([]FunctionMetrics, 0)}
+	for _, decl := range lp.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil {
+			continue
+		}
+		m := FunctionMetrics{
+			Name:       callerName(fd.Name, parents),
+			Range:      rangeForPos(lp.fset, fd.Pos(), fd.End()),
+			Complexity: cyclomaticComplexity(fd.Body),
+			MaxNesting: nestingDepth(fd.Body),
+		}
+		locks := make(map[types.Object]bool)
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.GoStmt:
+				m.Goroutines++
+			case *ast.CallExpr:
+				if obj, method := passes.LockCall(node, lp.info); obj != nil && (method == "Lock" || method == "RLock") {
+					locks[obj] = true
+				}
+			}
+			switch n.(type) {
+			case nil, *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			case ast.Stmt:
+				m.Statements++
+				if len(passes.HeldLocks(n, parents, lp.info)) > 0 {
+					m.LockedStatements++
+				}
+			}
+			return true
+		})
+		m.Locks = len(locks)
+		out.Functions = append(out.Functions, m)
+	}
+	return out
+}
+
+// cyclomaticComplexity returns 1 plus the number of branch points in body:
+// if, for and range statements, non-default switch and select cases, and
+// && and || operators.
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if node.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if node.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if node.Op == token.LAND || node.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// nestingDepth returns the deepest nesting of control statements and
+// function literals below node.
+func nestingDepth(node ast.Node) int {
+	max := 0
+	var walk func(n ast.Node, depth int)
+	walk = func(n ast.Node, depth int) {
+		ast.Inspect(n, func(c ast.Node) bool {
+			if c == n {
+				return true
+			}
+			switch c.(type) {
+			case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt,
+				*ast.TypeSwitchStmt, *ast.SelectStmt, *ast.FuncLit:
+				if depth+1 > max {
+					max = depth + 1
+				}
+				walk(c, depth+1)
+				return false
+			}
+			return true
+		})
+	}
+	walk(node, 0)
+	return max
+}
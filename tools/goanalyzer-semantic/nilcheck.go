@@ -0,0 +1,279 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// nilChecked annotates a use of the pointer variable v that dereferences it
+// (a field selector, *p, or an index into a pointer to an array). It is
+// "true" when a nil comparison or a non-nil assignment dominates the use,
+// "false" when the walk back reaches the variable's definition, a nil
+// initial value or a parameter without finding one, and "unknown" when v is
+// written inside a loop around the use or in a nested statement before it,
+// or is a package-level variable. Other uses get "".
+//
+// The walk is intraprocedural and structural: it climbs from the use
+// through the enclosing statements, looking at if conditions guarding the
+// branch it came from and at the statements preceding it in each block,
+// where `if p == nil { return }` counts as a guard. Function literals
+// inherit the checks of the code around them.
+func nilChecked(ident *ast.Ident, v *types.Var, info *types.Info, parents map[ast.Node]ast.Node) string {
+	if v.IsField() || !isDeref(ident, info, parents) {
+		return ""
+	}
+	if _, ok := v.Type().Underlying().(*types.Pointer); !ok {
+		return ""
+	}
+	if v.Pkg() != nil && v.Parent() == v.Pkg().Scope() {
+		return "unknown"
+	}
+	var cur ast.Node = ident
+	for p := parents[cur]; p != nil; cur, p = p, parents[p] {
+		switch node := p.(type) {
+		case *ast.BinaryExpr:
+			if node.Y == cur && (node.Op == token.LAND && nonNilWhenTrue(node.X, v, info) ||
+				node.Op == token.LOR && nonNilWhenFalse(node.X, v, info)) {
+				return "true"
+			}
+		case *ast.IfStmt:
+			if cur == node.Body && nonNilWhenTrue(node.Cond, v, info) ||
+				cur == node.Else && nonNilWhenFalse(node.Cond, v, info) {
+				return "true"
+			}
+			if cur != node.Init && node.Init != nil {
+				if state, done := precedingNilState([]ast.Stmt{node.Init}, v, info); done {
+					return state
+				}
+			}
+		case *ast.ForStmt:
+			// The condition is checked again before every iteration, and
+			// the post statement runs only after an iteration it allowed.
+			if cur == node.Body && nonNilWhenTrue(node.Cond, v, info) ||
+				cur == node.Post && !writesVar(node.Body, v, info) && nonNilWhenTrue(node.Cond, v, info) {
+				return "true"
+			}
+			if (cur == node.Body || cur == node.Post) && (writesVar(node.Body, v, info) || node.Post != nil && writesVar(node.Post, v, info)) {
+				return "unknown"
+			}
+			if cur != node.Init && node.Init != nil {
+				if state, done := precedingNilState([]ast.Stmt{node.Init}, v, info); done {
+					return state
+				}
+			}
+		case *ast.RangeStmt:
+			if cur == node.Body && writesVar(node.Body, v, info) {
+				return "unknown"
+			}
+		case *ast.BlockStmt:
+			switch parents[node].(type) {
+			case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				continue
+			}
+			if state, done := precedingNilState(stmtsBefore(node.List, cur), v, info); done {
+				return state
+			}
+		case *ast.CaseClause:
+			if state, done := precedingNilState(stmtsBefore(node.Body, cur), v, info); done {
+				return state
+			}
+		case *ast.CommClause:
+			if state, done := precedingNilState(stmtsBefore(node.Body, cur), v, info); done {
+				return state
+			}
+		case *ast.FuncDecl:
+			return "false"
+		}
+	}
+	return "false"
+}
+
+// isDeref reports whether ident is dereferenced where it is used.
+func isDeref(ident *ast.Ident, info *types.Info, parents map[ast.Node]ast.Node) bool {
+	var expr ast.Node = ident
+	for {
+		p, ok := parents[expr].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		expr = p
+	}
+	switch p := parents[expr].(type) {
+	case *ast.SelectorExpr:
+		s := info.Selections[p]
+		return p.X == expr && s != nil && s.Kind() == types.FieldVal
+	case *ast.StarExpr:
+		return true
+	case *ast.IndexExpr:
+		if p.X != expr {
+			return false
+		}
+		if ptr, ok := info.TypeOf(ident).Underlying().(*types.Pointer); ok {
+			_, ok := ptr.Elem().Underlying().(*types.Array)
+			return ok
+		}
+	}
+	return false
+}
+
+func stmtsBefore(list []ast.Stmt, cur ast.Node) []ast.Stmt {
+	for i, s := range list {
+		if s == cur {
+			return list[:i]
+		}
+	}
+	return nil
+}
+
+// precedingNilState scans stmts backwards from the use. It stops at a
+// terminating `if p == nil` guard, at a statement assigning p, which makes
+// the state that of the assigned value, and at a nested write, whose effect
+// it cannot tell.
+func precedingNilState(stmts []ast.Stmt, v *types.Var, info *types.Info) (string, bool) {
+	for i := len(stmts) - 1; i >= 0; i-- {
+		switch s := stmts[i].(type) {
+		case *ast.IfStmt:
+			if s.Else == nil && terminates(s.Body) && nonNilWhenFalse(s.Cond, v, info) {
+				return "true", true
+			}
+		case *ast.AssignStmt:
+			for j, lhs := range s.Lhs {
+				id, ok := passes.Unparen(lhs).(*ast.Ident)
+				if !ok || info.Defs[id] != v && info.Uses[id] != v {
+					continue
+				}
+				if len(s.Lhs) == len(s.Rhs) && s.Tok != token.ADD_ASSIGN && nonNilExpr(s.Rhs[j], info) {
+					return "true", true
+				}
+				return "false", true
+			}
+		case *ast.DeclStmt:
+			if gd, ok := s.Decl.(*ast.GenDecl); ok {
+				for _, spec := range gd.Specs {
+					vs, ok := spec.(*ast.ValueSpec)
+					if !ok {
+						continue
+					}
+					for j, name := range vs.Names {
+						if info.Defs[name] != v {
+							continue
+						}
+						if len(vs.Values) == len(vs.Names) && nonNilExpr(vs.Values[j], info) {
+							return "true", true
+						}
+						return "false", true
+					}
+				}
+			}
+		}
+		if writesVar(stmts[i], v, info) {
+			return "unknown", true
+		}
+	}
+	return "", false
+}
+
+// nonNilWhenTrue reports whether cond being true implies v != nil.
+func nonNilWhenTrue(cond ast.Expr, v *types.Var, info *types.Info) bool {
+	b, ok := passes.Unparen(cond).(*ast.BinaryExpr)
+	if !ok {
+		return false
+	}
+	switch b.Op {
+	case token.NEQ:
+		return isNilComparison(b, v, info)
+	case token.LAND:
+		return nonNilWhenTrue(b.X, v, info) || nonNilWhenTrue(b.Y, v, info)
+	}
+	return false
+}
+
+// nonNilWhenFalse reports whether cond being false implies v != nil.
+func nonNilWhenFalse(cond ast.Expr, v *types.Var, info *types.Info) bool {
+	b, ok := passes.Unparen(cond).(*ast.BinaryExpr)
+	if !ok {
+		return false
+	}
+	switch b.Op {
+	case token.EQL:
+		return isNilComparison(b, v, info)
+	case token.LOR:
+		return nonNilWhenFalse(b.X, v, info) || nonNilWhenFalse(b.Y, v, info)
+	}
+	return false
+}
+
+// isNilComparison reports whether b compares v with nil.
+func isNilComparison(b *ast.BinaryExpr, v *types.Var, info *types.Info) bool {
+	isV := func(e ast.Expr) bool {
+		id, ok := passes.Unparen(e).(*ast.Ident)
+		return ok && info.Uses[id] == v
+	}
+	isNil := func(e ast.Expr) bool {
+		return info.Types[passes.Unparen(e)].IsNil()
+	}
+	return isV(b.X) && isNil(b.Y) || isNil(b.X) && isV(b.Y)
+}
+
+// nonNilExpr reports whether expr is a pointer that cannot be nil: &x or
+// new(T).
+func nonNilExpr(expr ast.Expr, info *types.Info) bool {
+	switch e := passes.Unparen(expr).(type) {
+	case *ast.UnaryExpr:
+		return e.Op == token.AND
+	case *ast.CallExpr:
+		return isBuiltin(e, "new", info)
+	}
+	return false
+}
+
+// terminates reports whether the block ends by leaving the code that
+// follows it: a return, a branch statement or a call to panic.
+func terminates(body *ast.BlockStmt) bool {
+	if len(body.List) == 0 {
+		return false
+	}
+	switch s := body.List[len(body.List)-1].(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := passes.Unparen(s.X).(*ast.CallExpr); ok {
+			id, ok := passes.Unparen(call.Fun).(*ast.Ident)
+			return ok && id.Name == "panic"
+		}
+	}
+	return false
+}
+
+// writesVar reports whether node assigns v anywhere inside it.
+func writesVar(node ast.Node, v *types.Var, info *types.Info) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range s.Lhs {
+				if id, ok := passes.Unparen(lhs).(*ast.Ident); ok && (info.Defs[id] == v || info.Uses[id] == v) {
+					found = true
+				}
+			}
+		case *ast.RangeStmt:
+			for _, e := range []ast.Expr{s.Key, s.Value} {
+				if id, ok := e.(*ast.Ident); ok && s.Tok == token.ASSIGN && info.Uses[id] == v {
+					found = true
+				}
+			}
+		case *ast.UnaryExpr:
+			if id, ok := passes.Unparen(s.X).(*ast.Ident); ok && s.Op == token.AND && info.Uses[id] == v {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+)
+
+// OutlineOutput is the response of "outline" mode: the symbol tree of the
+// target file in source order.
+type OutlineOutput struct {
+	Symbols []OutlineSymbol `json:"symbols"`
+}
+
+// OutlineSymbol is one declaration in the outline. Kind is one of "const",
+// "var", "type", "struct", "interface", "func", "method" or "field".
+type OutlineSymbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Range covers the whole declaration and SelectionRange its name.
+	Range          Range `json:"range"`
+	SelectionRange Range `json:"selection_range"`
+	// TypeParams lists type parameters with their constraints, e.g.
+	// "K comparable". Methods of generic types list the receiver's.
+	TypeParams []string        `json:"type_params,omitempty"`
+	Children   []OutlineSymbol `json:"children,omitempty"`
+}
+
+func (o *OutlineOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	mapOutlineRanges(o.Symbols, f)
+}
+
+func mapOutlineRanges(symbols []OutlineSymbol, f func(*Range)) {
+	for i := range symbols {
+		f(&symbols[i].Range)
+		f(&symbols[i].SelectionRange)
+		mapOutlineRanges(symbols[i].Children, f)
+	}
+}
+
+// outline builds the symbol tree from the parsed target file alone; no
+// other file is read and nothing is type-checked. Methods are nested under
+// their receiver type when it is declared in the same file.
+func outline(in Input) *OutlineOutput {
+	if in.File == "" {
+		return nil
+	}
+	path := in.File
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	fset := token.NewFileSet()
+	file, _ := parseSingleFile(fset, path, in.Content)
+	if file == nil {
+		return nil
+	}
+	rangeOf := func(n ast.Node) Range { return rangeForPos(fset, n.Pos(), n.End()) }
+
+	out := &OutlineOutput{Symbols: make([]OutlineSymbol, 0)}
+	typeIndex := make(map[string]int)
+	var methods []*ast.FuncDecl
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				methods = append(methods, d)
+				continue
+			}
+			out.Symbols = append(out.Symbols, OutlineSymbol{
+				Name:           d.Name.Name,
+				Kind:           "func",
+				Range:          rangeOf(d),
+				SelectionRange: rangeForIdent(fset, d.Name),
+				TypeParams:     typeParamList(d.Type.TypeParams),
+			})
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				// An ungrouped declaration's range includes its keyword.
+				var node ast.Node = spec
+				if !d.Lparen.IsValid() {
+					node = d
+				}
+				switch s := spec.(type) {
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						out.Symbols = append(out.Symbols, OutlineSymbol{
+							Name:           name.Name,
+							Kind:           d.Tok.String(),
+							Range:          rangeOf(node),
+							SelectionRange: rangeForIdent(fset, name),
+						})
+					}
+				case *ast.TypeSpec:
+					sym := OutlineSymbol{
+						Name:           s.Name.Name,
+						Kind:           "type",
+						Range:          rangeOf(node),
+						SelectionRange: rangeForIdent(fset, s.Name),
+						TypeParams:     typeParamList(s.TypeParams),
+					}
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						sym.Kind = "struct"
+						sym.Children = fieldSymbols(fset, t.Fields, "field")
+					case *ast.InterfaceType:
+						sym.Kind = "interface"
+						sym.Children = fieldSymbols(fset, t.Methods, "method")
+					}
+					typeIndex[s.Name.Name] = len(out.Symbols)
+					out.Symbols = append(out.Symbols, sym)
+				}
+			}
+		}
+	}
+
+	for _, fd := range methods {
+		recv := fd.Recv.List[0].Type
+		sym := OutlineSymbol{
+			Name:           fd.Name.Name,
+			Kind:           "method",
+			Range:          rangeOf(fd),
+			SelectionRange: rangeForIdent(fset, fd.Name),
+			TypeParams:     receiverTypeParams(recv),
+		}
+		if i, ok := typeIndex[receiverTypeName(recv)]; ok {
+			out.Symbols[i].Children = append(out.Symbols[i].Children, sym)
+			continue
+		}
+		out.Symbols = append(out.Symbols, sym)
+	}
+	sortOutline(out.Symbols)
+	return out
+}
+
+// fieldSymbols lists struct fields or interface methods. Embedded fields
+// and interfaces are named after their type.
+func fieldSymbols(fset *token.FileSet, list *ast.FieldList, kind string) []OutlineSymbol {
+	if list == nil {
+		return nil
+	}
+	var symbols []OutlineSymbol
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			if name := receiverTypeName(field.Type); name != "" {
+				symbols = append(symbols, OutlineSymbol{
+					Name:           name,
+					Kind:           "field",
+					Range:          rangeForPos(fset, field.Pos(), field.End()),
+					SelectionRange: rangeForPos(fset, field.Type.Pos(), field.Type.End()),
+				})
+			}
+			continue
+		}
+		for _, name := range field.Names {
+			symbols = append(symbols, OutlineSymbol{
+				Name:           name.Name,
+				Kind:           kind,
+				Range:          rangeForPos(fset, field.Pos(), field.End()),
+				SelectionRange: rangeForIdent(fset, name),
+			})
+		}
+	}
+	return symbols
+}
+
+func typeParamList(list *ast.FieldList) []string {
+	if list == nil {
+		return nil
+	}
+	var params []string
+	for _, field := range list.List {
+		constraint := types.ExprString(field.Type)
+		for _, name := range field.Names {
+			params = append(params, name.Name+" "+constraint)
+		}
+	}
+	return params
+}
+
+// receiverTypeParams returns the type parameter names of a generic
+// receiver such as *Set[K].
+func receiverTypeParams(expr ast.Expr) []string {
+	var indices []ast.Expr
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeParams(e.X)
+	case *ast.ParenExpr:
+		return receiverTypeParams(e.X)
+	case *ast.IndexExpr:
+		indices = []ast.Expr{e.Index}
+	case *ast.IndexListExpr:
+		indices = e.Indices
+	}
+	var params []string
+	for _, index := range indices {
+		params = append(params, types.ExprString(index))
+	}
+	return params
+}
+
+// sortOutline orders every level by position. Methods are collected after
+// the other declarations, so they have to be moved into place.
+func sortOutline(symbols []OutlineSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return rangeLess(symbols[i].Range, symbols[j].Range)
+	})
+	for i := range symbols {
+		sortOutline(symbols[i].Children)
+	}
+}
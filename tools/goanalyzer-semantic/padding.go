@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/types"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// paddingAnalyzer flags struct types declared in the file whose fields could
+// be reordered to make the struct smaller on the host architecture, giving
+// the current size, the smallest size and the order that reaches it. Generic
+// structs have no fixed layout and are not reported; "struct_layout" mode
+// lays out their instantiations.
+var paddingAnalyzer = &analyzer{
+	name:     "padding",
+	code:     "GA312",
+	summary:  "Struct fields can be reordered to reduce padding",
+	severity: "info",
+	run:      runPadding,
+}
+
+func runPadding(p *pass) []Finding {
+	sizes := types.SizesFor("gc", build.Default.GOARCH)
+	if sizes == nil {
+		return nil
+	}
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok || spec.TypeParams != nil {
+			return true
+		}
+		if _, ok := spec.Type.(*ast.StructType); !ok {
+			return true
+		}
+		tn, ok := p.info.Defs[spec.Name].(*types.TypeName)
+		if !ok {
+			return true
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok || passes.ContainsTypeParam(st, make(map[types.Type]bool)) {
+			return true
+		}
+		fields := make([]*types.Var, st.NumFields())
+		for i := range fields {
+			fields[i] = st.Field(i)
+		}
+		size := sizes.Sizeof(st)
+		order, optimal := optimalFieldOrder(sizes, fields)
+		if optimal >= size {
+			return true
+		}
+		names := make([]string, len(order))
+		for i, f := range order {
+			names[i] = f.Name()
+		}
+		findings = append(findings, Finding{
+			Message: fmt.Sprintf("struct %s is %d bytes; ordering its fields as %s makes it %d bytes, saving %d",
+				spec.Name.Name, size, strings.Join(names, ", "), optimal, size-optimal),
+			Range: p.rangeForNode(spec),
+		})
+		return true
+	})
+	return findings
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// paramRetainAnalyzer flags slice and map parameters, or slices of them,
+// that are stored in a field or package-level variable. The caller's
+// buffer then lives as long as the receiver or the program, usually
+// without the caller knowing. Pointer parameters are not reported, since
+// storing them is the normal way to take ownership of a value.
+var paramRetainAnalyzer = &analyzer{
+	name:     "paramretain",
+	code:     "GA208",
+	summary:  "Slice or map parameter retained in a field or package-level variable",
+	severity: "info",
+	report:   "retention",
+	run:      runParamRetain,
+}
+
+func runParamRetain(p *pass) []Finding {
+	receivers := make(map[types.Object]bool)
+	for id := range receiverIdents(p.file) {
+		receivers[p.info.Defs[id]] = true
+	}
+	var findings []Finding
+	p.LongLivedAssigns(func(lhs, rhs ast.Expr) {
+		expr := passes.Unparen(rhs)
+		if se, ok := expr.(*ast.SliceExpr); ok {
+			expr = passes.Unparen(se.X)
+		}
+		id, ok := expr.(*ast.Ident)
+		if !ok {
+			return
+		}
+		v, ok := p.info.Uses[id].(*types.Var)
+		if !ok || receivers[v] || paramKind(v, p.info) != "param" {
+			return
+		}
+		switch v.Type().Underlying().(type) {
+		case *types.Slice, *types.Map:
+		default:
+			return
+		}
+		finding := Finding{
+			Message: "parameter " + v.Name() + " is retained by " + types.ExprString(lhs) + " after the call returns",
+			Range:   p.rangeForNode(lhs),
+		}
+		if decl, ok := p.objectRange(v); ok {
+			finding.Related = []RelatedRange{{Range: decl, Message: "parameter declared here"}}
+		}
+		findings = append(findings, finding)
+	})
+	return findings
+}
@@ -0,0 +1,102 @@
+package passes
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// CaptureUnlock flags `go func() {...}()` literals started after the
+// enclosing function released a mutex, whose body then accesses fields of
+// the same value without locking again. The goroutine typically outlives
+// the critical section the author had in mind, so the fields are read or
+// written unprotected.
+var CaptureUnlock = newCheck(&Check{
+	Name:     "captureunlock",
+	Code:     "GA103",
+	Summary:  "Goroutine started after an unlock accesses the guarded fields",
+	Severity: "warning",
+	Report:   "race",
+	Run:      runCaptureUnlock,
+})
+
+func runCaptureUnlock(p *Pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := Unparen(gs.Call.Fun).(*ast.FuncLit)
+		if !ok || lit.Body == nil {
+			return true
+		}
+		unlocks := p.unlocksBefore(gs)
+		if len(unlocks) == 0 {
+			return true
+		}
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			expr, ok := n.(ast.Expr)
+			if !ok {
+				return true
+			}
+			sel, field := p.StructField(expr)
+			if field == nil || p.IsLockOperand(sel) {
+				return true
+			}
+			root := RootIdent(sel.X)
+			if root == nil {
+				return true
+			}
+			unlock := unlocks[p.Info.Uses[root]]
+			if unlock == nil || len(HeldLocks(sel, p.Parents, p.Info)) > 0 {
+				return true
+			}
+			findings = append(findings, at(sel,
+				"goroutine accesses field "+field.Name()+" after the lock guarding "+root.Name+" was released",
+				relatedAt(unlock, "lock released here before the goroutine starts")))
+			return true
+		})
+		return true
+	})
+	return findings
+}
+
+// unlocksBefore returns the last Unlock or RUnlock call before gs in its
+// enclosing function, keyed by the variable the mutex is reached through,
+// e.g. s for s.mu.Unlock().
+func (p *Pass) unlocksBefore(gs *ast.GoStmt) map[types.Object]*ast.CallExpr {
+	var body *ast.BlockStmt
+	for cur := p.Parents[gs]; cur != nil && body == nil; cur = p.Parents[cur] {
+		switch fn := cur.(type) {
+		case *ast.FuncDecl:
+			body = fn.Body
+		case *ast.FuncLit:
+			body = fn.Body
+		}
+	}
+	unlocks := make(map[types.Object]*ast.CallExpr)
+	if body == nil {
+		return unlocks
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit, *ast.DeferStmt:
+			return false
+		case *ast.CallExpr:
+			if n.End() > gs.Pos() {
+				return true
+			}
+			obj, method := LockCall(n, p.Info)
+			if obj == nil || (method != "Unlock" && method != "RUnlock") {
+				return true
+			}
+			if root := RootIdent(Unparen(n.Fun).(*ast.SelectorExpr).X); root != nil {
+				if v := p.Info.Uses[root]; v != nil {
+					unlocks[v] = n
+				}
+			}
+		}
+		return true
+	})
+	return unlocks
+}
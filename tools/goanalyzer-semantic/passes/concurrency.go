@@ -0,0 +1,336 @@
+package passes
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// LaunchedFuncs returns the functions and methods started directly by a
+// `go f(...)` or `go x.m(...)` statement in file.
+func LaunchedFuncs(file *ast.File, info *types.Info) map[*types.Func]bool {
+	launched := make(map[*types.Func]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok || gs.Call == nil {
+			return true
+		}
+		if fn := CalledFunc(gs.Call, info); fn != nil {
+			launched[fn] = true
+		}
+		return true
+	})
+	return launched
+}
+
+// CalledFunc returns the statically known function or method invoked by call.
+func CalledFunc(call *ast.CallExpr, info *types.Info) *types.Func {
+	switch fun := Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[fun].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		if sel := info.Selections[fun]; sel != nil {
+			fn, _ := sel.Obj().(*types.Func)
+			return fn
+		}
+		fn, _ := info.Uses[fun.Sel].(*types.Func)
+		return fn
+	}
+	return nil
+}
+
+// GoroutineContext returns the node that starts the goroutine node runs in:
+// the GoStmt of a `go func() {...}()` literal, or the FuncDecl of a function
+// that is launched with `go` somewhere in the file. It returns nil for code
+// that runs on the caller's goroutine.
+func GoroutineContext(node ast.Node, parents map[ast.Node]ast.Node, info *types.Info, launched map[*types.Func]bool) ast.Node {
+	for cur := node; cur != nil; cur = parents[cur] {
+		switch fn := cur.(type) {
+		case *ast.FuncLit:
+			call, ok := parents[fn].(*ast.CallExpr)
+			if !ok || call.Fun != fn {
+				continue
+			}
+			if gs, ok := parents[call].(*ast.GoStmt); ok {
+				return gs
+			}
+		case *ast.FuncDecl:
+			if obj, ok := info.Defs[fn.Name].(*types.Func); ok && launched[obj] {
+				return fn
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// HeldLocks returns the mutexes that are locked on every straight-line path
+// from the start of the enclosing function to node. A Lock/RLock call counts
+// until a later non-deferred Unlock/RUnlock on the same receiver; deferred
+// unlocks keep the lock held for the rest of the function.
+func HeldLocks(node ast.Node, parents map[ast.Node]ast.Node, info *types.Info) map[types.Object]bool {
+	held := make(map[types.Object]bool)
+	for obj := range HeldLockCalls(node, parents, info) {
+		held[obj] = true
+	}
+	return held
+}
+
+// HeldLockCalls is HeldLocks with the Lock/RLock call that acquired each
+// mutex.
+func HeldLockCalls(node ast.Node, parents map[ast.Node]ast.Node, info *types.Info) map[types.Object]*ast.CallExpr {
+	var blocks []*ast.BlockStmt
+	var stmts []ast.Node
+	child := node
+	for cur := parents[node]; cur != nil; cur = parents[cur] {
+		if block, ok := cur.(*ast.BlockStmt); ok {
+			blocks = append(blocks, block)
+			stmts = append(stmts, child)
+		}
+		if _, ok := cur.(*ast.FuncLit); ok {
+			break
+		}
+		if _, ok := cur.(*ast.FuncDecl); ok {
+			break
+		}
+		child = cur
+	}
+	held := make(map[types.Object]*ast.CallExpr)
+	for i := len(blocks) - 1; i >= 0; i-- {
+		for _, stmt := range blocks[i].List {
+			if stmt == stmts[i] {
+				break
+			}
+			es, ok := stmt.(*ast.ExprStmt)
+			if !ok {
+				continue
+			}
+			call, ok := es.X.(*ast.CallExpr)
+			if !ok {
+				continue
+			}
+			obj, method := LockCall(call, info)
+			if obj == nil {
+				continue
+			}
+			switch method {
+			case "Lock", "RLock":
+				held[obj] = call
+			case "Unlock", "RUnlock":
+				delete(held, obj)
+			}
+		}
+	}
+	return held
+}
+
+// LockCall reports the mutex object and method name of a `mu.Lock()`-style
+// call. The object is the field or variable holding the mutex so that
+// accesses through different receivers of the same type compare equal.
+func LockCall(call *ast.CallExpr, info *types.Info) (types.Object, string) {
+	sel, ok := Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return nil, ""
+	}
+	switch sel.Sel.Name {
+	case "Lock", "RLock", "Unlock", "RUnlock":
+	default:
+		return nil, ""
+	}
+	return ExprObject(sel.X, info), sel.Sel.Name
+}
+
+// ExprObject returns the variable or field an identifier or selector
+// expression denotes.
+func ExprObject(expr ast.Expr, info *types.Info) types.Object {
+	switch e := Unparen(expr).(type) {
+	case *ast.Ident:
+		return info.Uses[e]
+	case *ast.SelectorExpr:
+		if sel := info.Selections[e]; sel != nil {
+			return sel.Obj()
+		}
+		return info.Uses[e.Sel]
+	}
+	return nil
+}
+
+// SharesLock reports whether the lock sets a and b have a mutex in common.
+func SharesLock(a, b map[types.Object]bool) bool {
+	for obj := range a {
+		if b[obj] {
+			return true
+		}
+	}
+	return false
+}
+
+// Unparen strips the parentheses around expr.
+func Unparen(expr ast.Expr) ast.Expr {
+	for {
+		p, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = p.X
+	}
+}
+
+// FieldAccess is a read or write of a struct field, with the goroutine it
+// runs on and the locks held at that point.
+type FieldAccess struct {
+	Sel   *ast.SelectorExpr
+	Field *types.Var
+	Write bool
+	// Atomic is set for fields passed by address to a sync/atomic function.
+	Atomic bool
+	// Ctx is the goroutine the access runs on, see GoroutineContext.
+	Ctx   ast.Node
+	Locks map[types.Object]bool
+}
+
+// FieldAccesses collects every struct field access in the file, grouped by
+// field. Taking a field's address for anything but sync/atomic counts as a
+// write.
+func (p *Pass) FieldAccesses() map[*types.Var][]*FieldAccess {
+	launched := LaunchedFuncs(p.File, p.Info)
+	atomicArgs := make(map[*ast.SelectorExpr]bool)
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		if op, _ := AtomicOp(call, p.Info); op == "" {
+			return true
+		}
+		if addr, ok := Unparen(call.Args[0]).(*ast.UnaryExpr); ok && addr.Op == token.AND {
+			if sel, field := p.StructField(addr.X); field != nil {
+				atomicArgs[sel] = true
+			}
+		}
+		return true
+	})
+
+	accesses := make(map[*types.Var][]*FieldAccess)
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+		sel, field := p.StructField(expr)
+		if field == nil || p.IsLockOperand(sel) {
+			return true
+		}
+		a := &FieldAccess{
+			Sel:    sel,
+			Field:  field,
+			Atomic: atomicArgs[sel],
+			Ctx:    GoroutineContext(sel, p.Parents, p.Info, launched),
+			Locks:  HeldLocks(sel, p.Parents, p.Info),
+		}
+		switch parent := p.Parents[sel].(type) {
+		case *ast.AssignStmt:
+			a.Write = p.IsAssignTarget(sel)
+		case *ast.IncDecStmt:
+			a.Write = true
+		case *ast.UnaryExpr:
+			a.Write = parent.Op == token.AND && !a.Atomic
+		}
+		accesses[field] = append(accesses[field], a)
+		return true
+	})
+	return accesses
+}
+
+// IsLockOperand reports whether sel is the mutex of a Lock/Unlock call.
+func (p *Pass) IsLockOperand(sel *ast.SelectorExpr) bool {
+	outer, ok := p.Parents[sel].(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	call, ok := p.Parents[outer].(*ast.CallExpr)
+	if !ok || call.Fun != outer {
+		return false
+	}
+	obj, _ := LockCall(call, p.Info)
+	return obj != nil
+}
+
+// RootIdent returns the variable an expression such as a.b[i].c is rooted
+// at, or nil when it is not rooted at an identifier.
+func RootIdent(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// CapturedRoot returns the local variable a reaches its field through, and
+// whether a is inside a `go func() {...}()` literal that captures that
+// variable from outside.
+func (p *Pass) CapturedRoot(a *FieldAccess) (*types.Var, bool) {
+	gs, ok := a.Ctx.(*ast.GoStmt)
+	if !ok {
+		return nil, false
+	}
+	lit, ok := Unparen(gs.Call.Fun).(*ast.FuncLit)
+	root := RootIdent(a.Sel.X)
+	if !ok || root == nil {
+		return nil, false
+	}
+	v, ok := p.Info.Uses[root].(*types.Var)
+	if !ok || v.Parent() == p.Pkg.Scope() {
+		return nil, false
+	}
+	return v, v.Pos() < lit.Pos() || v.Pos() >= lit.End()
+}
+
+// HasAtomicAccess reports whether one of accesses goes through sync/atomic.
+func HasAtomicAccess(accesses []*FieldAccess) bool {
+	for _, a := range accesses {
+		if a.Atomic {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessKind describes a as "write" or "read".
+func AccessKind(a *FieldAccess) string {
+	if a.Write {
+		return "write"
+	}
+	return "read"
+}
+
+// AtomicOp splits a sync/atomic function call such as atomic.AddInt64 into
+// its operation ("Add") and type suffix ("Int64").
+func AtomicOp(call *ast.CallExpr, info *types.Info) (string, string) {
+	fn := CalledFunc(call, info)
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "sync/atomic" {
+		return "", ""
+	}
+	if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+		return "", ""
+	}
+	for _, op := range []string{"CompareAndSwap", "Add", "And", "Or", "Load", "Store", "Swap"} {
+		if strings.HasPrefix(fn.Name(), op) {
+			return op, strings.TrimPrefix(fn.Name(), op)
+		}
+	}
+	return "", ""
+}
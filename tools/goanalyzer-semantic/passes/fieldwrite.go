@@ -0,0 +1,41 @@
+package passes
+
+// FieldWrite flags plain writes of struct fields on a goroutine, either in
+// a function launched with `go` or in a `go func() {...}()` literal,
+// without any lock held. Writes through a variable captured by the literal
+// are left to gomutate, and fields that are also accessed through
+// sync/atomic to MixedAtomic.
+var FieldWrite = newCheck(&Check{
+	Name:     "fieldwrite",
+	Code:     "GA101",
+	Summary:  "Struct field written on a goroutine without a lock",
+	Severity: "warning",
+	Report:   "race",
+	Run:      runFieldWrite,
+})
+
+func runFieldWrite(p *Pass) []Finding {
+	var findings []Finding
+	for field, accesses := range p.FieldAccesses() {
+		if HasAtomicAccess(accesses) {
+			continue
+		}
+		for _, w := range accesses {
+			if !w.Write || w.Ctx == nil || len(w.Locks) > 0 {
+				continue
+			}
+			if _, captured := p.CapturedRoot(w); captured {
+				continue
+			}
+			var related []Related
+			for _, a := range accesses {
+				if a.Ctx == w.Ctx || SharesLock(a.Locks, w.Locks) {
+					continue
+				}
+				related = append(related, relatedAt(a.Sel, AccessKind(a)+" of "+field.Name()+" on another goroutine"))
+			}
+			findings = append(findings, at(w.Sel, "field "+field.Name()+" is written on a goroutine without holding a lock", related...))
+		}
+	}
+	return findings
+}
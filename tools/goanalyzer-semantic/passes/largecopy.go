@@ -0,0 +1,82 @@
+package passes
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+)
+
+// LargeCopy flags copies of existing values of at least
+// DefaultLargeValueSize bytes, or the configured size: assignments, call
+// arguments and range values. Values built in place, such as composite
+// literals and call results, are not copies of anything and are not
+// reported.
+var LargeCopy = withSizeFlag(newCheck(&Check{
+	Name:     "largecopy",
+	Code:     "GA204",
+	Summary:  "Copy of a large value",
+	Severity: "info",
+	Report:   "retention",
+	Run:      runLargeCopy,
+}))
+
+func runLargeCopy(p *Pass) []Finding {
+	var findings []Finding
+	report := func(site ast.Node, typ types.Type, how string) {
+		size := SizeOf(typ)
+		findings = append(findings, at(site, how+" copies a "+types.TypeString(typ, types.RelativeTo(p.Pkg))+" ("+strconv.FormatInt(*size, 10)+" bytes); use a pointer instead"))
+	}
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.AssignStmt:
+			if len(node.Lhs) != len(node.Rhs) {
+				return true
+			}
+			for i, lhs := range node.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Name == "_" {
+					continue
+				}
+				if rhs := node.Rhs[i]; p.isCopySource(rhs) && p.IsLarge(p.Info.TypeOf(rhs)) {
+					report(lhs, p.Info.TypeOf(rhs), "assignment")
+				}
+			}
+		case *ast.CallExpr:
+			if tv, ok := p.Info.Types[node.Fun]; ok && tv.IsType() {
+				return true
+			}
+			if id, ok := Unparen(node.Fun).(*ast.Ident); ok {
+				if b, ok := p.Info.Uses[id].(*types.Builtin); ok && b.Name() != "append" {
+					return true
+				}
+			}
+			for _, arg := range node.Args {
+				if p.isCopySource(arg) && p.IsLarge(p.Info.TypeOf(arg)) {
+					report(arg, p.Info.TypeOf(arg), "argument")
+				}
+			}
+		case *ast.RangeStmt:
+			id, ok := node.Value.(*ast.Ident)
+			if ok && id.Name != "_" && p.IsLarge(p.Info.TypeOf(id)) {
+				report(id, p.Info.TypeOf(id), "range value")
+			}
+		}
+		return true
+	})
+	return findings
+}
+
+// isCopySource reports whether expr denotes an existing variable, field,
+// element or pointee, so that using its value copies it.
+func (p *Pass) isCopySource(expr ast.Expr) bool {
+	switch e := Unparen(expr).(type) {
+	case *ast.Ident:
+		_, ok := p.Info.Uses[e].(*types.Var)
+		return ok
+	case *ast.SelectorExpr:
+		_, field := p.StructField(e)
+		return field != nil
+	case *ast.StarExpr, *ast.IndexExpr:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package passes
+
+import (
+	"bufio"
+	"go/ast"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoopCapture flags `go func() {...}()` literals inside a loop that refer
+// to a variable declared by the loop itself. Before Go 1.22 all iterations
+// share one variable, so the goroutines observe whatever value the loop has
+// reached when they run. Modules declaring go 1.22 or later get a fresh
+// variable per iteration and are not checked.
+var LoopCapture = newCheck(&Check{
+	Name:     "loopcapture",
+	Code:     "GA104",
+	Summary:  "Goroutine captures a loop variable shared by all iterations",
+	Severity: "warning",
+	Report:   "race",
+	Run:      runLoopCapture,
+})
+
+func runLoopCapture(p *Pass) []Finding {
+	if perIterationLoopVars(p.Fset.Position(p.File.Pos()).Filename) {
+		return nil
+	}
+	var findings []Finding
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := Unparen(gs.Call.Fun).(*ast.FuncLit)
+		if !ok || lit.Body == nil {
+			return true
+		}
+		loopVars := p.enclosingLoopVars(gs)
+		if len(loopVars) == 0 {
+			return true
+		}
+		reported := make(map[types.Object]bool)
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := p.Info.Uses[id]
+			decl := loopVars[obj]
+			if decl == nil || reported[obj] {
+				return true
+			}
+			reported[obj] = true
+			findings = append(findings, at(id,
+				"goroutine captures loop variable "+id.Name+", which is shared by all iterations before Go 1.22",
+				relatedAt(decl, "loop variable declared here")))
+			return true
+		})
+		return true
+	})
+	return findings
+}
+
+// enclosingLoopVars returns the variables declared by the for and range
+// statements whose bodies contain node, within its function.
+func (p *Pass) enclosingLoopVars(node ast.Node) map[types.Object]*ast.Ident {
+	vars := make(map[types.Object]*ast.Ident)
+	add := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if id, ok := expr.(*ast.Ident); ok {
+				if obj := p.Info.Defs[id]; obj != nil {
+					vars[obj] = id
+				}
+			}
+		}
+	}
+	child := node
+	for cur := p.Parents[node]; cur != nil; cur = p.Parents[cur] {
+		switch loop := cur.(type) {
+		case *ast.ForStmt:
+			if as, ok := loop.Init.(*ast.AssignStmt); ok && loop.Body == child {
+				add(as.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if loop.Body == child {
+				add(loop.Key, loop.Value)
+			}
+		case *ast.FuncDecl, *ast.FuncLit:
+			return vars
+		}
+		child = cur
+	}
+	return vars
+}
+
+// perIterationLoopVars reports whether the module containing file declares
+// go 1.22 or later in its go.mod, so loop variables are per iteration.
+func perIterationLoopVars(file string) bool {
+	root := moduleRoot(filepath.Dir(file))
+	if root == "" {
+		return false
+	}
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != "go" {
+			continue
+		}
+		parts := strings.SplitN(fields[1], ".", 3)
+		if len(parts) < 2 {
+			return false
+		}
+		major, err1 := strconv.Atoi(parts[0])
+		minor, err2 := strconv.Atoi(parts[1])
+		return err1 == nil && err2 == nil && (major > 1 || minor >= 22)
+	}
+	return false
+}
+
+// moduleRoot returns dir or the closest directory above it holding a
+// go.mod, or "" when there is none.
+func moduleRoot(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		if fi, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil && !fi.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,40 @@
+package passes
+
+// MixedAtomic flags plain reads and writes of struct fields that are also
+// accessed through sync/atomic and written plainly somewhere. Atomic
+// operations only synchronize with other atomic operations, so every plain
+// access races with them. Fields whose writes are all atomic are left to
+// atomicload.
+var MixedAtomic = newCheck(&Check{
+	Name:     "mixedatomic",
+	Code:     "GA102",
+	Summary:  "Field accessed both atomically and plainly",
+	Severity: "error",
+	Report:   "race",
+	Run:      runMixedAtomic,
+})
+
+func runMixedAtomic(p *Pass) []Finding {
+	var findings []Finding
+	for field, accesses := range p.FieldAccesses() {
+		var related []Related
+		plainWrite := false
+		for _, a := range accesses {
+			if a.Atomic {
+				related = append(related, relatedAt(a.Sel, "atomic access of "+field.Name()))
+			} else if a.Write {
+				plainWrite = true
+			}
+		}
+		if len(related) == 0 || !plainWrite {
+			continue
+		}
+		for _, a := range accesses {
+			if a.Atomic {
+				continue
+			}
+			findings = append(findings, at(a.Sel, "plain "+AccessKind(a)+" of field "+field.Name()+", which is also accessed atomically", related...))
+		}
+	}
+	return findings
+}
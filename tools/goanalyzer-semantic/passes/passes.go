@@ -0,0 +1,168 @@
+// Package passes holds the detection passes behind the race and retention
+// reports: unsynchronized field writes, mixed atomic access, goroutines
+// started after an unlock, loop variable capture, retained sub-slices and
+// substrings, and large copies. The JSON helper runs each Check on one file
+// at a time; the Analyzer of a Check runs the same code under go/analysis,
+// e.g. from cmd/goanalyzer-vet, and reports the same finding codes.
+package passes
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Pass is what a check sees: one file of a type-checked package.
+type Pass struct {
+	Fset    *token.FileSet
+	File    *ast.File
+	Pkg     *types.Package
+	Info    *types.Info
+	Parents map[ast.Node]ast.Node
+	// LargeValueSize overrides DefaultLargeValueSize when positive.
+	LargeValueSize int64
+}
+
+// Finding is a diagnostic of a check, positioned in the pass's file set.
+type Finding struct {
+	Pos, End token.Pos
+	Message  string
+	Related  []Related
+}
+
+// Related points at a secondary site that explains a finding, such as the
+// other side of a racing pair of accesses.
+type Related struct {
+	Pos, End token.Pos
+	Message  string
+}
+
+// Check is a detection pass together with how the JSON protocol presents
+// it: Code identifies it stably, Severity is its default severity and
+// Report names the report mode that includes it, e.g. "race" for
+// "race_report". Analyzer runs Run over every file of a package and reports
+// each finding with Code as its category and at the end of its message.
+type Check struct {
+	Name     string
+	Code     string
+	Summary  string
+	Severity string
+	Report   string
+	Run      func(p *Pass) []Finding
+	Analyzer *analysis.Analyzer
+}
+
+// Checks lists every check in the order the JSON helper runs them.
+var Checks = []*Check{FieldWrite, MixedAtomic, CaptureUnlock, LoopCapture, SubSlice, SubString, LargeCopy}
+
+// Analyzers are the Analyzer values of Checks, for analysis drivers.
+var Analyzers = func() []*analysis.Analyzer {
+	analyzers := make([]*analysis.Analyzer, len(Checks))
+	for i, c := range Checks {
+		analyzers[i] = c.Analyzer
+	}
+	return analyzers
+}()
+
+// largeValueSize is the -size flag of the analyzers that report large
+// values.
+var largeValueSize int64 = DefaultLargeValueSize
+
+func newCheck(c *Check) *Check {
+	c.Analyzer = &analysis.Analyzer{
+		Name: c.Name,
+		Doc:  c.Summary + " (" + c.Code + ")",
+		Run:  c.runAnalysis,
+	}
+	return c
+}
+
+// withSizeFlag adds the -size flag to the analyzer of c.
+func withSizeFlag(c *Check) *Check {
+	c.Analyzer.Flags.Int64Var(&largeValueSize, "size", DefaultLargeValueSize, "report values of at least this many bytes")
+	return c
+}
+
+func (c *Check) runAnalysis(pass *analysis.Pass) (interface{}, error) {
+	for _, file := range pass.Files {
+		p := &Pass{
+			Fset:           pass.Fset,
+			File:           file,
+			Pkg:            pass.Pkg,
+			Info:           pass.TypesInfo,
+			Parents:        ParentMap(file),
+			LargeValueSize: largeValueSize,
+		}
+		for _, f := range c.Run(p) {
+			d := analysis.Diagnostic{Pos: f.Pos, End: f.End, Category: c.Code, Message: f.Message + " (" + c.Code + ")"}
+			for _, r := range f.Related {
+				d.Related = append(d.Related, analysis.RelatedInformation{Pos: r.Pos, End: r.End, Message: r.Message})
+			}
+			pass.Report(d)
+		}
+	}
+	return nil, nil
+}
+
+// at returns a finding covering n.
+func at(n ast.Node, message string, related ...Related) Finding {
+	return Finding{Pos: n.Pos(), End: n.End(), Message: message, Related: related}
+}
+
+// relatedAt returns a related site covering n.
+func relatedAt(n ast.Node, message string) Related {
+	return Related{Pos: n.Pos(), End: n.End(), Message: message}
+}
+
+// ParentMap maps every node below root to its parent.
+func ParentMap(root ast.Node) map[ast.Node]ast.Node {
+	parents := make(map[ast.Node]ast.Node)
+	var stack []ast.Node
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			return false
+		}
+		if len(stack) > 0 {
+			parents[n] = stack[len(stack)-1]
+		}
+		stack = append(stack, n)
+		return true
+	})
+	return parents
+}
+
+// StructField returns expr as a selector of a struct field.
+func (p *Pass) StructField(expr ast.Expr) (*ast.SelectorExpr, *types.Var) {
+	sel, ok := Unparen(expr).(*ast.SelectorExpr)
+	if !ok {
+		return nil, nil
+	}
+	selection := p.Info.Selections[sel]
+	if selection == nil || selection.Kind() != types.FieldVal {
+		return nil, nil
+	}
+	field, ok := selection.Obj().(*types.Var)
+	if !ok {
+		return nil, nil
+	}
+	return sel, field
+}
+
+// IsAssignTarget reports whether expr is on the left of an assignment.
+func (p *Pass) IsAssignTarget(expr ast.Expr) bool {
+	as, ok := p.Parents[expr].(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	for _, lhs := range as.Lhs {
+		if lhs == expr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package passes_test
+
+import (
+	"testing"
+
+	"goanalyzer-semantic/passes"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// TestAnalyzers runs the analyzer of every check over the testdata package
+// named after it; the want comments spell out the finding codes.
+func TestAnalyzers(t *testing.T) {
+	for _, c := range passes.Checks {
+		t.Run(c.Name, func(t *testing.T) {
+			analysistest.Run(t, analysistest.TestData(), c.Analyzer, c.Name)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package passes
+
+import (
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+)
+
+// DefaultLargeValueSize is the size in bytes from which copying a value or
+// keeping it alive is reported by the retention checks.
+const DefaultLargeValueSize = 1024
+
+// LongLivedAssigns calls f for every assignment in the file whose target
+// outlives the function: a struct field, a package-level variable, or an
+// element of a map held in one of those.
+func (p *Pass) LongLivedAssigns(f func(lhs, rhs ast.Expr)) {
+	ast.Inspect(p.File, func(n ast.Node) bool {
+		as, ok := n.(*ast.AssignStmt)
+		if !ok || as.Tok != token.ASSIGN || len(as.Lhs) != len(as.Rhs) {
+			return true
+		}
+		for i, lhs := range as.Lhs {
+			if p.isLongLived(lhs) {
+				f(lhs, as.Rhs[i])
+			}
+		}
+		return true
+	})
+}
+
+func (p *Pass) isLongLived(expr ast.Expr) bool {
+	expr = Unparen(expr)
+	if ix, ok := expr.(*ast.IndexExpr); ok {
+		if _, isMap := p.Info.TypeOf(ix.X).Underlying().(*types.Map); isMap {
+			expr = Unparen(ix.X)
+		}
+	}
+	if _, field := p.StructField(expr); field != nil {
+		return true
+	}
+	id, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	v, ok := p.Info.Uses[id].(*types.Var)
+	return ok && v.Parent() == p.Pkg.Scope()
+}
+
+// IsLarge reports whether values of typ are at least DefaultLargeValueSize
+// bytes, or LargeValueSize.
+func (p *Pass) IsLarge(typ types.Type) bool {
+	if typ == nil {
+		return false
+	}
+	limit := int64(DefaultLargeValueSize)
+	if p.LargeValueSize > 0 {
+		limit = p.LargeValueSize
+	}
+	size := SizeOf(typ)
+	return size != nil && *size >= limit
+}
+
+// SizeOf returns the gc size of typ for the host architecture, or nil when
+// the type has no fixed size.
+func SizeOf(typ types.Type) *int64 {
+	if b, ok := typ.(*types.Basic); ok && b.Info()&types.IsUntyped != 0 {
+		return nil
+	}
+	if ContainsTypeParam(typ, make(map[types.Type]bool)) {
+		return nil
+	}
+	sizes := types.SizesFor("gc", build.Default.GOARCH)
+	if sizes == nil {
+		return nil
+	}
+	size := sizes.Sizeof(typ)
+	return &size
+}
+
+// ContainsTypeParam reports whether the size of typ depends on a type
+// parameter. seen guards against recursive types.
+func ContainsTypeParam(typ types.Type, seen map[types.Type]bool) bool {
+	if seen[typ] {
+		return false
+	}
+	seen[typ] = true
+	switch t := typ.(type) {
+	case *types.TypeParam:
+		return true
+	case *types.Named:
+		if t.TypeParams().Len() > 0 && t.TypeArgs().Len() == 0 {
+			return true
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if ContainsTypeParam(t.TypeArgs().At(i), seen) {
+				return true
+			}
+		}
+		return ContainsTypeParam(t.Underlying(), seen)
+	case *types.Array:
+		return ContainsTypeParam(t.Elem(), seen)
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if ContainsTypeParam(t.Field(i).Type(), seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
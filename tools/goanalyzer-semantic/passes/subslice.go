@@ -0,0 +1,62 @@
+package passes
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// SubSlice flags sub-slices stored in a field or package-level variable.
+// The stored slice keeps the whole backing array of its source alive,
+// however small the slice itself is.
+var SubSlice = newCheck(&Check{
+	Name:     "subslice",
+	Code:     "GA201",
+	Summary:  "Stored sub-slice retains its whole backing array",
+	Severity: "warning",
+	Report:   "retention",
+	Run:      func(p *Pass) []Finding { return p.storedSlices(false) },
+})
+
+// SubString is SubSlice for substrings, which share the bytes of the
+// string they were sliced from.
+var SubString = newCheck(&Check{
+	Name:     "substring",
+	Code:     "GA202",
+	Summary:  "Stored substring retains the whole source string",
+	Severity: "warning",
+	Report:   "retention",
+	Run:      func(p *Pass) []Finding { return p.storedSlices(true) },
+})
+
+// storedSlices reports slice expressions of slices, or of strings when
+// strings is set, that are assigned to long-lived targets.
+func (p *Pass) storedSlices(strings bool) []Finding {
+	what := "sub-slice"
+	if strings {
+		what = "substring"
+	}
+	var findings []Finding
+	p.LongLivedAssigns(func(lhs, rhs ast.Expr) {
+		se, ok := Unparen(rhs).(*ast.SliceExpr)
+		if !ok {
+			return
+		}
+		switch t := p.Info.TypeOf(se.X).Underlying().(type) {
+		case *types.Slice:
+			if strings {
+				return
+			}
+		case *types.Basic:
+			if !strings || t.Info()&types.IsString == 0 {
+				return
+			}
+		default:
+			return
+		}
+		src := types.ExprString(se.X)
+		findings = append(findings, at(lhs,
+			"storing a "+what+" of "+src+" keeps its whole backing memory alive; copy the part you need",
+			relatedAt(se.X, "backing memory of "+src)))
+	})
+	return findings
+}
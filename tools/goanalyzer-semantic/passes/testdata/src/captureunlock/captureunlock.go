@@ -0,0 +1,17 @@
+package captureunlock
+
+import "sync"
+
+type store struct {
+	mu   sync.Mutex
+	name string
+}
+
+func (s *store) publish() {
+	s.mu.Lock()
+	s.name = "ready"
+	s.mu.Unlock()
+	go func() {
+		println(s.name) // want `goroutine accesses field name after the lock guarding s was released \(GA103\)`
+	}()
+}
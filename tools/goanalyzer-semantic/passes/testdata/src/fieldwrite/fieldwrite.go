@@ -0,0 +1,23 @@
+package fieldwrite
+
+import "sync"
+
+type counter struct {
+	mu   sync.Mutex
+	hits int
+}
+
+func (c *counter) work() {
+	c.hits = 1 // want `field hits is written on a goroutine without holding a lock \(GA101\)`
+}
+
+func (c *counter) lockedWork() {
+	c.mu.Lock()
+	c.hits = 2
+	c.mu.Unlock()
+}
+
+func start(c *counter) {
+	go c.work()
+	go c.lockedWork()
+}
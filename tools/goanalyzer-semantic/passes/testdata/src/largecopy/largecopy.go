@@ -0,0 +1,13 @@
+package largecopy
+
+type frame struct {
+	data [2048]byte
+}
+
+func consume(f frame) {}
+
+func copies(p *frame) {
+	f := *p    // want `assignment copies a frame \(2048 bytes\); use a pointer instead \(GA204\)`
+	consume(f) // want `argument copies a frame \(2048 bytes\); use a pointer instead \(GA204\)`
+	consume(frame{})
+}
@@ -0,0 +1,12 @@
+package loopcapture
+
+func fanOut(items []string) {
+	for _, item := range items {
+		go func() {
+			println(item) // want `goroutine captures loop variable item, which is shared by all iterations before Go 1.22 \(GA104\)`
+		}()
+		go func(item string) {
+			println(item)
+		}(item)
+	}
+}
@@ -0,0 +1,15 @@
+package mixedatomic
+
+import "sync/atomic"
+
+type stats struct {
+	total int64
+}
+
+func (s *stats) add() {
+	atomic.AddInt64(&s.total, 1)
+}
+
+func (s *stats) reset() {
+	s.total = 0 // want `plain write of field total, which is also accessed atomically \(GA102\)`
+}
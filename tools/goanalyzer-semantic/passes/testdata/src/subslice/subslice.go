@@ -0,0 +1,10 @@
+package subslice
+
+type cache struct {
+	head []byte
+}
+
+func (c *cache) keep(buf []byte) {
+	c.head = buf[:16] // want `storing a sub-slice of buf keeps its whole backing memory alive; copy the part you need \(GA201\)`
+	c.head = append([]byte(nil), buf[:16]...)
+}
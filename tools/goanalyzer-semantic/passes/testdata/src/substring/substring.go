@@ -0,0 +1,7 @@
+package substring
+
+var lastKey string
+
+func remember(line string) {
+	lastKey = line[:8] // want `storing a substring of line keeps its whole backing memory alive; copy the part you need \(GA202\)`
+}
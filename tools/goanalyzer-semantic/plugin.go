@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultPluginTimeout = 5 * time.Second
+
+// PluginSpec declares an external executable run by the report modes after
+// type-checking. It receives a PluginSummary as JSON on stdin and answers
+// with a PluginOutput on stdout. Findings must be set for its findings to
+// be merged, with their codes prefixed by Name (the base name of Cmd by
+// default) and a slash.
+type PluginSpec struct {
+	Cmd       string   `json:"cmd"`
+	Args      []string `json:"args,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Findings  bool     `json:"findings"`
+	TimeoutMs int      `json:"timeout_ms,omitempty"`
+}
+
+// PluginSummary is what a plugin is told about the target's package: its
+// package-level symbols per file and how each struct field declared in it
+// is accessed.
+type PluginSummary struct {
+	ProtocolVersion int           `json:"protocol_version"`
+	Package         string        `json:"package"`
+	Target          string        `json:"target"`
+	Files           []PluginFile  `json:"files"`
+	Fields          []FieldAccess `json:"fields"`
+}
+
+type PluginFile struct {
+	Path    string         `json:"path"`
+	Symbols []PluginSymbol `json:"symbols"`
+}
+
+// PluginSymbol is a package-level declaration; methods are named "T.m".
+// Kind is a decl_kind as in DefinitionOutput.
+type PluginSymbol struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Type string `json:"type"`
+	Decl Range  `json:"decl"`
+}
+
+// FieldAccess lists the reads and writes of a struct field across the
+// package through selectors. Compound assignments and ++/-- count as both;
+// taking the field's address counts as neither and is listed apart.
+// Composite literal keys are not listed.
+type FieldAccess struct {
+	Struct    string  `json:"struct"`
+	Field     string  `json:"field"`
+	Type      string  `json:"type"`
+	Decl      Range   `json:"decl"`
+	Reads     []Range `json:"reads"`
+	Writes    []Range `json:"writes"`
+	Addressed []Range `json:"addressed,omitempty"`
+}
+
+// PluginOutput is the response a plugin writes to stdout.
+type PluginOutput struct {
+	Findings []Finding `json:"findings"`
+}
+
+// PluginError reports a plugin that failed, timed out or answered with
+// something other than a PluginOutput. Its findings are dropped and the
+// rest of the report is unaffected.
+type PluginError struct {
+	Plugin string `json:"plugin"`
+	Error  string `json:"error"`
+}
+
+// runPlugins runs the plugins of the request that produce findings and
+// adds their findings, or the reason they have none, to out. Unless
+// wholePackage is set, only findings in the target file are kept.
+func runPlugins(lp *loadedPackage, specs []PluginSpec, wholePackage bool, out *AnalyzeOutput) {
+	var summary []byte
+	for _, spec := range specs {
+		if !spec.Findings || spec.Cmd == "" {
+			continue
+		}
+		name := spec.Name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(spec.Cmd), ".exe")
+		}
+		if summary == nil {
+			data, err := json.Marshal(pluginSummary(lp))
+			if err != nil {
+				return
+			}
+			summary = data
+		}
+		findings, err := runPlugin(spec, summary)
+		if err != nil {
+			out.PluginErrors = append(out.PluginErrors, PluginError{Plugin: name, Error: err.Error()})
+			continue
+		}
+		target := lp.fset.Position(lp.file.Pos()).Filename
+		for _, f := range findings {
+			f.Analyzer = name + "/" + f.Analyzer
+			f.Code = name + "/" + f.Code
+			if _, ok := sarifLevels[f.Severity]; !ok {
+				f.Severity = "warning"
+			}
+			if f.Range.File == "" {
+				f.Range.File = target
+			}
+			if !wholePackage && !sameFile(f.Range.File, target) {
+				continue
+			}
+			out.Findings = append(out.Findings, f)
+		}
+	}
+}
+
+// runPlugin runs one plugin with its timeout. WaitDelay keeps a plugin
+// whose children hold stdout open from blocking past the deadline.
+func runPlugin(spec PluginSpec, summary []byte) ([]Finding, error) {
+	timeout := defaultPluginTimeout
+	if spec.TimeoutMs > 0 {
+		timeout = time.Duration(spec.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, spec.Cmd, spec.Args...)
+	cmd.Stdin = bytes.NewReader(summary)
+	cmd.WaitDelay = time.Second
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	var po PluginOutput
+	if err := json.Unmarshal(stdout.Bytes(), &po); err != nil {
+		return nil, err
+	}
+	return po.Findings, nil
+}
+
+func pluginSummary(lp *loadedPackage) *PluginSummary {
+	summary := &PluginSummary{
+		ProtocolVersion: protocolVersion,
+		Package:         lp.pkg.Path(),
+		Target:          lp.fset.Position(lp.file.Pos()).Filename,
+		Files:           make([]PluginFile, 0, len(lp.files)),
+		Fields:          make([]FieldAccess, 0),
+	}
+	qualifier := types.RelativeTo(lp.pkg)
+	files := make(map[string]*PluginFile)
+	for _, f := range lp.files {
+		path := lp.fset.Position(f.Pos()).Filename
+		summary.Files = append(summary.Files, PluginFile{Path: path, Symbols: make([]PluginSymbol, 0)})
+	}
+	for i := range summary.Files {
+		files[summary.Files[i].Path] = &summary.Files[i]
+	}
+	addSymbol := func(obj types.Object, name string) {
+		pf := files[lp.fset.Position(obj.Pos()).Filename]
+		if pf == nil {
+			return
+		}
+		pf.Symbols = append(pf.Symbols, PluginSymbol{
+			Name: name,
+			Kind: declKind(obj, lp.info),
+			Type: types.TypeString(obj.Type(), qualifier),
+			Decl: rangeForPos(lp.fset, obj.Pos(), obj.Pos()+token.Pos(len(obj.Name()))),
+		})
+	}
+
+	fields := make(map[*types.Var]*FieldAccess)
+	var order []*types.Var
+	scope := lp.pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		addSymbol(obj, name)
+		tn, ok := obj.(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			m := named.Method(i)
+			addSymbol(m, name+"."+m.Name())
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			v := st.Field(i)
+			fields[v] = &FieldAccess{
+				Struct: name,
+				Field:  v.Name(),
+				Type:   types.TypeString(v.Type(), qualifier),
+				Decl:   rangeForPos(lp.fset, v.Pos(), v.Pos()+token.Pos(len(v.Name()))),
+				Reads:  make([]Range, 0),
+				Writes: make([]Range, 0),
+			}
+			order = append(order, v)
+		}
+	}
+	for i := range summary.Files {
+		sort.Slice(summary.Files[i].Symbols, func(a, b int) bool {
+			return rangeLess(summary.Files[i].Symbols[a].Decl, summary.Files[i].Symbols[b].Decl)
+		})
+	}
+
+	parents := buildPackageParentMap(lp.files)
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			s := lp.info.Selections[sel]
+			if s == nil || s.Kind() != types.FieldVal {
+				return true
+			}
+			fa := fields[s.Obj().(*types.Var)]
+			if fa == nil {
+				return true
+			}
+			r := rangeForIdent(lp.fset, sel.Sel)
+			var expr ast.Node = sel
+			for {
+				p, ok := parents[expr].(*ast.ParenExpr)
+				if !ok {
+					break
+				}
+				expr = p
+			}
+			switch p := parents[expr].(type) {
+			case *ast.UnaryExpr:
+				if p.Op == token.AND {
+					fa.Addressed = append(fa.Addressed, r)
+					return true
+				}
+			case *ast.IncDecStmt:
+				fa.Reads = append(fa.Reads, r)
+				fa.Writes = append(fa.Writes, r)
+				return true
+			case *ast.AssignStmt:
+				if identIsAssignTargetInList(sel.Sel, p.Lhs) {
+					if p.Tok != token.ASSIGN && p.Tok != token.DEFINE {
+						fa.Reads = append(fa.Reads, r)
+					}
+					fa.Writes = append(fa.Writes, r)
+					return true
+				}
+			}
+			fa.Reads = append(fa.Reads, r)
+			return true
+		})
+	}
+	for _, v := range order {
+		summary.Fields = append(summary.Fields, *fields[v])
+	}
+	return summary
+}
@@ -0,0 +1,83 @@
+// Command writeonly is an example goanalyzer-semantic plugin. It reads the
+// package summary from stdin and reports unexported struct fields that are
+// written but never read or addressed, using the summary's field access
+// table. Run it by adding it to a report request:
+//
+//	{"mode": "analyze", "file": "...", "plugins": [{"cmd": "writeonly", "findings": true}]}
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The types mirror the parts of the protocol the plugin uses; see the
+// x-plugin-input and x-plugin-output definitions of --schema.
+type pos struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+type rng struct {
+	File  string `json:"file,omitempty"`
+	Start pos    `json:"start"`
+	End   pos    `json:"end"`
+}
+
+type fieldAccess struct {
+	Struct    string `json:"struct"`
+	Field     string `json:"field"`
+	Decl      rng    `json:"decl"`
+	Reads     []rng  `json:"reads"`
+	Writes    []rng  `json:"writes"`
+	Addressed []rng  `json:"addressed"`
+}
+
+type summary struct {
+	Fields []fieldAccess `json:"fields"`
+}
+
+type related struct {
+	Range   rng    `json:"range"`
+	Message string `json:"message"`
+}
+
+type finding struct {
+	Analyzer string    `json:"analyzer"`
+	Code     string    `json:"code"`
+	Severity string    `json:"severity"`
+	Message  string    `json:"message"`
+	Range    rng       `json:"range"`
+	Related  []related `json:"related,omitempty"`
+}
+
+func main() {
+	var in summary
+	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
+		os.Exit(1)
+	}
+	findings := make([]finding, 0)
+	for _, f := range in.Fields {
+		if r, _ := utf8.DecodeRuneInString(f.Field); unicode.IsUpper(r) || f.Field == "_" {
+			continue
+		}
+		if len(f.Writes) == 0 || len(f.Reads) > 0 || len(f.Addressed) > 0 {
+			continue
+		}
+		var rel []related
+		for _, w := range f.Writes {
+			rel = append(rel, related{Range: w, Message: "write of " + f.Field})
+		}
+		findings = append(findings, finding{
+			Analyzer: "writeonly",
+			Code:     "WO001",
+			Severity: "info",
+			Message:  "field " + f.Struct + "." + f.Field + " is written but never read",
+			Range:    f.Decl,
+			Related:  rel,
+		})
+	}
+	_ = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"findings": findings})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// rangeMutateAnalyzer flags inserts and deletes on a map inside a range
+// loop over the same map. Whether an entry added during iteration is
+// visited is unspecified, and a deleted entry that has not been reached yet
+// is silently skipped. Writing or deleting the entry for the current key is
+// well defined and is not reported. Function literals in the loop body are
+// skipped, since they may run after the loop.
+var rangeMutateAnalyzer = &analyzer{
+	name:     "rangemutate",
+	code:     "GA305",
+	summary:  "Map modified while ranging over it",
+	severity: "warning",
+	run:      runRangeMutate,
+}
+
+func runRangeMutate(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		rs, ok := n.(*ast.RangeStmt)
+		if !ok {
+			return true
+		}
+		typ := p.info.TypeOf(rs.X)
+		if typ == nil {
+			return true
+		}
+		if _, ok := typ.Underlying().(*types.Map); !ok {
+			return true
+		}
+		m := passes.ExprObject(rs.X, p.info)
+		if m == nil {
+			return true
+		}
+		var key types.Object
+		if id, ok := rs.Key.(*ast.Ident); ok {
+			key = p.info.ObjectOf(id)
+		}
+		report := func(site ast.Node, target, index ast.Expr, what string) {
+			if passes.ExprObject(target, p.info) != m || types.ExprString(passes.Unparen(target)) != types.ExprString(passes.Unparen(rs.X)) {
+				return
+			}
+			if id, ok := passes.Unparen(index).(*ast.Ident); ok && key != nil && p.info.Uses[id] == key {
+				return
+			}
+			findings = append(findings, Finding{
+				Message: what + " map " + m.Name() + " while ranging over it; entries added or removed during iteration may or may not be visited",
+				Range:   p.rangeForNode(site),
+				Related: []RelatedRange{{Range: p.rangeForNode(rs.X), Message: "range over " + m.Name()}},
+			})
+		}
+		ast.Inspect(rs.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.AssignStmt:
+				if node.Tok == token.DEFINE {
+					return true
+				}
+				for _, lhs := range node.Lhs {
+					if ix, ok := passes.Unparen(lhs).(*ast.IndexExpr); ok {
+						report(ix, ix.X, ix.Index, "inserting into")
+					}
+				}
+			case *ast.CallExpr:
+				id, ok := passes.Unparen(node.Fun).(*ast.Ident)
+				if !ok || len(node.Args) != 2 {
+					return true
+				}
+				if b, ok := p.info.Uses[id].(*types.Builtin); ok && b.Name() == "delete" {
+					report(node, node.Args[0], node.Args[1], "deleting from")
+				}
+			}
+			return true
+		})
+		return true
+	})
+	return findings
+}
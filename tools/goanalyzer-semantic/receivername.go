@@ -0,0 +1,104 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// receiverNameAnalyzer flags methods whose receiver is named differently
+// from the other methods of the same type in the package. The name used by
+// most methods, or by the first declared on a tie, is taken as the type's
+// receiver name; unnamed and blank receivers are ignored.
+var receiverNameAnalyzer = &analyzer{
+	name:     "receivername",
+	code:     "GA313",
+	summary:  "Method receiver named inconsistently with the type's other methods",
+	severity: "info",
+	run:      runReceiverName,
+}
+
+func runReceiverName(p *pass) []Finding {
+	type receiverUse struct {
+		ident *ast.Ident
+		count int
+	}
+	// first holds, per type, the first receiver declared under each name.
+	first := make(map[*types.TypeName]map[string]*receiverUse)
+	var order []*ast.Ident
+	for _, f := range p.files {
+		for _, decl := range f.Decls {
+			id := receiverIdent(decl)
+			tn := p.receiverTypeName(id)
+			if tn == nil {
+				continue
+			}
+			names := first[tn]
+			if names == nil {
+				names = make(map[string]*receiverUse)
+				first[tn] = names
+			}
+			if names[id.Name] == nil {
+				names[id.Name] = &receiverUse{ident: id}
+			}
+			names[id.Name].count++
+			order = append(order, id)
+		}
+	}
+
+	var findings []Finding
+	for _, id := range order {
+		if p.fset.File(id.Pos()) != p.fset.File(p.file.Pos()) {
+			continue
+		}
+		names := first[p.receiverTypeName(id)]
+		var want *receiverUse
+		for _, use := range names {
+			if want == nil || use.count > want.count || use.count == want.count && use.ident.Pos() < want.ident.Pos() {
+				want = use
+			}
+		}
+		if id.Name == want.ident.Name {
+			continue
+		}
+		findings = append(findings, Finding{
+			Message: "receiver " + id.Name + " differs from the name " + want.ident.Name + " used by the other methods of " + p.receiverTypeName(id).Name(),
+			Range:   p.rangeForNode(id),
+			Related: []RelatedRange{{Range: p.rangeForNode(want.ident), Message: "receiver named " + want.ident.Name + " here"}},
+		})
+	}
+	return findings
+}
+
+// receiverIdent returns the receiver name of a method declaration, or nil
+// for functions and unnamed or blank receivers.
+func receiverIdent(decl ast.Decl) *ast.Ident {
+	fd, ok := decl.(*ast.FuncDecl)
+	if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 || len(fd.Recv.List[0].Names) != 1 {
+		return nil
+	}
+	if id := fd.Recv.List[0].Names[0]; id.Name != "_" {
+		return id
+	}
+	return nil
+}
+
+// receiverTypeName returns the named type whose method declares the
+// receiver id, through a pointer and for generic types alike.
+func (p *pass) receiverTypeName(id *ast.Ident) *types.TypeName {
+	if id == nil {
+		return nil
+	}
+	recv, ok := p.info.Defs[id].(*types.Var)
+	if !ok {
+		return nil
+	}
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil
+	}
+	return named.Origin().Obj()
+}
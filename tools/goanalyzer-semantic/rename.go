@@ -0,0 +1,372 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// RenameCheckOutput is the response of "rename_check" mode. An empty
+// Conflicts list means renaming the symbol to NewName by rewriting every
+// range in Edits keeps the program's meaning.
+type RenameCheckOutput struct {
+	Name      string           `json:"name"`
+	NewName   string           `json:"new_name"`
+	Conflicts []RenameConflict `json:"conflicts"`
+	// Edits are the declaration followed by every use.
+	Edits []Range `json:"edits"`
+}
+
+type RenameConflict struct {
+	Range  Range  `json:"range"`
+	Reason string `json:"reason"`
+}
+
+func (o *RenameCheckOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Conflicts {
+		f(&o.Conflicts[i].Range)
+	}
+	for i := range o.Edits {
+		f(&o.Edits[i])
+	}
+}
+
+// RenameRefusal explains why the symbol at a position cannot be renamed.
+// Code is one of "no_symbol", "blank", "builtin", "package_name", "label",
+// "external", "implicit", "embedded" or "exported".
+type RenameRefusal struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// PrepareRenameOutput is the response of "prepare_rename" mode: the range
+// and name of the identifier under the cursor, and a refusal when the
+// rename UI should not open.
+type PrepareRenameOutput struct {
+	Name    string         `json:"name"`
+	Range   Range          `json:"range"`
+	Refusal *RenameRefusal `json:"refusal,omitempty"`
+}
+
+func (o *PrepareRenameOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Range)
+}
+
+// renameTarget resolves the symbol to rename at line/col. prepare_rename
+// and rename_check both go through it, so a symbol one of them accepts is
+// never refused by the other. The identifier under the cursor is returned
+// even when the rename is refused; it is nil only if there is none.
+func renameTarget(lp *loadedPackage, line, col int, parents map[ast.Node]ast.Node) (*symbolTarget, *ast.Ident, *RenameRefusal) {
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, line, col)
+	if ident == nil {
+		return nil, nil, &RenameRefusal{Code: "no_symbol", Reason: "there is no identifier at the position"}
+	}
+	refuse := func(code, reason string) (*symbolTarget, *ast.Ident, *RenameRefusal) {
+		return nil, ident, &RenameRefusal{Code: code, Reason: reason}
+	}
+	if ident.Name == "_" {
+		return refuse("blank", "the blank identifier cannot be renamed")
+	}
+	sel := selMap[ident]
+	switch obj := identObject(lp.info, lp.pkg, ident, sel).(type) {
+	case *types.Builtin, *types.Nil:
+		return refuse("builtin", ident.Name+" is a predeclared identifier")
+	case *types.PkgName:
+		return refuse("package_name", ident.Name+" names an imported package")
+	case *types.Label:
+		return refuse("label", "labels cannot be renamed")
+	case *types.TypeName, *types.Const:
+		if obj.Pkg() == nil {
+			return refuse("builtin", ident.Name+" is a predeclared identifier")
+		}
+	case *types.Var:
+		if obj.Embedded() {
+			return refuse("embedded", "the name of embedded field "+ident.Name+" is the name of its type")
+		}
+	}
+	t := resolveIdent(lp, ident, sel, parents, true)
+	switch {
+	case t == nil:
+		return refuse("no_symbol", ident.Name+" does not denote a renameable symbol")
+	case t.declIdent == nil && t.obj.Pkg() == lp.pkg:
+		return refuse("implicit", t.name()+" has no declaring identifier to rename")
+	case t.declIdent == nil:
+		return refuse("external", t.name()+" is declared in package "+t.obj.Pkg().Path()+", outside the files being analyzed")
+	case t.obj != nil && t.obj.Exported() && t.obj.Parent() == lp.pkg.Scope() && lp.pkg.Name() != "main":
+		return refuse("exported", t.name()+" is exported and references from other packages are not searched")
+	}
+	return t, ident, nil
+}
+
+func prepareRename(in Input) *PrepareRenameOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	_, ident, refusal := renameTarget(lp, in.Line, in.Col, buildPackageParentMap(lp.files))
+	if ident == nil {
+		return nil
+	}
+	return &PrepareRenameOutput{
+		Name:    ident.Name,
+		Range:   rangeForIdent(lp.fset, ident),
+		Refusal: refusal,
+	}
+}
+
+func renameCheck(in Input) *RenameCheckOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := buildPackageParentMap(lp.files)
+	t, ident, refusal := renameTarget(lp, in.Line, in.Col, parents)
+	if ident == nil {
+		return nil
+	}
+	out := &RenameCheckOutput{
+		Name:      ident.Name,
+		NewName:   in.NewName,
+		Conflicts: make([]RenameConflict, 0),
+		Edits:     make([]Range, 0),
+	}
+	conflict := func(r Range, reason string) {
+		out.Conflicts = append(out.Conflicts, RenameConflict{Range: r, Reason: reason})
+	}
+	if refusal != nil {
+		conflict(rangeForIdent(lp.fset, ident), refusal.Reason)
+		return out
+	}
+	out.Name = t.name()
+	decl := t.declRange(lp.fset)
+	switch {
+	case token.IsKeyword(in.NewName):
+		conflict(decl, "\""+in.NewName+"\" is a Go keyword")
+		return out
+	case !token.IsIdentifier(in.NewName):
+		conflict(decl, "\""+in.NewName+"\" is not a valid Go identifier")
+		return out
+	case in.NewName == "_":
+		conflict(decl, "renaming to the blank identifier drops the declaration")
+		return out
+	case in.NewName == out.Name:
+		return out
+	}
+
+	uses := collectUses(lp.info, lp.fset, lp.files, t.objects, decl, nil, parents, useOptions{})
+	out.Edits = append(out.Edits, decl)
+	for _, u := range uses {
+		out.Edits = append(out.Edits, u.Range)
+	}
+
+	rc := &renameChecker{lp: lp, parents: parents, target: t, newName: in.NewName, conflict: conflict}
+	rc.checkDeclScope()
+	rc.checkShadowedUses()
+	rc.checkCapturedReferences()
+	rc.checkExport()
+	sortConflicts(out.Conflicts)
+	return out
+}
+
+type renameChecker struct {
+	lp       *loadedPackage
+	parents  map[ast.Node]ast.Node
+	target   *symbolTarget
+	newName  string
+	conflict func(Range, string)
+}
+
+func (rc *renameChecker) isTarget(obj types.Object) bool {
+	for _, o := range rc.target.objects {
+		if o == obj {
+			return true
+		}
+	}
+	return false
+}
+
+// declScope returns the scope the renamed symbol is declared in, or nil for
+// fields and methods, which are resolved through selectors.
+func (rc *renameChecker) declScope() *types.Scope {
+	for _, o := range rc.target.objects {
+		if o.Parent() != nil {
+			if rc.target.typeSwitch != nil {
+				// Each clause has its own implicit object; they all
+				// stand for the guard declared in the switch's scope.
+				return o.Parent().Parent()
+			}
+			return o.Parent()
+		}
+	}
+	return nil
+}
+
+// checkDeclScope reports objects that already use the new name where the
+// symbol is declared: the same scope, the file scopes (imports) for a
+// package-level symbol, or the field and method set for a selector.
+func (rc *renameChecker) checkDeclScope() {
+	pkg := rc.lp.pkg
+	if scope := rc.declScope(); scope != nil {
+		if other := scope.Lookup(rc.newName); other != nil && !rc.isTarget(other) {
+			rc.conflictAt(other, rc.newName+" is already declared in this scope")
+		}
+		if scope == pkg.Scope() {
+			for _, f := range rc.lp.files {
+				if fs := rc.lp.info.Scopes[f]; fs != nil {
+					if other := fs.Lookup(rc.newName); other != nil {
+						rc.conflictAt(other, rc.newName+" is already declared in file scope by an import")
+					}
+				}
+			}
+		}
+		return
+	}
+
+	var recv types.Type
+	switch obj := rc.target.obj.(type) {
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			recv = sig.Recv().Type()
+		}
+	case *types.Var:
+		if owner := fieldOwner(rc.target.declIdent, rc.parents); owner != "" {
+			if tn, ok := pkg.Scope().Lookup(owner).(*types.TypeName); ok {
+				recv = types.NewPointer(tn.Type())
+			}
+		}
+		if recv == nil {
+			if st := enclosingStruct(rc.target.declIdent, rc.parents, rc.lp.info); st != nil {
+				recv = st
+			}
+		}
+	}
+	if recv == nil {
+		return
+	}
+	if other, _, _ := types.LookupFieldOrMethod(recv, true, pkg, rc.newName); other != nil {
+		rc.conflictAt(other, rc.newName+" is already a field or method of the same type")
+	}
+}
+
+// checkShadowedUses reports uses of the symbol that would resolve to a
+// different object named newName declared in a scope nested inside the
+// symbol's own scope.
+func (rc *renameChecker) checkShadowedUses() {
+	declScope := rc.declScope()
+	if declScope == nil {
+		return
+	}
+	for id, obj := range rc.lp.info.Uses {
+		if !rc.isTarget(obj) {
+			continue
+		}
+		scope := rc.lp.pkg.Scope().Innermost(id.Pos())
+		if scope == nil {
+			continue
+		}
+		_, other := scope.LookupParent(rc.newName, id.Pos())
+		if other == nil || rc.isTarget(other) || !strictlyInside(other.Parent(), declScope) {
+			continue
+		}
+		rc.conflict(rangeForIdent(rc.lp.fset, id),
+			"this use would refer to the "+rc.newName+" declared at "+rc.position(other)+" instead")
+	}
+}
+
+// checkCapturedReferences reports existing references to another object
+// named newName that would resolve to the renamed symbol, because they are
+// inside its scope and the other object is declared further out.
+func (rc *renameChecker) checkCapturedReferences() {
+	declScope := rc.declScope()
+	if declScope == nil {
+		return
+	}
+	local := declScope != rc.lp.pkg.Scope()
+	for id, obj := range rc.lp.info.Uses {
+		if id.Name != rc.newName || obj.Parent() == nil || rc.isTarget(obj) {
+			continue
+		}
+		if sel, ok := rc.parents[id].(*ast.SelectorExpr); ok && sel.Sel == id {
+			continue
+		}
+		scope := rc.lp.pkg.Scope().Innermost(id.Pos())
+		if scope == nil || (scope != declScope && !strictlyInside(scope, declScope)) {
+			continue
+		}
+		if local && id.Pos() < rc.target.declIdent.Pos() {
+			continue
+		}
+		if !strictlyInside(declScope, obj.Parent()) {
+			continue
+		}
+		rc.conflict(rangeForIdent(rc.lp.fset, id),
+			"this reference to "+rc.newName+" would refer to the renamed "+rc.target.name()+" instead")
+	}
+}
+
+// checkExport reports unexporting a field or method outside package main:
+// other packages may reference it and are not searched. Exported
+// package-level symbols are already refused by renameTarget.
+func (rc *renameChecker) checkExport() {
+	if rc.target.obj == nil || !rc.target.obj.Exported() || token.IsExported(rc.newName) || rc.lp.pkg.Name() == "main" {
+		return
+	}
+	if rc.declScope() != nil {
+		return
+	}
+	rc.conflict(rangeForIdent(rc.lp.fset, rc.target.declIdent),
+		"unexporting "+rc.target.name()+" breaks references from other packages")
+}
+
+func (rc *renameChecker) conflictAt(obj types.Object, reason string) {
+	r, ok := rangeForObject(rc.lp.fset, obj)
+	if !ok {
+		r = rangeForIdent(rc.lp.fset, rc.target.declIdent)
+	}
+	rc.conflict(r, reason)
+}
+
+func (rc *renameChecker) position(obj types.Object) string {
+	pos := rc.lp.fset.Position(obj.Pos())
+	if !pos.IsValid() {
+		return "package " + obj.Pkg().Path()
+	}
+	return pos.String()
+}
+
+// strictlyInside reports whether inner is nested somewhere below outer.
+func strictlyInside(inner, outer *types.Scope) bool {
+	if inner == nil || outer == nil {
+		return false
+	}
+	for s := inner.Parent(); s != nil; s = s.Parent() {
+		if s == outer {
+			return true
+		}
+	}
+	return false
+}
+
+// enclosingStruct returns the struct type whose field list declares ident.
+func enclosingStruct(ident *ast.Ident, parents map[ast.Node]ast.Node, info *types.Info) *types.Struct {
+	for n := parents[ident]; n != nil; n = parents[n] {
+		if st, ok := n.(*ast.StructType); ok {
+			s, _ := info.TypeOf(st).(*types.Struct)
+			return s
+		}
+	}
+	return nil
+}
+
+func sortConflicts(conflicts []RenameConflict) {
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return rangeLess(conflicts[i].Range, conflicts[j].Range)
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLog is the subset of the SARIF 2.1.0 object model the helper emits:
+// one run whose driver lists a rule per finding code.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion positions are one-based, and columns count code points as
+// the run's columnKind says.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifLevels maps finding severities to SARIF result levels.
+var sarifLevels = map[string]string{
+	"error":   "error",
+	"warning": "warning",
+	"info":    "note",
+	"hint":    "note",
+}
+
+// toSARIF converts the findings of a report to a SARIF log. Related ranges,
+// such as the other half of a race, become related locations. A nil report,
+// e.g. for a target that does not load, gives a run without results.
+func toSARIF(out *AnalyzeOutput, in Input) *sarifLog {
+	if out == nil {
+		out = &AnalyzeOutput{}
+	}
+	byCode := make(map[string]*analyzer)
+	for _, a := range analyzers {
+		byCode[a.code] = a
+	}
+	var codes []string
+	seen := make(map[string]bool)
+	for _, f := range out.Findings {
+		if !seen[f.Code] {
+			seen[f.Code] = true
+			codes = append(codes, f.Code)
+		}
+	}
+	sort.Strings(codes)
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:    "goanalyzer-semantic",
+			Version: strconv.Itoa(protocolVersion),
+			Rules:   make([]sarifRule, 0, len(codes)),
+		}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    make([]sarifResult, 0, len(out.Findings)),
+	}
+	ruleIndex := make(map[string]int)
+	for i, code := range codes {
+		ruleIndex[code] = i
+		rule := sarifRule{ID: code}
+		if a := byCode[code]; a != nil {
+			rule.Name = a.name
+			rule.ShortDescription.Text = a.summary
+			rule.DefaultConfiguration.Level = sarifLevels[a.severity]
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+
+	target := in.File
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	cols := &columnMapper{target: filepath.Clean(target), content: in.Content, lines: make(map[string][]string)}
+	location := func(r Range) sarifLocation {
+		file := r.File
+		if file == "" {
+			file = cols.target
+		}
+		codePoints := func(line, col int) int {
+			text := cols.line(file, line)
+			if col > len(text) {
+				return utf8.RuneCountInString(text) + col - len(text) + 1
+			}
+			return utf8.RuneCountInString(text[:col]) + 1
+		}
+		return sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: pathToURI(file)},
+			Region: sarifRegion{
+				StartLine:   r.Start.Line + 1,
+				StartColumn: codePoints(r.Start.Line, r.Start.Col),
+				EndLine:     r.End.Line + 1,
+				EndColumn:   codePoints(r.End.Line, r.End.Col),
+			},
+		}}
+	}
+	for _, f := range out.Findings {
+		res := sarifResult{
+			RuleID:    f.Code,
+			RuleIndex: ruleIndex[f.Code],
+			Level:     sarifLevels[f.Severity],
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{location(f.Range)},
+		}
+		for i, rel := range f.Related {
+			loc := location(rel.Range)
+			id := i + 1
+			loc.ID = &id
+			loc.Message = &sarifMessage{Text: rel.Message}
+			res.RelatedLocations = append(res.RelatedLocations, loc)
+		}
+		run.Results = append(run.Results, res)
+	}
+	return &sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}
+}
+
+// writeSARIF writes log to path, or to stdout when path is empty.
+func writeSARIF(path string, log *sarifLog) error {
+	if path == "" {
+		writeOutput(log)
+		return nil
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// protocolMode registers the response type of a mode for the --schema
+// document; "" is the default resolve query.
+type protocolMode struct {
+	name     string
+	response interface{}
+}
+
+// protocolModes lists every mode handled by main. The report modes share
+// AnalyzeOutput.
+var protocolModes = []protocolMode{
+	{"", Output{}},
+	{"analyze", AnalyzeOutput{}},
+	{"definition", DefinitionOutput{}},
+	{"hover", HoverOutput{}},
+	{"callers", CallersOutput{}},
+	{"implementations", ImplementationsOutput{}},
+	{"outline", OutlineOutput{}},
+	{"unused", UnusedOutput{}},
+	{"shadow_report", ShadowReportOutput{}},
+	{"race_report", AnalyzeOutput{}},
+	{"retention_report", AnalyzeOutput{}},
+	{"concurrency", AnalyzeOutput{}},
+	{"metrics", MetricsOutput{}},
+	{"lifetime", LifetimeOutput{}},
+	{"closure_report", ClosureReportOutput{}},
+	{"struct_layout", StructLayoutOutput{}},
+	{"lock_report", LockReportOutput{}},
+	{"goroutine_map", GoroutineMapOutput{}},
+	{"defer_report", DeferReportOutput{}},
+	{"channel_report", ChannelReportOutput{}},
+	{"error_flow", ErrorFlowOutput{}},
+	{"const_group", ConstGroupOutput{}},
+	{"interface_usage", InterfaceUsageOutput{}},
+	{"callgraph", CallGraphOutput{}},
+	{"test_refs", TestRefsOutput{}},
+	{"semantic_tokens", SemanticTokensOutput{}},
+	{"capabilities", CapabilitiesOutput{}},
+	{"prepare_rename", PrepareRenameOutput{}},
+	{"rename_check", RenameCheckOutput{}},
+}
+
+// schemaEnums lists the values of string fields that take one of a fixed
+// set, keyed by Go type name and JSON field name. Input.mode is derived
+// from protocolModes.
+var schemaEnums = map[string][]string{
+	"Input.column_mode":           {"byte", "visual"},
+	"Input.unused_scope":          {"file", "function"},
+	"Input.report_scope":          {"file", "package"},
+	"Input.defer_scope":           {"file", "function"},
+	"Input.format":                {"sarif"},
+	"Finding.severity":            {"error", "warning", "info", "hint"},
+	"LoadDiagnostic.reason":       {"parse_error", "build_constraints", "cgo"},
+	"UseEntry.nil_checked":        {"true", "false", "unknown"},
+	"ChannelOp.kind":              {"send", "receive", "close", "range"},
+	"ErrorUse.kind":               {"checked", "returned", "wrapped", "logged", "blank", "assigned", "overwritten", "other"},
+	"GoroutineLaunch.kind":        {"literal", "func", "method", "value"},
+	"GoroutineLaunch.termination": {"context", "stop_channel", "bounded", "none", "unknown"},
+	"LockMember.status":           {"guarded", "partial", "unguarded"},
+	"OutlineSymbol.kind":          {"const", "var", "type", "struct", "interface", "func", "method", "field"},
+	"DefinitionOutput.decl_kind":  {"var", "param", "result", "field", "const", "type", "func", "method", "package"},
+	"HoverOutput.decl_kind":       {"var", "param", "result", "field", "const", "type", "func", "method", "package"},
+	"RenameRefusal.code":          {"no_symbol", "blank", "builtin", "package_name", "label", "external", "implicit", "embedded", "exported"},
+	"Shadowing.kind":              {"if_init", "type_switch", "loop_copy", "param", "local"},
+	"UnusedVar.kind":              {"local", "param", "result"},
+}
+
+// protocolSchema returns a JSON Schema (draft 2020-12) document describing
+// the request and the response of every mode. Every struct is a definition
+// under $defs; x-request and x-responses point at the request and at each
+// mode's response, which is null when the query finds nothing,
+// x-plugin-input and x-plugin-output at what plugins read and write, and
+// x-watch-event at the lines written by --watch.
+func protocolSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+	responses := make(map[string]interface{})
+	for _, m := range protocolModes {
+		responses[m.name] = schemaType(reflect.PtrTo(reflect.TypeOf(m.response)), defs)
+	}
+	return map[string]interface{}{
+		"$schema":            "https://json-schema.org/draft/2020-12/schema",
+		"title":              "goanalyzer-semantic protocol",
+		"x-protocol-version": protocolVersion,
+		"x-request":          schemaType(reflect.TypeOf(Input{}), defs),
+		"x-responses":        responses,
+		"x-plugin-input":     schemaType(reflect.TypeOf(PluginSummary{}), defs),
+		"x-plugin-output":    schemaType(reflect.TypeOf(PluginOutput{}), defs),
+		"x-watch-event":      schemaType(reflect.TypeOf(WatchEvent{}), defs),
+		"$defs":              defs,
+	}
+}
+
+// schemaType returns the schema of t, adding the structs it refers to to
+// defs. Slices may be null, as nil slices encode.
+func schemaType(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return map[string]interface{}{"anyOf": []interface{}{schemaType(t.Elem(), defs), map[string]interface{}{"type": "null"}}}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": schemaType(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaType(t.Elem(), defs)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+		if _, ok := defs[t.Name()]; ok {
+			return ref
+		}
+		// Register before the fields so that recursive types terminate.
+		def := map[string]interface{}{"type": "object"}
+		defs[t.Name()] = def
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := schemaType(f.Type, defs)
+			if values := schemaEnum(t.Name(), name); values != nil {
+				prop["enum"] = values
+			}
+			properties[name] = prop
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		def["properties"] = properties
+		def["required"] = required
+		return ref
+	}
+	return map[string]interface{}{}
+}
+
+func schemaEnum(typeName, field string) []string {
+	if typeName == "Input" && field == "mode" {
+		modes := make([]string, 0, len(protocolModes))
+		for _, m := range protocolModes {
+			modes = append(modes, m.name)
+		}
+		return modes
+	}
+	return schemaEnums[typeName+"."+field]
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// semanticTokenTypes and semanticTokenModifiers form the legend of
+// "semantic_tokens" mode. A token's type is an index into the first, its
+// modifiers a bitset over the second. Fields use LSP's "property".
+var (
+	semanticTokenTypes     = []string{"parameter", "variable", "property", "constant"}
+	semanticTokenModifiers = []string{"declaration", "readonly", "captured", "pointer"}
+)
+
+const (
+	tokenParameter = iota
+	tokenVariable
+	tokenProperty
+	tokenConstant
+)
+
+const (
+	modDeclaration = 1 << iota
+	modReadonly
+	modCaptured
+	modPointer
+)
+
+// SemanticTokensOutput is the response of "semantic_tokens" mode. Data is
+// the LSP encoding of the tokens, five integers each: the line delta from
+// the previous token, the start column (relative to the previous token's
+// when on the same line), the length, the type and the modifier bitset.
+// Columns and lengths follow ColumnMode.
+type SemanticTokensOutput struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Data   []int                `json:"data"`
+	tokens []semanticToken
+}
+
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"token_types"`
+	TokenModifiers []string `json:"token_modifiers"`
+}
+
+type semanticToken struct {
+	Range     Range
+	Type      int
+	Modifiers int
+}
+
+// mapRanges converts the token ranges before they are delta-encoded, so
+// that Data is computed from the converted columns.
+func (o *SemanticTokensOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.tokens {
+		f(&o.tokens[i].Range)
+	}
+}
+
+func (o *SemanticTokensOutput) MarshalJSON() ([]byte, error) {
+	type plain SemanticTokensOutput
+	out := plain(*o)
+	out.Data = encodeSemanticTokens(o.tokens)
+	return json.Marshal(out)
+}
+
+func encodeSemanticTokens(tokens []semanticToken) []int {
+	data := make([]int, 0, 5*len(tokens))
+	line, col := 0, 0
+	for _, t := range tokens {
+		if t.Range.Start.Line != line {
+			col = 0
+		}
+		data = append(data,
+			t.Range.Start.Line-line,
+			t.Range.Start.Col-col,
+			t.Range.End.Col-t.Range.Start.Col,
+			t.Type,
+			t.Modifiers)
+		line, col = t.Range.Start.Line, t.Range.Start.Col
+	}
+	return data
+}
+
+// semanticTokens classifies the identifiers of the target file that denote
+// variables, fields and constants. A variable is readonly when no use in
+// the package reassigns it, captured when used from a function literal
+// other than the one declaring it, and pointer-like when its type is one
+// isPointerType accepts. Constants are always readonly.
+func semanticTokens(in Input) *SemanticTokensOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := buildPackageParentMap(lp.files)
+	written := make(map[types.Object]bool)
+	declFuncs := make(map[types.Object]ast.Node)
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if obj := lp.info.Defs[id]; obj != nil {
+					declFuncs[obj] = enclosingFunc(id, parents)
+				} else if obj := lp.info.Uses[id]; obj != nil && isReassign(id, lp.info, parents) {
+					written[obj] = true
+				}
+			}
+			return true
+		})
+	}
+
+	out := &SemanticTokensOutput{
+		Legend: SemanticTokensLegend{TokenTypes: semanticTokenTypes, TokenModifiers: semanticTokenModifiers},
+	}
+	ast.Inspect(lp.file, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Name == "_" {
+			return true
+		}
+		mods := 0
+		obj := lp.info.Defs[id]
+		if obj != nil {
+			mods |= modDeclaration
+		} else {
+			obj = lp.info.Uses[id]
+		}
+		var typ int
+		switch o := obj.(type) {
+		case *types.Const:
+			typ = tokenConstant
+			mods |= modReadonly
+		case *types.Var:
+			switch {
+			case o.IsField():
+				if o.Embedded() && mods&modDeclaration != 0 {
+					return true
+				}
+				typ = tokenProperty
+			case paramKind(o, lp.info) != "":
+				typ = tokenParameter
+			default:
+				typ = tokenVariable
+			}
+			if !written[o] {
+				mods |= modReadonly
+			}
+			if isCaptured(id, o, declFuncs[o], parents, useOptions{}) {
+				mods |= modCaptured
+			}
+			if isPointerType(o.Type()) {
+				mods |= modPointer
+			}
+		default:
+			return true
+		}
+		out.tokens = append(out.tokens, semanticToken{Range: rangeForIdent(lp.fset, id), Type: typ, Modifiers: mods})
+		return true
+	})
+	sort.Slice(out.tokens, func(i, j int) bool { return rangeLess(out.tokens[i].Range, out.tokens[j].Range) })
+	return out
+}
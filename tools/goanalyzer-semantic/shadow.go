@@ -0,0 +1,166 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// ShadowReportOutput is the response of "shadow_report" mode.
+type ShadowReportOutput struct {
+	Shadows []Shadowing `json:"shadows"`
+}
+
+// Shadowing is a declaration that hides an outer variable of the same name.
+// Kind is "if_init", "type_switch", "loop_copy", "param" or "local".
+type Shadowing struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Decl      Range  `json:"decl"`
+	OuterDecl Range  `json:"outer_decl"`
+	// Depth is the number of scopes between the shadowing declaration and
+	// the outer variable.
+	Depth    int    `json:"depth"`
+	Function string `json:"function"`
+	// Idiom marks `v := v` copies of a loop variable inside the loop body,
+	// which are deliberate rather than accidental.
+	Idiom bool `json:"idiom,omitempty"`
+	// OuterUsedAfter is set when the outer variable is used after the
+	// shadowing scope ends, where a write meant for it may have been lost.
+	OuterUsedAfter bool `json:"outer_used_after,omitempty"`
+}
+
+func (o *ShadowReportOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Shadows {
+		f(&o.Shadows[i].Decl)
+		f(&o.Shadows[i].OuterDecl)
+	}
+}
+
+// shadowReport lists every variable declaration in the target file that
+// shadows an outer variable, including type switch guards.
+func shadowReport(in Input) *ShadowReportOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	loopScopes := make(map[*types.Scope]bool)
+	for node, scope := range lp.info.Scopes {
+		switch node.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			loopScopes[scope] = true
+		}
+	}
+
+	out := &ShadowReportOutput{Shadows: make([]Shadowing, 0)}
+	report := func(id *ast.Ident, kind string, inner *types.Scope, outer *types.Var, end token.Pos) {
+		depth := 0
+		for s := inner; s != nil && s != outer.Parent(); s = s.Parent() {
+			depth++
+		}
+		outerDecl, _ := lp.objectRange(outer)
+		out.Shadows = append(out.Shadows, Shadowing{
+			Name:           id.Name,
+			Kind:           kind,
+			Decl:           rangeForIdent(lp.fset, id),
+			OuterDecl:      outerDecl,
+			Depth:          depth,
+			Function:       callerName(id, parents),
+			OuterUsedAfter: usedAfter(lp.info, outer, end),
+		})
+	}
+	ast.Inspect(lp.file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TypeSwitchStmt:
+			// The guard has no object of its own; each clause declares an
+			// implicit one, so look up from the first clause's scope.
+			as, ok := n.Assign.(*ast.AssignStmt)
+			if !ok || len(n.Body.List) == 0 {
+				return true
+			}
+			implicit := lp.info.Implicits[n.Body.List[0]]
+			if implicit == nil || implicit.Parent() == nil {
+				return true
+			}
+			guard := as.Lhs[0].(*ast.Ident)
+			if outer := shadowedVar(implicit.Parent(), guard.Name, guard.Pos()); outer != nil {
+				report(guard, "type_switch", implicit.Parent(), outer, n.End())
+			}
+		case *ast.Ident:
+			v, ok := lp.info.Defs[n].(*types.Var)
+			if !ok || v.IsField() || n.Name == "_" || v.Parent() == nil || v.Parent() == lp.pkg.Scope() {
+				return true
+			}
+			outer := shadowedVar(v.Parent(), v.Name(), v.Pos())
+			if outer == nil {
+				return true
+			}
+			kind := paramKind(v, lp.info)
+			if kind == "" {
+				kind = "local"
+			}
+			as, _ := parents[n].(*ast.AssignStmt)
+			if as != nil {
+				if ifs, ok := parents[as].(*ast.IfStmt); ok && ifs.Init == as {
+					kind = "if_init"
+				}
+			}
+			report(n, kind, v.Parent(), outer, v.Parent().End())
+			if as != nil && loopScopes[outer.Parent()] && copiesVar(lp.info, as, n, outer) {
+				last := &out.Shadows[len(out.Shadows)-1]
+				last.Kind = "loop_copy"
+				last.Idiom = true
+			}
+		}
+		return true
+	})
+	sort.SliceStable(out.Shadows, func(i, j int) bool {
+		return rangeLess(out.Shadows[i].Decl, out.Shadows[j].Decl)
+	})
+	return out
+}
+
+// shadowedVar returns the variable named name that a declaration at pos in
+// scope hides, looking only at the scopes enclosing scope.
+func shadowedVar(scope *types.Scope, name string, pos token.Pos) *types.Var {
+	if scope.Parent() == nil {
+		return nil
+	}
+	_, obj := scope.Parent().LookupParent(name, pos)
+	v, ok := obj.(*types.Var)
+	if !ok || v.IsField() {
+		return nil
+	}
+	return v
+}
+
+// copiesVar reports whether the define statement as assigns outer to id, as
+// in `v := v`.
+func copiesVar(info *types.Info, as *ast.AssignStmt, id *ast.Ident, outer *types.Var) bool {
+	if as.Tok != token.DEFINE || len(as.Lhs) != len(as.Rhs) {
+		return false
+	}
+	for i, lhs := range as.Lhs {
+		if lhs == id {
+			rhs, ok := passes.Unparen(as.Rhs[i]).(*ast.Ident)
+			return ok && info.Uses[rhs] == outer
+		}
+	}
+	return false
+}
+
+func usedAfter(info *types.Info, obj types.Object, end token.Pos) bool {
+	for id, used := range info.Uses {
+		if used == obj && id.Pos() >= end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// useStream writes a resolve response as JSON lines so that clients can
+// render symbols with thousands of uses progressively: first the Output
+// without uses, then each UseEntry as soon as it is found, then a
+// streamEnd. Column conversion and relative lines are applied per line.
+type useStream struct {
+	enc *json.Encoder
+	// mu, when set, is locked before the first line and never released.
+	mu       *sync.Mutex
+	cols     *columnMapper
+	relative bool
+	declLine int
+	count    int
+	started  bool
+}
+
+// streamEnd terminates a streamed response; Count is the number of uses
+// written.
+type streamEnd struct {
+	Done  bool `json:"done"`
+	Count int  `json:"count"`
+}
+
+// header writes the symbol.
+func (s *useStream) header(out *Output) {
+	if s.mu != nil {
+		s.mu.Lock()
+	}
+	s.started = true
+	if s.cols != nil {
+		out.mapRanges(s.cols.mapRange)
+	}
+	s.declLine = out.Decl.Start.Line
+	_ = s.enc.Encode(out)
+}
+
+func (s *useStream) use(u UseEntry) {
+	if s.cols != nil {
+		s.cols.mapRange(&u.Range)
+	}
+	if s.relative {
+		u.Range.Start.Line -= s.declLine
+		u.Range.End.Line -= s.declLine
+	}
+	s.count++
+	_ = s.enc.Encode(u)
+}
+
+func (s *useStream) end() {
+	_ = s.enc.Encode(streamEnd{Done: true, Count: s.count})
+}
+
+// streamResolve answers a resolve query in streaming form. Results that
+// are only available in one piece, such as the syntax-only fallback, are
+// replayed through the same stream; a symbol that does not resolve is a
+// single null line, as in batch mode.
+func streamResolve(in Input, cols *columnMapper) {
+	// Like writeOutput, the stream takes outputMu for good, so once it has
+	// started a timeout can no longer interleave a null.
+	s := &useStream{
+		enc:      json.NewEncoder(os.Stdout),
+		mu:       &outputMu,
+		cols:     cols,
+		relative: in.RelativeToDecl,
+	}
+	out := resolveTo(in, s)
+	if s.started {
+		return
+	}
+	if out == nil {
+		encodeNil()
+		return
+	}
+	uses := out.Uses
+	out.Uses = nil
+	s.header(out)
+	for _, u := range uses {
+		s.use(u)
+	}
+	s.end()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"go/build"
+	"go/types"
+	"sort"
+
+	"goanalyzer-semantic/passes"
+)
+
+// StructLayoutOutput is the response of "struct_layout" mode. Sizes are in
+// bytes for the gc compiler on Arch. Fields whose type depends on a type
+// parameter have size, alignment and offset -1, as do the totals of their
+// struct; query an instantiation such as Pair[int, string] instead.
+type StructLayoutOutput struct {
+	Name    string        `json:"name"`
+	Decl    Range         `json:"decl"`
+	Arch    string        `json:"arch"`
+	Size    int64         `json:"size"`
+	Align   int64         `json:"align"`
+	Padding int64         `json:"padding"`
+	Fields  []FieldLayout `json:"fields"`
+	// Suggested is the field order with the least padding, equal to the
+	// declared order when no permutation is smaller, and SuggestedSize the
+	// struct size with that order. Both are omitted for generic layouts.
+	Suggested     []string `json:"suggested,omitempty"`
+	SuggestedSize int64    `json:"suggested_size,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// FieldLayout places one field of the struct. Padding is the number of
+// unused bytes between the field and the next one, or the end of the
+// struct. An embedded field is a single field named after its type.
+type FieldLayout struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Offset   int64  `json:"offset"`
+	Size     int64  `json:"size"`
+	Align    int64  `json:"align"`
+	Padding  int64  `json:"padding"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+func (o *StructLayoutOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+}
+
+// structLayout lays out the struct type named at Line/Col for GOARCH, or the
+// host architecture when the request does not set it. A use of a generic
+// type with type arguments is laid out for those arguments.
+func structLayout(in Input) *StructLayoutOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	tn, ok := identObject(lp.info, lp.pkg, ident, selMap[ident]).(*types.TypeName)
+	if !ok {
+		return nil
+	}
+	typ := tn.Type()
+	if inst, ok := lp.info.Instances[ident]; ok {
+		typ = inst.Type
+	}
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	out := &StructLayoutOutput{Name: tn.Name(), Arch: in.GOARCH, Fields: make([]FieldLayout, 0, st.NumFields())}
+	out.Decl, _ = lp.objectRange(tn)
+	if out.Arch == "" {
+		out.Arch = build.Default.GOARCH
+	}
+	sizes := types.SizesFor("gc", out.Arch)
+	if sizes == nil {
+		out.Error = "unknown architecture " + out.Arch
+		return out
+	}
+
+	fields := make([]*types.Var, st.NumFields())
+	generic := false
+	for i := range fields {
+		fields[i] = st.Field(i)
+		generic = generic || passes.ContainsTypeParam(fields[i].Type(), make(map[types.Type]bool))
+	}
+	if generic {
+		out.Size, out.Align, out.Padding = -1, -1, -1
+		for _, f := range fields {
+			fl := FieldLayout{Name: f.Name(), Type: types.TypeString(f.Type(), nil), Offset: -1, Size: -1, Align: -1, Padding: -1, Embedded: f.Embedded()}
+			if !passes.ContainsTypeParam(f.Type(), make(map[types.Type]bool)) {
+				fl.Size, fl.Align = sizes.Sizeof(f.Type()), sizes.Alignof(f.Type())
+			}
+			out.Fields = append(out.Fields, fl)
+		}
+		return out
+	}
+
+	out.Size, out.Align = sizes.Sizeof(st), sizes.Alignof(st)
+	offsets := sizes.Offsetsof(fields)
+	out.Padding = out.Size
+	for i, f := range fields {
+		fl := FieldLayout{
+			Name:     f.Name(),
+			Type:     types.TypeString(f.Type(), nil),
+			Offset:   offsets[i],
+			Size:     sizes.Sizeof(f.Type()),
+			Align:    sizes.Alignof(f.Type()),
+			Embedded: f.Embedded(),
+		}
+		next := out.Size
+		if i+1 < len(fields) {
+			next = offsets[i+1]
+		}
+		fl.Padding = next - fl.Offset - fl.Size
+		out.Padding -= fl.Size
+		out.Fields = append(out.Fields, fl)
+	}
+
+	var order []*types.Var
+	order, out.SuggestedSize = optimalFieldOrder(sizes, fields)
+	for _, f := range order {
+		out.Suggested = append(out.Suggested, f.Name())
+	}
+	return out
+}
+
+// optimalFieldOrder returns the order of fields with the least padding and
+// the struct size it gives. When no order is smaller than the declared one,
+// fields is returned unchanged. The fields must not depend on type
+// parameters.
+func optimalFieldOrder(sizes types.Sizes, fields []*types.Var) ([]*types.Var, int64) {
+	size := sizes.Sizeof(types.NewStruct(fields, nil))
+	// Zero-size fields go first, since a trailing one is padded, then the
+	// rest by decreasing alignment. Go sizes are multiples of their
+	// alignment, so this order needs no padding between fields.
+	order := append([]*types.Var(nil), fields...)
+	sort.SliceStable(order, func(i, j int) bool {
+		zi, zj := sizes.Sizeof(order[i].Type()) == 0, sizes.Sizeof(order[j].Type()) == 0
+		if zi != zj {
+			return zi
+		}
+		return sizes.Alignof(order[i].Type()) > sizes.Alignof(order[j].Type())
+	})
+	if optimal := sizes.Sizeof(types.NewStruct(order, nil)); optimal < size {
+		return order, optimal
+	}
+	return fields, size
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Suppression directives are line comments of the form
+//
+//	//goanalyzer:ignore GA101,GA2* reason
+//
+// where the code list is optional and matched with path.Match, so a bare
+// directive drops every finding. "ignore" covers its own line and the
+// next, "ignore-func" the function declaration it documents or appears in,
+// and "ignore-file" the whole file. Anything after the codes is a free-form
+// reason.
+const (
+	ignoreDirective     = "//goanalyzer:ignore"
+	ignoreFuncDirective = "//goanalyzer:ignore-func"
+	ignoreFileDirective = "//goanalyzer:ignore-file"
+)
+
+// codeListPattern matches a comma-separated list of finding code patterns,
+// each optionally prefixed with a plugin name.
+var codeListPattern = regexp.MustCompile(`^([a-z0-9_.-]+/)?[A-Z0-9*?]+(,([a-z0-9_.-]+/)?[A-Z0-9*?]+)*$`)
+
+// suppression drops the findings with a matching code whose range starts
+// on one of the zero-based lines from first to last.
+type suppression struct {
+	codes       []string
+	first, last int
+}
+
+func (s suppression) matches(f Finding) bool {
+	if f.Range.Start.Line < s.first || f.Range.Start.Line > s.last {
+		return false
+	}
+	if len(s.codes) == 0 {
+		return true
+	}
+	for _, pattern := range s.codes {
+		if ok, _ := path.Match(pattern, f.Code); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSuppressions collects the directives in the comments of file. Only
+// comment nodes are considered, and a directive must start its comment, so
+// the text inside strings and commented-out code does not count.
+func fileSuppressions(fset *token.FileSet, file *ast.File) []suppression {
+	var sups []suppression
+	line := func(pos token.Pos) int { return fset.Position(pos).Line - 1 }
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			directive, rest := c.Text, ""
+			if i := strings.IndexAny(c.Text, " \t"); i >= 0 {
+				directive, rest = c.Text[:i], c.Text[i+1:]
+			}
+			var codes []string
+			if fields := strings.Fields(rest); len(fields) > 0 && codeListPattern.MatchString(fields[0]) {
+				codes = strings.Split(fields[0], ",")
+			}
+			switch directive {
+			case ignoreDirective:
+				l := line(c.Pos())
+				sups = append(sups, suppression{codes: codes, first: l, last: l + 1})
+			case ignoreFuncDirective:
+				if fd := documentedFunc(file, c.Pos()); fd != nil {
+					sups = append(sups, suppression{codes: codes, first: line(fd.Pos()), last: line(fd.End())})
+				}
+			case ignoreFileDirective:
+				sups = append(sups, suppression{codes: codes, first: 0, last: line(file.End())})
+			}
+		}
+	}
+	return sups
+}
+
+// documentedFunc returns the function declaration of file whose doc comment
+// or extent contains pos.
+func documentedFunc(file *ast.File, pos token.Pos) *ast.FuncDecl {
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		start := fd.Pos()
+		if fd.Doc != nil {
+			start = fd.Doc.Pos()
+		}
+		if start <= pos && pos < fd.End() {
+			return fd
+		}
+	}
+	return nil
+}
+
+// suppressFindings removes the findings silenced by a directive in the
+// files of lp and returns how many were removed.
+func suppressFindings(lp *loadedPackage, out *AnalyzeOutput) int {
+	byFile := make(map[string][]suppression)
+	for _, f := range lp.files {
+		if sups := fileSuppressions(lp.fset, f); len(sups) > 0 {
+			byFile[lp.fset.Position(f.Pos()).Filename] = sups
+		}
+	}
+	if len(byFile) == 0 {
+		return 0
+	}
+	kept := out.Findings[:0]
+	suppressed := 0
+	for _, f := range out.Findings {
+		silenced := false
+		for _, s := range byFile[f.Range.File] {
+			if s.matches(f) {
+				silenced = true
+				break
+			}
+		}
+		if silenced {
+			suppressed++
+			continue
+		}
+		kept = append(kept, f)
+	}
+	out.Findings = kept
+	return suppressed
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// errNeedsTypes is returned for queries that cannot be answered from syntax
+// alone, such as selector fields and methods.
+const errNeedsTypes = "requires type information, which is unavailable because no imports could be loaded"
+
+// syntaxResolve answers a resolve query from the parser's object resolution
+// when type-checking is useless because every import failed. It covers
+// locals and package-level variables and constants; selector queries need
+// type information and are reported as errors.
+func syntaxResolve(lp *loadedPackage, in Input) *Output {
+	fset, file := lp.fset, lp.file
+	parentMap := buildPackageParentMap(lp.files)
+	ident, selMap := findIdentAtPosition(fset, file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	if sel := selMap[ident]; sel != nil && sel.Sel == ident {
+		return &Output{Name: ident.Name, Uses: make([]UseEntry, 0), SizeBytes: -1, Degraded: true, Error: errNeedsTypes}
+	}
+
+	obj := ident.Obj
+	packageLevel := false
+	if obj == nil {
+		obj = lookupFileScopes(lp.files, ident.Name)
+		packageLevel = obj != nil
+	} else {
+		packageLevel = lookupFileScopes(lp.files, ident.Name) == obj
+	}
+	if obj == nil || (obj.Kind != ast.Var && obj.Kind != ast.Con) {
+		return nil
+	}
+	declIdent := syntaxDeclIdent(obj)
+	if declIdent == nil {
+		return nil
+	}
+
+	decl := rangeForIdent(fset, declIdent)
+	declFunc := enclosingFunc(declIdent, parentMap)
+	noTypes := &types.Info{Defs: make(map[*ast.Ident]types.Object)}
+	uses := make([]UseEntry, 0)
+	for _, f := range lp.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok || id.Name != obj.Name || id == declIdent {
+				return true
+			}
+			// Package-level names are only resolved within their own file;
+			// elsewhere they stay unresolved and match by name.
+			if id.Obj != obj && !(packageLevel && id.Obj == nil) {
+				return true
+			}
+			if sel, ok := parentMap[id].(*ast.SelectorExpr); ok && sel.Sel == id {
+				return true
+			}
+			if kv, ok := parentMap[id].(*ast.KeyValueExpr); ok && kv.Key == id && id.Obj == nil {
+				return true
+			}
+			uses = append(uses, UseEntry{
+				Range:           rangeForIdent(fset, id),
+				Reassign:        isReassign(id, noTypes, parentMap),
+				Captured:        !packageLevel && syntaxCaptured(id, declFunc, parentMap),
+				PackageInit:     isPackageInit(id, parentMap),
+				InComparison:    isComparisonOperand(id, parentMap),
+				PassedByPointer: isPointerArgument(id, parentMap),
+				InSelect:        inSelect(id, parentMap),
+			})
+			return true
+		})
+	}
+	sortUses(uses)
+
+	return &Output{
+		Name:      obj.Name,
+		Decl:      decl,
+		Uses:      uses,
+		SizeBytes: -1,
+		Degraded:  true,
+	}
+}
+
+// lookupFileScopes finds a package-level object declared in any file.
+func lookupFileScopes(files []*ast.File, name string) *ast.Object {
+	for _, f := range files {
+		if f.Scope == nil {
+			continue
+		}
+		if obj := f.Scope.Lookup(name); obj != nil {
+			return obj
+		}
+	}
+	return nil
+}
+
+// syntaxDeclIdent finds the identifier that declares obj inside obj.Decl.
+func syntaxDeclIdent(obj *ast.Object) *ast.Ident {
+	node, ok := obj.Decl.(ast.Node)
+	if !ok {
+		return nil
+	}
+	var found *ast.Ident
+	ast.Inspect(node, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		if id, ok := n.(*ast.Ident); ok && id.Name == obj.Name && id.Obj == obj {
+			found = id
+		}
+		return true
+	})
+	return found
+}
+
+func syntaxCaptured(ident *ast.Ident, declFunc ast.Node, parents map[ast.Node]ast.Node) bool {
+	useFunc := enclosingFunc(ident, parents)
+	if _, ok := useFunc.(*ast.FuncLit); !ok {
+		return false
+	}
+	return declFunc != nil && useFunc != declFunc
+}
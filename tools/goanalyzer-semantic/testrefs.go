@@ -0,0 +1,127 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// TestRefsOutput is the response of "test_refs" mode: the Test, Benchmark
+// and Fuzz functions of the package's _test.go files that refer to the
+// symbol at Line/Col. An empty Tests means no test refers to it. Test
+// files of an external _test package are not loaded and not searched.
+type TestRefsOutput struct {
+	Name  string    `json:"name"`
+	Decl  Range     `json:"decl"`
+	Tests []TestRef `json:"tests"`
+}
+
+// TestRef groups the references made by one test function, directly or in
+// the functions of the test files it calls, which Via names.
+type TestRef struct {
+	Name string   `json:"name"`
+	Decl Range    `json:"decl"`
+	Refs []Range  `json:"refs"`
+	Via  []string `json:"via,omitempty"`
+}
+
+func (o *TestRefsOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	f(&o.Decl)
+	for i := range o.Tests {
+		f(&o.Tests[i].Decl)
+		for j := range o.Tests[i].Refs {
+			f(&o.Tests[i].Refs[j])
+		}
+	}
+}
+
+func testRefs(in Input) *TestRefsOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	ident, selMap := findIdentAtPosition(lp.fset, lp.file, in.Line, in.Col)
+	if ident == nil {
+		return nil
+	}
+	obj := identObject(lp.info, lp.pkg, ident, selMap[ident])
+	if obj == nil {
+		return nil
+	}
+	out := &TestRefsOutput{Name: obj.Name(), Tests: make([]TestRef, 0)}
+	out.Decl, _ = lp.objectRange(obj)
+
+	type funcRefs struct {
+		decl *ast.FuncDecl
+		refs []Range
+	}
+	var tests []*funcRefs
+	helpers := make(map[types.Object]*funcRefs)
+	for _, f := range lp.files {
+		if !strings.HasSuffix(lp.fset.Position(f.Pos()).Filename, "_test.go") {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			fr := &funcRefs{decl: fd}
+			ast.Inspect(fd.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && lp.info.Uses[id] == obj {
+					fr.refs = append(fr.refs, rangeForIdent(lp.fset, id))
+				}
+				return true
+			})
+			if isTestFunc(fd) {
+				tests = append(tests, fr)
+			} else if len(fr.refs) > 0 {
+				helpers[lp.info.Defs[fd.Name]] = fr
+			}
+		}
+	}
+
+	for _, test := range tests {
+		ref := TestRef{Name: test.decl.Name.Name, Decl: rangeForIdent(lp.fset, test.decl.Name), Refs: test.refs}
+		called := make(map[types.Object]bool)
+		ast.Inspect(test.decl.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if fn := passes.CalledFunc(call, lp.info); fn != nil && helpers[fn] != nil && !called[fn] {
+				called[fn] = true
+				ref.Refs = append(ref.Refs, helpers[fn].refs...)
+				ref.Via = append(ref.Via, fn.Name())
+			}
+			return true
+		})
+		if len(ref.Refs) == 0 {
+			continue
+		}
+		sort.Slice(ref.Refs, func(i, j int) bool { return rangeLess(ref.Refs[i], ref.Refs[j]) })
+		out.Tests = append(out.Tests, ref)
+	}
+	sort.Slice(out.Tests, func(i, j int) bool { return out.Tests[i].Name < out.Tests[j].Name })
+	return out
+}
+
+// isTestFunc reports whether fd is a Test, Benchmark or Fuzz function that
+// the go test command runs.
+func isTestFunc(fd *ast.FuncDecl) bool {
+	if fd.Recv != nil {
+		return false
+	}
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz"} {
+		if rest := strings.TrimPrefix(fd.Name.Name, prefix); rest != fd.Name.Name {
+			return rest == "" || !('a' <= rest[0] && rest[0] <= 'z')
+		}
+	}
+	return false
+}
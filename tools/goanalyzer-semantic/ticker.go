@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// tickerAnalyzer flags tickers that are never stopped: time.Tick calls,
+// whose ticker cannot be stopped at all, and time.NewTicker results kept
+// in a local variable that has no Stop call in the function. A running
+// ticker keeps its channel and timer alive until stopped (before Go 1.23,
+// forever). Tickers stored elsewhere or passed on are assumed to be
+// stopped by their new owner.
+var tickerAnalyzer = &analyzer{
+	name:     "ticker",
+	code:     "GA207",
+	summary:  "Ticker that is never stopped",
+	severity: "warning",
+	report:   "retention",
+	run:      runTicker,
+}
+
+func runTicker(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fn := passes.CalledFunc(call, p.info)
+		if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != "time" {
+			return true
+		}
+		switch fn.Name() {
+		case "Tick":
+			findings = append(findings, Finding{
+				Message: "time.Tick creates a ticker that can never be stopped; use time.NewTicker and Stop it",
+				Range:   p.rangeForNode(call),
+			})
+		case "NewTicker":
+			as, ok := p.parents[call].(*ast.AssignStmt)
+			if !ok || len(as.Lhs) != 1 {
+				return true
+			}
+			id, ok := as.Lhs[0].(*ast.Ident)
+			if !ok {
+				return true
+			}
+			v, ok := p.info.ObjectOf(id).(*types.Var)
+			if !ok || v.Parent() == p.pkg.Scope() || p.tickerHandledOff(v) {
+				return true
+			}
+			findings = append(findings, Finding{
+				Message: "ticker " + v.Name() + " is never stopped; add defer " + v.Name() + ".Stop()",
+				Range:   p.rangeForNode(call),
+			})
+		}
+		return true
+	})
+	return findings
+}
+
+// tickerHandledOff reports whether ticker is stopped, or used other than
+// through its C field and Reset method, anywhere in the file.
+func (p *pass) tickerHandledOff(ticker *types.Var) bool {
+	handled := false
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || handled || p.info.Uses[id] != ticker {
+			return !handled
+		}
+		sel, ok := p.parents[id].(*ast.SelectorExpr)
+		if !ok || sel.X != id || (sel.Sel.Name != "C" && sel.Sel.Name != "Reset") {
+			handled = true
+		}
+		return true
+	})
+	return handled
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// typedNilAnalyzer flags comparisons of an interface variable with nil
+// where the variable is assigned a concrete pointer somewhere in the file.
+// An interface holding a nil pointer is itself non-nil, so the check passes
+// even when the pointer it was given is nil. Assignments of &x or new(T)
+// cannot be nil and are ignored.
+var typedNilAnalyzer = &analyzer{
+	name:     "typednil",
+	code:     "GA309",
+	summary:  "Interface compared with nil may hold a nil pointer",
+	severity: "warning",
+	run:      runTypedNil,
+}
+
+func runTypedNil(p *pass) []Finding {
+	assigned := make(map[*types.Var][]RelatedRange)
+	record := func(id *ast.Ident, value ast.Expr, typ types.Type) {
+		v, ok := p.info.Defs[id].(*types.Var)
+		if !ok {
+			v, ok = p.info.Uses[id].(*types.Var)
+		}
+		if !ok || !types.IsInterface(v.Type()) || typ == nil || nonNilExpr(value, p.info) {
+			return
+		}
+		if _, ok := typ.Underlying().(*types.Pointer); !ok {
+			return
+		}
+		assigned[v] = append(assigned[v], RelatedRange{
+			Range:   p.rangeForNode(value),
+			Message: "assigned a " + types.TypeString(typ, types.RelativeTo(p.pkg)) + " that may be nil",
+		})
+	}
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		switch s := n.(type) {
+		case *ast.AssignStmt:
+			for j, lhs := range s.Lhs {
+				if id, ok := passes.Unparen(lhs).(*ast.Ident); ok && len(s.Lhs) == len(s.Rhs) {
+					record(id, s.Rhs[j], p.info.TypeOf(s.Rhs[j]))
+				}
+			}
+		case *ast.ValueSpec:
+			for j, name := range s.Names {
+				if len(s.Names) == len(s.Values) {
+					record(name, s.Values[j], p.info.TypeOf(s.Values[j]))
+				}
+			}
+		}
+		return true
+	})
+	if len(assigned) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		b, ok := n.(*ast.BinaryExpr)
+		if !ok || b.Op != token.EQL && b.Op != token.NEQ {
+			return true
+		}
+		for _, side := range [][2]ast.Expr{{b.X, b.Y}, {b.Y, b.X}} {
+			id, ok := passes.Unparen(side[0]).(*ast.Ident)
+			if !ok || !p.info.Types[passes.Unparen(side[1])].IsNil() {
+				continue
+			}
+			v, ok := p.info.Uses[id].(*types.Var)
+			if !ok || assigned[v] == nil {
+				continue
+			}
+			findings = append(findings, Finding{
+				Message: "comparison of interface " + id.Name + " with nil is false when it holds a nil pointer",
+				Range:   p.rangeForNode(b),
+				Related: assigned[v],
+			})
+		}
+		return true
+	})
+	return findings
+}
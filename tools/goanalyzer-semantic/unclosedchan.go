@@ -0,0 +1,149 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// unclosedChanAnalyzer flags channel variables and fields declared in the
+// file that are created with make, sent to and consumed by a range loop,
+// but never closed anywhere in the package. The range only ends when the
+// channel is closed, so the consuming goroutine leaks once the senders are
+// done. Channels used in any other way, such as passed to a function or
+// stored elsewhere, may be closed out of sight and are not reported.
+var unclosedChanAnalyzer = &analyzer{
+	name:     "unclosedchan",
+	code:     "GA311",
+	summary:  "Channel consumed by a range loop is never closed",
+	severity: "warning",
+	run:      runUnclosedChan,
+}
+
+// chanUses classifies the references to one channel variable or field.
+type chanUses struct {
+	made, closed, escapes bool
+	sends, ranges         []ast.Node
+}
+
+func runUnclosedChan(p *pass) []Finding {
+	parents := buildPackageParentMap(p.files)
+	uses := make(map[types.Object]*chanUses)
+	for _, f := range p.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := p.info.ObjectOf(id)
+			v, ok := obj.(*types.Var)
+			if !ok {
+				return true
+			}
+			if _, ok := v.Type().Underlying().(*types.Chan); !ok {
+				return true
+			}
+			cu := uses[v]
+			if cu == nil {
+				cu = &chanUses{}
+				uses[v] = cu
+			}
+			p.classifyChanRef(id, cu, parents)
+			return true
+		})
+	}
+
+	var findings []Finding
+	for obj, cu := range uses {
+		if !cu.made || cu.closed || cu.escapes || len(cu.sends) == 0 || len(cu.ranges) == 0 {
+			continue
+		}
+		if p.fset.File(obj.Pos()) != p.fset.File(p.file.Pos()) {
+			continue
+		}
+		var related []RelatedRange
+		for _, r := range cu.ranges {
+			related = append(related, RelatedRange{Range: p.rangeForNode(r), Message: "range loop waits for " + obj.Name() + " to be closed"})
+		}
+		for _, s := range cu.sends {
+			related = append(related, RelatedRange{Range: p.rangeForNode(s), Message: "send on " + obj.Name()})
+		}
+		findings = append(findings, Finding{
+			Message: "channel " + obj.Name() + " is sent to and ranged over but never closed, so the range loop never ends",
+			Range:   rangeForPos(p.fset, obj.Pos(), obj.Pos()+token.Pos(len(obj.Name()))),
+			Related: related,
+		})
+	}
+	return findings
+}
+
+// classifyChanRef records what the reference id does with its channel.
+func (p *pass) classifyChanRef(id *ast.Ident, cu *chanUses, parents map[ast.Node]ast.Node) {
+	var ref ast.Node = id
+	if sel, ok := parents[id].(*ast.SelectorExpr); ok && sel.Sel == id {
+		ref = sel
+	}
+	for {
+		paren, ok := parents[ref].(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		ref = paren
+	}
+	isMake := func(e ast.Expr) bool {
+		call, ok := passes.Unparen(e).(*ast.CallExpr)
+		return ok && isBuiltin(call, "make", p.info)
+	}
+	switch parent := parents[ref].(type) {
+	case *ast.Field:
+		// The declaration of a field or parameter.
+	case *ast.SendStmt:
+		if parent.Chan == ref {
+			cu.sends = append(cu.sends, parent)
+			return
+		}
+		cu.escapes = true
+	case *ast.RangeStmt:
+		if parent.X == ref {
+			cu.ranges = append(cu.ranges, parent)
+			return
+		}
+		cu.escapes = true
+	case *ast.UnaryExpr:
+		cu.escapes = cu.escapes || parent.Op != token.ARROW
+	case *ast.CallExpr:
+		switch {
+		case isBuiltin(parent, "close", p.info):
+			cu.closed = true
+		case isBuiltin(parent, "len", p.info), isBuiltin(parent, "cap", p.info):
+		default:
+			cu.escapes = true
+		}
+	case *ast.AssignStmt:
+		for i, lhs := range parent.Lhs {
+			if lhs == ref && len(parent.Lhs) == len(parent.Rhs) && isMake(parent.Rhs[i]) {
+				cu.made = true
+				return
+			}
+		}
+		cu.escapes = true
+	case *ast.ValueSpec:
+		for i, name := range parent.Names {
+			if name == ref && len(parent.Names) == len(parent.Values) && isMake(parent.Values[i]) {
+				cu.made = true
+				return
+			}
+		}
+		cu.escapes = true
+	case *ast.KeyValueExpr:
+		if parent.Key == ref && isMake(parent.Value) {
+			cu.made = true
+			return
+		}
+		cu.escapes = true
+	default:
+		cu.escapes = true
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"sort"
+	"strings"
+
+	"goanalyzer-semantic/passes"
+)
+
+// UnusedOutput is the response of "unused" mode.
+type UnusedOutput struct {
+	Unused []UnusedVar `json:"unused"`
+}
+
+// UnusedVar is a local variable, parameter or named result that is never
+// read. Kind is "local", "param" or "result".
+type UnusedVar struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Range Range  `json:"range"`
+	// Function is the enclosing function declaration, "T.m" for methods.
+	Function string `json:"function"`
+	// WrittenOnly marks locals that are assigned after their declaration
+	// but never read.
+	WrittenOnly bool `json:"written_only,omitempty"`
+}
+
+func (o *UnusedOutput) mapRanges(f func(*Range)) {
+	if o == nil {
+		return
+	}
+	for i := range o.Unused {
+		f(&o.Unused[i].Range)
+	}
+}
+
+type varAccess struct {
+	reads, writes int
+}
+
+// unused reports variables of the target file, or with UnusedScope
+// "function" of the function enclosing Line/Col, that are never read. It is
+// use collection inverted: every entry of info.Uses is classified as a read
+// or a write, and variables without reads are reported. `_ = x` reads x.
+// Receivers, the blank identifier and, unless IncludeUnderscore is set,
+// names starting with an underscore are never reported.
+func unused(in Input) *UnusedOutput {
+	lp := loadPackage(in)
+	if lp == nil || lp.syntaxOnly {
+		return nil
+	}
+	parents := passes.ParentMap(lp.file)
+	var scope ast.Node = lp.file
+	if in.UnusedScope == "function" {
+		fd := funcDeclAt(lp, in.Line, in.Col)
+		if fd == nil {
+			return nil
+		}
+		scope = fd
+	}
+
+	access := make(map[*types.Var]*varAccess)
+	for id, obj := range lp.info.Uses {
+		v, ok := obj.(*types.Var)
+		if !ok {
+			continue
+		}
+		a := access[v]
+		if a == nil {
+			a = &varAccess{}
+			access[v] = a
+		}
+		if isReassign(id, lp.info, parents) {
+			a.writes++
+		} else {
+			a.reads++
+		}
+	}
+
+	out := &UnusedOutput{Unused: make([]UnusedVar, 0)}
+	receivers := receiverIdents(lp.file)
+	ast.Inspect(scope, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || receivers[id] || id.Name == "_" {
+			return true
+		}
+		if strings.HasPrefix(id.Name, "_") && !in.IncludeUnderscore {
+			return true
+		}
+		v, ok := lp.info.Defs[id].(*types.Var)
+		if !ok || v.IsField() || v.Parent() == nil || v.Parent() == lp.pkg.Scope() {
+			return true
+		}
+		a := access[v]
+		if a == nil {
+			a = &varAccess{}
+		}
+		if a.reads > 0 {
+			return true
+		}
+		kind := paramKind(v, lp.info)
+		switch {
+		case kind == "":
+			kind = "local"
+		case a.writes > 0:
+			// A parameter or result that is assigned is referenced; a
+			// result assigned before a bare return is returned.
+			return true
+		}
+		out.Unused = append(out.Unused, UnusedVar{
+			Name:        id.Name,
+			Kind:        kind,
+			Range:       rangeForIdent(lp.fset, id),
+			Function:    callerName(id, parents),
+			WrittenOnly: kind == "local" && a.writes > 0,
+		})
+		return true
+	})
+	sort.Slice(out.Unused, func(i, j int) bool {
+		return rangeLess(out.Unused[i].Range, out.Unused[j].Range)
+	})
+	return out
+}
+
+// funcDeclAt returns the function declaration of the target file that
+// contains line/col.
+func funcDeclAt(lp *loadedPackage, line, col int) *ast.FuncDecl {
+	pos, ok := filePos(lp.fset, lp.file, line, col)
+	if !ok {
+		return nil
+	}
+	for _, decl := range lp.file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Pos() <= pos && pos < fd.End() {
+			return fd
+		}
+	}
+	return nil
+}
+
+func receiverIdents(file *ast.File) map[*ast.Ident]bool {
+	receivers := make(map[*ast.Ident]bool)
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv != nil {
+			for _, field := range fd.Recv.List {
+				for _, name := range field.Names {
+					receivers[name] = true
+				}
+			}
+		}
+	}
+	return receivers
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// unusedFieldAnalyzer flags struct fields declared in the file that are
+// neither read nor written anywhere in the package. Write-only fields are
+// left to other checks. Exported, embedded, blank and tagged fields may be
+// used from other packages or through reflection and are not reported; an
+// unkeyed composite literal uses every field of its type.
+var unusedFieldAnalyzer = &analyzer{
+	name:     "unusedfield",
+	code:     "GA306",
+	summary:  "Struct field that is never used",
+	severity: "info",
+	run:      runUnusedField,
+}
+
+func runUnusedField(p *pass) []Finding {
+	used := make(map[*types.Var]bool)
+	for _, obj := range p.info.Uses {
+		if v, ok := obj.(*types.Var); ok && v.IsField() {
+			used[v] = true
+		}
+	}
+	for _, f := range p.files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			lit, ok := n.(*ast.CompositeLit)
+			if !ok || len(lit.Elts) == 0 {
+				return true
+			}
+			if _, keyed := lit.Elts[0].(*ast.KeyValueExpr); keyed {
+				return true
+			}
+			typ := p.info.TypeOf(lit)
+			if typ == nil {
+				return true
+			}
+			// Elided literals in a []*T are recorded with type *T.
+			if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+				typ = ptr.Elem()
+			}
+			if st, ok := typ.Underlying().(*types.Struct); ok {
+				for i := 0; i < st.NumFields(); i++ {
+					used[st.Field(i)] = true
+				}
+			}
+			return true
+		})
+	}
+
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		for _, field := range st.Fields.List {
+			if field.Tag != nil {
+				continue
+			}
+			for _, name := range field.Names {
+				v, ok := p.info.Defs[name].(*types.Var)
+				if !ok || name.Name == "_" || name.IsExported() || used[v] {
+					continue
+				}
+				findings = append(findings, Finding{
+					Message: "field " + name.Name + " is never read or written",
+					Range:   p.rangeForNode(name),
+				})
+			}
+		}
+		return true
+	})
+	return findings
+}
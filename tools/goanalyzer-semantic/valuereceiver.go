@@ -0,0 +1,114 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"goanalyzer-semantic/passes"
+)
+
+// valueReceiverAnalyzer flags methods with a value receiver that assign to
+// the receiver's fields. The receiver is a copy, so the writes are lost when
+// the method returns. Writes through maps, slices and pointers reach shared
+// memory and are not reported, and methods that use the receiver as a whole
+// value (returning or passing it on, as builders do) are skipped.
+var valueReceiverAnalyzer = &analyzer{
+	name:     "valuereceiver",
+	code:     "GA303",
+	summary:  "Value receiver method assigns to its fields",
+	severity: "warning",
+	run:      runValueReceiver,
+}
+
+func runValueReceiver(p *pass) []Finding {
+	var findings []Finding
+	for _, decl := range p.file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Body == nil || fd.Recv == nil || len(fd.Recv.List) != 1 || len(fd.Recv.List[0].Names) != 1 {
+			continue
+		}
+		recv, ok := p.info.Defs[fd.Recv.List[0].Names[0]].(*types.Var)
+		if !ok || recv == nil {
+			continue
+		}
+		if _, isPtr := recv.Type().(*types.Pointer); isPtr {
+			continue
+		}
+
+		var writes []ast.Expr
+		wholeValue := false
+		ast.Inspect(fd.Body, func(n ast.Node) bool {
+			switch node := n.(type) {
+			case *ast.AssignStmt:
+				if node.Tok == token.DEFINE {
+					return true
+				}
+				for _, lhs := range node.Lhs {
+					if p.receiverFieldWrite(lhs, recv) {
+						writes = append(writes, lhs)
+					}
+				}
+			case *ast.IncDecStmt:
+				if p.receiverFieldWrite(node.X, recv) {
+					writes = append(writes, node.X)
+				}
+			case *ast.Ident:
+				if p.info.Uses[node] == recv {
+					if sel, ok := p.parents[node].(*ast.SelectorExpr); !ok || sel.X != node {
+						wholeValue = true
+					}
+				}
+			}
+			return true
+		})
+		if wholeValue || len(writes) == 0 {
+			continue
+		}
+		related := make([]RelatedRange, 0, len(writes))
+		for _, w := range writes {
+			related = append(related, RelatedRange{
+				Range:   p.rangeForNode(w),
+				Message: "write to a field of the receiver copy is lost",
+			})
+		}
+		findings = append(findings, Finding{
+			Message: "method " + fd.Name.Name + " has a value receiver but assigns to its fields; use a pointer receiver",
+			Range:   p.rangeForNode(fd.Name),
+			Related: related,
+		})
+	}
+	return findings
+}
+
+// receiverFieldWrite reports whether assigning to expr changes memory that
+// belongs to the copied receiver: a chain of direct field selections and
+// array indexes rooted at recv.
+func (p *pass) receiverFieldWrite(expr ast.Expr, recv *types.Var) bool {
+	sawField := false
+	for {
+		switch e := passes.Unparen(expr).(type) {
+		case *ast.SelectorExpr:
+			selection := p.info.Selections[e]
+			if selection == nil || selection.Kind() != types.FieldVal || selection.Indirect() {
+				return false
+			}
+			sawField = true
+			expr = e.X
+		case *ast.IndexExpr:
+			// TypeOf is nil for operands the checker rejected.
+			t := p.info.TypeOf(e.X)
+			if t == nil {
+				return false
+			}
+			if _, ok := t.Underlying().(*types.Array); !ok {
+				return false
+			}
+			expr = e.X
+		case *ast.Ident:
+			return sawField && p.info.Uses[e] == recv
+		default:
+			return false
+		}
+	}
+}
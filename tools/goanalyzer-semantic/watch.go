@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WatchEvent is one JSON line written by --watch: the time of the run and
+// either the full report or, with --watch-diff after the first run, the
+// findings that appeared and disappeared since the previous one. Error is
+// set when the target could not be loaded; the next run then reports in
+// full again.
+type WatchEvent struct {
+	Time    string         `json:"time"`
+	Report  *AnalyzeOutput `json:"report,omitempty"`
+	Added   []Finding      `json:"added,omitempty"`
+	Removed []Finding      `json:"removed,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// reportModes are the modes answering with an AnalyzeOutput: the ones
+// --watch can re-run and --sarif can render.
+var reportModes = map[string]func(Input) *AnalyzeOutput{
+	"analyze":          analyze,
+	"race_report":      raceReport,
+	"retention_report": retentionReport,
+	"concurrency":      concurrency,
+}
+
+const watchInterval = 300 * time.Millisecond
+
+// watch runs the report of in, then polls the Go files of the target's
+// directory every interval and runs it again once they change, until stop
+// delivers a signal. A change is only acted on after the files have stayed
+// the same for a whole interval, which absorbs bursts of writes and the
+// moment an editor's atomic save leaves the target missing. Runs go
+// through packageCache like server requests do. When stop fires while a
+// change is pending, its report is written before returning. The files are
+// read from disk; in.Content is ignored.
+func watch(in Input, run func(Input) *AnalyzeOutput, diff bool, interval time.Duration, w io.Writer, stop <-chan os.Signal) int {
+	in.Content = ""
+	target := in.File
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	enc := json.NewEncoder(w)
+	var prev *AnalyzeOutput
+	emit := func() error {
+		out := run(in)
+		if cols := newColumnMapper(in); cols != nil {
+			out.mapRanges(cols.mapRange)
+		}
+		ev := WatchEvent{Time: time.Now().UTC().Format(time.RFC3339Nano)}
+		switch {
+		case out == nil:
+			ev.Error = "cannot load " + target
+		case diff && prev != nil:
+			ev.Added, ev.Removed = diffFindings(prev.Findings, out.Findings)
+		default:
+			ev.Report = out
+		}
+		prev = out
+		return enc.Encode(ev)
+	}
+
+	last := packageFingerprint(filepath.Dir(target), nil)
+	if err := emit(); err != nil {
+		return 1
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	pending := ""
+	for {
+		select {
+		case <-stop:
+			if pending != "" && fileExists(target) {
+				if err := emit(); err != nil {
+					return 1
+				}
+			}
+			return 0
+		case <-ticker.C:
+		}
+		fp := packageFingerprint(filepath.Dir(target), nil)
+		switch {
+		case fp == last:
+			pending = ""
+		case fp != pending:
+			pending = fp
+		case fileExists(target):
+			if err := emit(); err != nil {
+				return 1
+			}
+			last, pending = fp, ""
+		}
+	}
+}
+
+// diffFindings returns the findings of cur missing from prev and those of
+// prev missing from cur. Findings are matched by code, range and message,
+// so a finding moved by an edit above it counts as removed and added.
+func diffFindings(prev, cur []Finding) (added, removed []Finding) {
+	key := func(f Finding) string {
+		return fmt.Sprintf("%s %+v %s", f.Code, f.Range, f.Message)
+	}
+	count := make(map[string]int)
+	for _, f := range prev {
+		count[key(f)]++
+	}
+	for _, f := range cur {
+		if k := key(f); count[k] > 0 {
+			count[k]--
+		} else {
+			added = append(added, f)
+		}
+	}
+	for _, f := range prev {
+		if k := key(f); count[k] > 0 {
+			count[k]--
+			removed = append(removed, f)
+		}
+	}
+	return added, removed
+}
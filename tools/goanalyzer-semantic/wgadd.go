@@ -0,0 +1,63 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+
+	"goanalyzer-semantic/passes"
+)
+
+// wgAddAnalyzer flags sync.WaitGroup.Add calls made by the goroutine they
+// count, inside the body of a `go func() {...}()` literal. The launching
+// code can reach Wait before the goroutine has run Add, so Wait returns
+// early. WaitGroups declared inside the literal itself are not reported,
+// and nested function literals are skipped.
+var wgAddAnalyzer = &analyzer{
+	name:     "wgadd",
+	code:     "GA314",
+	summary:  "WaitGroup.Add called inside the goroutine it counts",
+	severity: "warning",
+	report:   "race",
+	run:      runWGAdd,
+}
+
+func runWGAdd(p *pass) []Finding {
+	var findings []Finding
+	ast.Inspect(p.file, func(n ast.Node) bool {
+		gs, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		lit, ok := passes.Unparen(gs.Call.Fun).(*ast.FuncLit)
+		if !ok || lit.Body == nil {
+			return true
+		}
+		inspectFuncBody(lit.Body, func(n ast.Node) {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return
+			}
+			fn := passes.CalledFunc(call, p.info)
+			if fn == nil || fn.FullName() != "(*sync.WaitGroup).Add" {
+				return
+			}
+			sel, ok := passes.Unparen(call.Fun).(*ast.SelectorExpr)
+			if !ok {
+				return
+			}
+			if obj := passes.ExprObject(sel.X, p.info); obj != nil && lit.Pos() <= obj.Pos() && obj.Pos() < lit.End() {
+				return
+			}
+			findings = append(findings, Finding{
+				Message: "WaitGroup.Add runs inside the goroutine it counts, so Wait can return before it; call Add before the go statement",
+				Range:   p.rangeForNode(call),
+				Related: []RelatedRange{{
+					Range:   rangeForPos(p.fset, gs.Go, gs.Go+token.Pos(len("go"))),
+					Message: "goroutine started here",
+				}},
+			})
+		})
+		return true
+	})
+	return findings
+}